@@ -0,0 +1,220 @@
+package messagingutilities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// DefaultPhoneCountryCode, such as "254", is assumed for numbers in local
+// format when numbers are compared, such as in suppression lists and when
+// matching the recipients of bulk responses.
+var DefaultPhoneCountryCode string
+
+// phoneNumberKey returns number in E.164 form, so that "+254712345678",
+// "254712345678" and "0712345678" compare equal under the default country
+// code. Numbers that cannot be normalized are returned trimmed, without a
+// leading "+".
+func phoneNumberKey(number string) string {
+	number = strings.TrimPrefix(strings.TrimSpace(number), "whatsapp:")
+	if normalized, err := NormalizePhoneNumber(number, DefaultPhoneCountryCode); err == nil {
+		return normalized
+	}
+	if normalized, err := NormalizePhoneNumber("+"+number, ""); err == nil {
+		return normalized
+	}
+
+	return strings.TrimPrefix(number, "+")
+}
+
+func NormalizePhoneNumber(number, defaultCountryCode string) (string, error) {
+	normalized := strings.Map(func(character rune) rune {
+		switch character {
+		case ' ', '-', '.', '(', ')', '\t':
+			return -1
+		}
+		return character
+	}, number)
+
+	switch {
+	case strings.HasPrefix(normalized, "+"):
+	case strings.HasPrefix(normalized, "00"):
+		normalized = "+" + normalized[2:]
+	case strings.HasPrefix(normalized, "0") && defaultCountryCode != "":
+		normalized = "+" + strings.TrimPrefix(defaultCountryCode, "+") + normalized[1:]
+	case defaultCountryCode != "" && strings.HasPrefix(normalized, strings.TrimPrefix(defaultCountryCode, "+")):
+		normalized = "+" + normalized
+	}
+
+	if !e164Pattern.MatchString(normalized) {
+		return "", &ValidationError{Field: "phoneNumber", Message: "Invalid E.164 phone number: " + number}
+	}
+
+	return normalized, nil
+}
+
+func NormalizeEmailAddress(address string) (string, error) {
+	parsed, err := mail.ParseAddress(strings.TrimSpace(address))
+	if err != nil {
+		return "", &ValidationError{Field: "email", Message: "Invalid email address: " + address}
+	}
+
+	local, domain, found := strings.Cut(parsed.Address, "@")
+	if !found || local == "" || domain == "" {
+		return "", &ValidationError{Field: "email", Message: "Invalid email address: " + address}
+	}
+
+	return local + "@" + strings.ToLower(domain), nil
+}
+
+var (
+	smtpDotAtomPattern     = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+/=?^_`{|}~-]+(\\.[A-Za-z0-9!#$%&'*+/=?^_`{|}~-]+)*$")
+	smtpQuotedLocalPattern = regexp.MustCompile(`^[\x20-\x7e]+$`)
+	smtpDomainLabelPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?$`)
+)
+
+// EmailValidator checks addresses against the RFC 5321 mailbox syntax and,
+// when CheckMX is set, that their domain accepts mail: it must publish MX
+// records, or an address record when it has none, and must not publish a
+// null MX. Resolver defaults to net.DefaultResolver.
+type EmailValidator struct {
+	CheckMX  bool
+	Resolver *net.Resolver
+}
+
+// Validate returns a *ValidationError for an address that is invalid. DNS
+// failures that do not prove the domain wrong, such as timeouts, are
+// returned as other errors.
+func (validator *EmailValidator) Validate(ctx context.Context, address string) error {
+	_, domain, err := validateSMTPMailbox(address)
+	if err != nil {
+		return err
+	}
+
+	if !validator.CheckMX || strings.HasPrefix(domain, "[") {
+		return nil
+	}
+
+	return validator.checkDomain(ctx, address, domain)
+}
+
+// Invalid validates each address and returns the failures keyed by address.
+func (validator *EmailValidator) Invalid(ctx context.Context, addresses []string) map[string]error {
+	invalid := map[string]error{}
+	domains := map[string]error{}
+	for _, address := range addresses {
+		_, domain, err := validateSMTPMailbox(address)
+		if err == nil && validator.CheckMX && !strings.HasPrefix(domain, "[") {
+			key := strings.ToLower(domain)
+			domainErr, checked := domains[key]
+			if !checked {
+				domainErr = validator.checkDomain(ctx, address, domain)
+				domains[key] = domainErr
+			}
+			err = domainErr
+		}
+		if err != nil {
+			invalid[address] = err
+		}
+	}
+
+	return invalid
+}
+
+func (validator *EmailValidator) checkDomain(ctx context.Context, address, domain string) error {
+	resolver := validator.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	records, err := resolver.LookupMX(ctx, domain)
+	if err == nil {
+		if len(records) == 1 && records[0].Host == "." {
+			return &ValidationError{Field: "email", Message: "Domain does not accept email: " + address}
+		}
+		if len(records) > 0 {
+			return nil
+		}
+	}
+
+	var dnsError *net.DNSError
+	if err != nil && !(errors.As(err, &dnsError) && dnsError.IsNotFound) {
+		return fmt.Errorf("Failed to look up MX records for %s: %w", domain, err)
+	}
+
+	// Without MX records mail is delivered to the domain's own address.
+	if _, err := resolver.LookupIPAddr(ctx, domain); err != nil {
+		if errors.As(err, &dnsError) && dnsError.IsNotFound {
+			return &ValidationError{Field: "email", Message: "Domain does not accept email: " + address}
+		}
+		return fmt.Errorf("Failed to look up %s: %w", domain, err)
+	}
+
+	return nil
+}
+
+// validateSMTPMailbox checks the mailbox of an address, which may carry a
+// display name, against the RFC 5321 grammar and length limits.
+func validateSMTPMailbox(address string) (string, string, error) {
+	invalid := &ValidationError{Field: "email", Message: "Invalid email address: " + address}
+
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return "", "", invalid
+	}
+
+	at := strings.LastIndex(parsed.Address, "@")
+	if at < 0 {
+		return "", "", invalid
+	}
+	local, domain := parsed.Address[:at], parsed.Address[at+1:]
+
+	if len(parsed.Address) > 254 || len(local) > 64 || len(domain) > 255 {
+		return "", "", invalid
+	}
+
+	// net/mail unquotes the local part; anything printable can be quoted.
+	if !smtpDotAtomPattern.MatchString(local) && !smtpQuotedLocalPattern.MatchString(local) {
+		return "", "", invalid
+	}
+
+	if strings.HasPrefix(domain, "[") && strings.HasSuffix(domain, "]") {
+		literal := strings.TrimSuffix(strings.TrimPrefix(domain, "["), "]")
+		if strings.HasPrefix(strings.ToLower(literal), "ipv6:") {
+			ip, err := netip.ParseAddr(literal[5:])
+			if err != nil || !ip.Is6() {
+				return "", "", invalid
+			}
+		} else if ip, err := netip.ParseAddr(literal); err != nil || !ip.Is4() {
+			return "", "", invalid
+		}
+
+		return local, domain, nil
+	}
+
+	asciiDomain, err := idna.Lookup.ToASCII(domain)
+	if err != nil || len(asciiDomain) > 255 {
+		return "", "", invalid
+	}
+
+	labels := strings.Split(asciiDomain, ".")
+	if len(labels) < 2 {
+		return "", "", invalid
+	}
+	for _, label := range labels {
+		if !smtpDomainLabelPattern.MatchString(label) {
+			return "", "", invalid
+		}
+	}
+
+	return local, domain, nil
+}
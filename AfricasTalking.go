@@ -0,0 +1,333 @@
+package messagingutilities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Errors of messages rejected by Africa's Talking, wrapped by
+// AfricasTalkingError.
+var (
+	ErrInvalidSenderID     = errors.New("Invalid sender ID")
+	ErrInvalidPhoneNumber  = errors.New("Invalid phone number")
+	ErrInsufficientBalance = errors.New("Insufficient account balance")
+	ErrRecipientBlocked    = errors.New("Recipient does not accept messages from this sender")
+)
+
+// AfricasTalkingError is the status of a receiver the message was not sent
+// to. It wraps one of the errors above when the status code has a matching
+// error, so callers can test for them with errors.Is.
+type AfricasTalkingError struct {
+	Number     string
+	Status     string
+	StatusCode int
+}
+
+func (err *AfricasTalkingError) Error() string {
+	return fmt.Sprintf("Africa's talking could not send the message (%d): %s", err.StatusCode, err.Status)
+}
+
+func (err *AfricasTalkingError) Unwrap() error {
+	switch err.StatusCode {
+	case 402:
+		return ErrInvalidSenderID
+	case 403, 404:
+		return ErrInvalidPhoneNumber
+	case 405:
+		return ErrInsufficientBalance
+	case 406, 409:
+		return ErrRecipientBlocked
+	}
+
+	return nil
+}
+
+// err returns nil for the codes of processed, sent and queued messages.
+func (recipient *atSmsResponseRecipient) err() error {
+	switch {
+	case recipient.StatusCode >= 100 && recipient.StatusCode <= 102:
+		return nil
+	case recipient.StatusCode == 0 && recipient.Status == "Success":
+		return nil
+	}
+
+	return &AfricasTalkingError{Number: recipient.Number, Status: recipient.Status, StatusCode: recipient.StatusCode}
+}
+
+func (recipient *atSmsResponseRecipient) fill(result *RecipientResult) {
+	// Rejected receivers are reported with the message id "None".
+	if recipient.MessageID != "None" {
+		result.MessageID = recipient.MessageID
+	}
+	result.Status = recipient.Status
+	result.Cost = africasTalkingCost(recipient.Cost)
+}
+
+// africasTalkingCost turns costs such as "KES 0.8000" into the "0.8000 KES"
+// format used by the other senders, and drops zero costs.
+func africasTalkingCost(cost string) string {
+	currency, amount, found := strings.Cut(strings.TrimSpace(cost), " ")
+	if !found {
+		if cost == "0" {
+			return ""
+		}
+		return cost
+	}
+
+	if value, err := strconv.ParseFloat(amount, 64); err == nil && value == 0 {
+		return ""
+	}
+
+	return amount + " " + currency
+}
+
+// AfricasTalkingPremiumOptions are the parameters of premium SMS, sent to
+// the subscribers of a premium product from its short code.
+type AfricasTalkingPremiumOptions struct {
+	Keyword string
+	// LinkID is the LinkID of the inbound message being replied to, required
+	// by on-demand services.
+	LinkID string
+	// BulkSMSMode defaults to true, billing the sender. Set it to false to
+	// bill the subscriber.
+	BulkSMSMode *bool
+	// Enqueue queues the messages when sending large batches.
+	Enqueue bool
+	// RetryDurationInHours is how long delivery of a subscription message is
+	// retried.
+	RetryDurationInHours int
+}
+
+func (options *AfricasTalkingPremiumOptions) setParameters(payload url.Values) {
+	if options.Keyword != "" {
+		payload.Set("keyword", options.Keyword)
+	}
+	if options.LinkID != "" {
+		payload.Set("linkId", options.LinkID)
+	}
+	if options.BulkSMSMode != nil {
+		payload.Set("bulkSMSMode", "0")
+		if *options.BulkSMSMode {
+			payload.Set("bulkSMSMode", "1")
+		}
+	}
+	if options.Enqueue {
+		payload.Set("enqueue", "1")
+	}
+	if options.RetryDurationInHours > 0 {
+		payload.Set("retryDurationInHours", strconv.Itoa(options.RetryDurationInHours))
+	}
+}
+
+// africasTalkingMaxRecipients is the number of receivers sent in a single
+// bulk request, as recommended by Africa's Talking.
+const africasTalkingMaxRecipients = 1000
+
+// SendBulk sends the text of message to all of its receivers in as few
+// requests as possible, rather than one request per receiver like Send. The
+// status of each receiver is reported in PerRecipient.
+func (sender *AfricasTalkingSender) SendBulk(ctx context.Context, message *Message) (*SendResult, error) {
+	if err := checkChannel(message, ChannelSMS); err != nil {
+		return nil, err
+	}
+
+	message, err := suppressRecipients(ctx, ChannelSMS, message)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := prepareSmsText(&message.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(message.To) == 0 {
+		return nil, &ValidationError{Field: "to", Message: "Receivers cannot be empty"}
+	}
+
+	result := &SendResult{Provider: "africastalking", Recipients: []string{}}
+	errs := []error{}
+	for start := 0; start < len(message.To); start += africasTalkingMaxRecipients {
+		if err := ctx.Err(); err != nil {
+			for _, skipped := range message.To[start:] {
+				result.PerRecipient = append(result.PerRecipient, RecipientResult{
+					Recipient: skipped,
+					Error:     ErrNotAttempted.Error(),
+				})
+				errs = append(errs, fmt.Errorf("%s: %w", skipped, ErrNotAttempted))
+			}
+			errs = append(errs, err)
+			break
+		}
+
+		receivers := message.To[start:min(start+africasTalkingMaxRecipients, len(message.To))]
+		results, err := sendAfricasTalkingSmsChunk(ctx, sender.Credentials, sender.Premium, text, receivers)
+		for _, recipientResult := range results {
+			result.PerRecipient = append(result.PerRecipient, recipientResult)
+			if recipientResult.Error == "" {
+				result.Recipients = append(result.Recipients, recipientResult.Recipient)
+			} else if err == nil {
+				errs = append(errs, fmt.Errorf("%s: %s", recipientResult.Recipient, recipientResult.Error))
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// SendAfricasTalkingBulkSms sends message to all receivers, in one request
+// per 1000 receivers.
+func SendAfricasTalkingBulkSms(
+	ctx context.Context,
+	credentials *AfricasTalkingCredentials,
+	message *string,
+	receivers []string,
+) (*SendResult, error) {
+	builder := NewSMS().To(receivers...)
+	if message != nil {
+		builder.Text(*message)
+	}
+
+	return NewAfricasTalkingSender(credentials).SendBulk(ctx, builder.Build())
+}
+
+// sendAfricasTalkingSmsChunk returns a result for every receiver. When the
+// request itself fails, each result carries its error.
+func sendAfricasTalkingSmsChunk(
+	ctx context.Context,
+	credentials *AfricasTalkingCredentials,
+	premium *AfricasTalkingPremiumOptions,
+	text string,
+	receivers []string,
+) ([]RecipientResult, error) {
+	results := make([]RecipientResult, len(receivers))
+	for index, receiver := range receivers {
+		results[index].Recipient = receiver
+	}
+
+	atResp, responseBody, err := postAfricasTalkingSms(ctx, credentials, premium, text, receivers)
+	if err != nil {
+		for index := range results {
+			results[index].Error = err.Error()
+			results[index].Raw = string(responseBody)
+			DefaultStats.RecordResult("africastalking", "sms", err)
+		}
+		return results, err
+	}
+
+	// Recipients are matched by number, as Africa's Talking does not
+	// guarantee they are returned in the order sent, and returns them in
+	// E.164 form whatever form they were sent in.
+	recipients := map[string][]atSmsResponseRecipient{}
+	for _, recipient := range atResp.SMSMessageData.Recipients {
+		number := phoneNumberKey(recipient.Number)
+		recipients[number] = append(recipients[number], recipient)
+	}
+
+	for index := range results {
+		number := phoneNumberKey(results[index].Recipient)
+		if national, ok := strings.CutPrefix(number, "0"); ok && len(recipients[number]) == 0 {
+			// Without DefaultPhoneCountryCode, local numbers are matched
+			// by the national number that ends the returned one.
+			for returned, matches := range recipients {
+				if len(matches) > 0 && strings.HasSuffix(returned, national) {
+					number = returned
+					break
+				}
+			}
+		}
+		matches := recipients[number]
+
+		var err error
+		if len(matches) == 0 {
+			err = fmt.Errorf("Receiver is missing from the response")
+		} else {
+			matches[0].fill(&results[index])
+			err = matches[0].err()
+			recipients[number] = matches[1:]
+		}
+		if err != nil {
+			results[index].Error = err.Error()
+		}
+		DefaultStats.RecordResult("africastalking", "sms", err)
+	}
+
+	return results, nil
+}
+
+// AfricasTalkingInboundOptions configures the handler of the incoming
+// messages callback of a short code or keyword.
+type AfricasTalkingInboundOptions struct {
+	// OnMessage is called with every message received. An error makes the
+	// handler answer with a server error so that the callback is retried.
+	OnMessage func(ctx context.Context, message InboundSMS) error
+	// Auth is required, as Africa's Talking does not sign its callbacks and
+	// anyone who can reach the handler could otherwise opt any number out.
+	Auth         *WebhookAuth
+	MaxBodyBytes int64
+}
+
+type AfricasTalkingInboundHandler struct {
+	options AfricasTalkingInboundOptions
+}
+
+func NewAfricasTalkingInboundHandler(options AfricasTalkingInboundOptions) (*AfricasTalkingInboundHandler, error) {
+	if options.OnMessage == nil {
+		return nil, fmt.Errorf("A message callback is required")
+	}
+
+	if !options.Auth.configured() {
+		return nil, fmt.Errorf("Africa's talking callbacks require a secret, basic auth or allowed networks")
+	}
+
+	if options.MaxBodyBytes <= 0 {
+		options.MaxBodyBytes = 1 << 20
+	}
+
+	return &AfricasTalkingInboundHandler{options: options}, nil
+}
+
+func (handler *AfricasTalkingInboundHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(writer, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !handler.options.Auth.authorize(writer, request) {
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, handler.options.MaxBodyBytes))
+	if err != nil {
+		http.Error(writer, "Could not read request body", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(writer, "Invalid form payload", http.StatusBadRequest)
+		return
+	}
+
+	message := ParseAfricasTalkingInboundSMS(form)
+	if message.From == "" {
+		http.Error(writer, "Missing sender", http.StatusBadRequest)
+		return
+	}
+
+	if err := handler.options.OnMessage(request.Context(), message); err != nil {
+		http.Error(writer, "Could not process message", http.StatusInternalServerError)
+		return
+	}
+
+	writer.WriteHeader(http.StatusOK)
+}
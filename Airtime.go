@@ -0,0 +1,161 @@
+package messagingutilities
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// AirtimeRecipient is a top-up of Amount in CurrencyCode, such as "KES",
+// for PhoneNumber.
+type AirtimeRecipient struct {
+	PhoneNumber  string
+	CurrencyCode string
+	Amount       float64
+}
+
+type AirtimeResult struct {
+	PhoneNumber  string `json:"phoneNumber"`
+	RequestID    string `json:"requestId,omitempty"`
+	Status       string `json:"status"`
+	Amount       string `json:"amount,omitempty"`
+	Discount     string `json:"discount,omitempty"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
+}
+
+type AirtimeResponse struct {
+	NumSent       int             `json:"numSent"`
+	TotalAmount   string          `json:"totalAmount,omitempty"`
+	TotalDiscount string          `json:"totalDiscount,omitempty"`
+	Results       []AirtimeResult `json:"results"`
+	Raw           string          `json:"raw,omitempty"`
+}
+
+type atAirtimeRecipient struct {
+	PhoneNumber string `json:"phoneNumber"`
+	Amount      string `json:"amount"`
+}
+
+type atAirtimeEntry struct {
+	PhoneNumber  string `json:"phoneNumber"`
+	ErrorMessage string `json:"errorMessage"`
+	Amount       string `json:"amount"`
+	Status       string `json:"status"`
+	RequestID    string `json:"requestId"`
+	Discount     string `json:"discount"`
+}
+
+type atAirtimeResponse struct {
+	ErrorMessage  string           `json:"errorMessage"`
+	NumSent       int              `json:"numSent"`
+	TotalAmount   string           `json:"totalAmount"`
+	TotalDiscount string           `json:"totalDiscount"`
+	Responses     []atAirtimeEntry `json:"responses"`
+}
+
+// SendAfricasTalkingAirtime tops up every recipient in a single request. The
+// response lists the outcome of each recipient, and the returned error
+// joins the failed ones.
+func SendAfricasTalkingAirtime(
+	ctx context.Context,
+	credentials *AfricasTalkingCredentials,
+	recipients []AirtimeRecipient,
+) (response *AirtimeResponse, err error) {
+	defer func() { DefaultStats.RecordResult("africastalking", "airtime", err) }()
+
+	if len(recipients) == 0 {
+		return nil, &ValidationError{Field: "recipients", Message: "Airtime recipients cannot be empty"}
+	}
+
+	atRecipients := []atAirtimeRecipient{}
+	for _, recipient := range recipients {
+		if recipient.PhoneNumber == "" {
+			return nil, &ValidationError{Field: "phoneNumber", Message: "Airtime phone number cannot be empty"}
+		}
+		if len(recipient.CurrencyCode) != 3 {
+			return nil, &ValidationError{Field: "currencyCode", Message: "Airtime currency code must have three letters"}
+		}
+		if recipient.Amount <= 0 {
+			return nil, &ValidationError{Field: "amount", Message: "Airtime amount must be positive"}
+		}
+
+		atRecipients = append(atRecipients, atAirtimeRecipient{
+			PhoneNumber: recipient.PhoneNumber,
+			Amount:      strings.ToUpper(recipient.CurrencyCode) + " " + strconv.FormatFloat(recipient.Amount, 'f', 2, 64),
+		})
+	}
+
+	encoded, err := json.Marshal(atRecipients)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode Africa's talking airtime request: %w", err)
+	}
+
+	payload := url.Values{}
+	payload.Set("username", credentials.username())
+	payload.Set("recipients", string(encoded))
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		credentials.url("api", "/version1/airtime/send"),
+		strings.NewReader(payload.Encode()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("apiKey", credentials.ApiKey)
+
+	_, responseBody, err := doProviderRequest("africastalking", "Africa's talking", request)
+	if err != nil {
+		return nil, err
+	}
+
+	var atResp atAirtimeResponse
+	if err := json.Unmarshal(responseBody, &atResp); err != nil {
+		return nil, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	response = &AirtimeResponse{
+		NumSent:       atResp.NumSent,
+		TotalAmount:   africasTalkingCost(atResp.TotalAmount),
+		TotalDiscount: africasTalkingCost(atResp.TotalDiscount),
+		Results:       []AirtimeResult{},
+		Raw:           string(responseBody),
+	}
+
+	if len(atResp.Responses) == 0 && atResp.ErrorMessage != "" && atResp.ErrorMessage != "None" {
+		return response, fmt.Errorf("Africa's talking rejected the airtime request: %s", atResp.ErrorMessage)
+	}
+
+	errs := []error{}
+	for _, entry := range atResp.Responses {
+		result := AirtimeResult{
+			PhoneNumber: entry.PhoneNumber,
+			Status:      entry.Status,
+			Amount:      africasTalkingCost(entry.Amount),
+			Discount:    africasTalkingCost(entry.Discount),
+		}
+		if entry.RequestID != "None" {
+			result.RequestID = entry.RequestID
+		}
+		if entry.ErrorMessage != "" && entry.ErrorMessage != "None" {
+			result.ErrorMessage = entry.ErrorMessage
+		}
+		response.Results = append(response.Results, result)
+
+		// Accepted top-ups are reported as "Sent" and completed asynchronously.
+		if entry.Status != "Sent" && entry.Status != "Success" {
+			errs = append(errs, fmt.Errorf("%s: Airtime could not be sent: %s", entry.PhoneNumber, entry.ErrorMessage))
+		}
+	}
+
+	return response, errors.Join(errs...)
+}
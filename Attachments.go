@@ -0,0 +1,97 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+func AttachmentFromBytes(name string, data []byte) EmailAttachment {
+	return EmailAttachment{
+		Data:        bytes.NewReader(data),
+		Name:        &name,
+		ContentType: mime.TypeByExtension(filepath.Ext(name)),
+	}
+}
+
+// AttachmentFromFile reads the file into memory so no handle is left open
+// if the message is never sent.
+func AttachmentFromFile(filePath string) (EmailAttachment, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return EmailAttachment{}, fmt.Errorf("Failed to read attachment: %w", err)
+	}
+
+	return AttachmentFromBytes(filepath.Base(filePath), data), nil
+}
+
+// MaxAttachmentDownloadSize caps the bytes AttachmentFromURL reads from a
+// response. Zero or less disables the limit.
+var MaxAttachmentDownloadSize int64 = 25 << 20
+
+// AttachmentFromURL downloads an attachment. The name comes from the
+// Content-Disposition filename or the last path segment, and the content
+// type from the response when the server sends one. Bodies over
+// MaxAttachmentDownloadSize fail with a MessageSizeError.
+func AttachmentFromURL(ctx context.Context, rawURL string) (EmailAttachment, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return EmailAttachment{}, fmt.Errorf("Invalid attachment URL: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "GET", parsed.String(), nil)
+	if err != nil {
+		return EmailAttachment{}, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	response, err := newProviderHTTPClient("attachment").Do(request)
+	if err != nil {
+		return EmailAttachment{}, fmt.Errorf("Failed to download attachment: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return EmailAttachment{}, fmt.Errorf("Attachment download failed with status %d", response.StatusCode)
+	}
+
+	name := path.Base(parsed.Path)
+	if _, params, err := mime.ParseMediaType(response.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		name = path.Base(params["filename"])
+	}
+	if name == "" || name == "." || name == "/" {
+		name = "attachment"
+	}
+
+	limit := MaxAttachmentDownloadSize
+	if limit > 0 && response.ContentLength > limit {
+		return EmailAttachment{}, &MessageSizeError{Attachment: name, Size: response.ContentLength, Limit: limit}
+	}
+
+	reader := io.Reader(response.Body)
+	if limit > 0 {
+		reader = io.LimitReader(reader, limit+1)
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return EmailAttachment{}, fmt.Errorf("Failed to download attachment: %w", err)
+	}
+	if limit > 0 && int64(len(data)) > limit {
+		return EmailAttachment{}, &MessageSizeError{Attachment: name, Size: int64(len(data)), Limit: limit}
+	}
+
+	attachment := AttachmentFromBytes(name, data)
+	mediaType, _, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	if err == nil && (mediaType != "application/octet-stream" || attachment.ContentType == "") {
+		attachment.ContentType = mediaType
+	}
+
+	return attachment, nil
+}
@@ -0,0 +1,162 @@
+package messagingutilities
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var ErrNotAttempted = errors.New("Message was not attempted")
+
+type BatchItemResult struct {
+	Index     int
+	Receiver  string
+	Attempted bool
+	Err       error
+}
+
+type BatchResult struct {
+	Items        []BatchItemResult
+	Sent         int
+	Failed       int
+	NotAttempted int
+	Err          error
+}
+
+func (result *BatchResult) SentReceivers() []string {
+	return result.receivers(func(item *BatchItemResult) bool {
+		return item.Attempted && item.Err == nil
+	})
+}
+
+func (result *BatchResult) FailedReceivers() []string {
+	return result.receivers(func(item *BatchItemResult) bool {
+		return item.Attempted && item.Err != nil
+	})
+}
+
+func (result *BatchResult) NotAttemptedReceivers() []string {
+	return result.receivers(func(item *BatchItemResult) bool {
+		return !item.Attempted
+	})
+}
+
+func (result *BatchResult) receivers(match func(*BatchItemResult) bool) []string {
+	receivers := []string{}
+	for index := range result.Items {
+		if match(&result.Items[index]) {
+			receivers = append(receivers, result.Items[index].Receiver)
+		}
+	}
+
+	return receivers
+}
+
+func SendBatch(
+	ctx context.Context,
+	receivers []string,
+	concurrency int,
+	send func(ctx context.Context, receiver string) error,
+) *BatchResult {
+	return sendIndexedBatch(ctx, receivers, concurrency, func(ctx context.Context, index int) error {
+		return send(ctx, receivers[index])
+	})
+}
+
+// sendIndexedBatch passes the index of each receiver to send so callers can
+// look up per-receiver data even when receivers repeat.
+func sendIndexedBatch(
+	ctx context.Context,
+	receivers []string,
+	concurrency int,
+	send func(ctx context.Context, index int) error,
+) *BatchResult {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	result := &BatchResult{Items: make([]BatchItemResult, len(receivers))}
+	for index, receiver := range receivers {
+		result.Items[index] = BatchItemResult{
+			Index:    index,
+			Receiver: receiver,
+			Err:      ErrNotAttempted,
+		}
+	}
+
+	indexes := make(chan int)
+	waitGroup := sync.WaitGroup{}
+	for range concurrency {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for index := range indexes {
+				// An index handed over as the batch is cancelled is left
+				// not attempted rather than failed with the context error.
+				if ctx.Err() != nil {
+					continue
+				}
+				item := &result.Items[index]
+				item.Attempted = true
+				item.Err = send(ctx, index)
+			}
+		}()
+	}
+
+feed:
+	for index := range receivers {
+		if ctx.Err() != nil {
+			break
+		}
+
+		select {
+		case indexes <- index:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	waitGroup.Wait()
+
+	for index := range result.Items {
+		item := &result.Items[index]
+		switch {
+		case !item.Attempted:
+			result.NotAttempted++
+		case item.Err != nil:
+			result.Failed++
+		default:
+			result.Sent++
+		}
+	}
+
+	if result.NotAttempted > 0 {
+		result.Err = ctx.Err()
+	}
+
+	return result
+}
+
+func SendTwilioSmsBatch(
+	ctx context.Context,
+	credentials *TwilioCredentials,
+	message *string,
+	receivers []string,
+	concurrency int,
+) *BatchResult {
+	return SendBatch(ctx, receivers, concurrency, func(ctx context.Context, receiver string) error {
+		return SendTwilioSmsMessageContext(ctx, credentials, message, &receiver)
+	})
+}
+
+func SendAfricasTalkingSmsBatch(
+	ctx context.Context,
+	credentials *AfricasTalkingCredentials,
+	message *string,
+	receivers []string,
+	concurrency int,
+) *BatchResult {
+	return SendBatch(ctx, receivers, concurrency, func(ctx context.Context, receiver string) error {
+		return SendAfricasTalkingSmsMessageContext(ctx, credentials, message, &receiver)
+	})
+}
@@ -0,0 +1,165 @@
+package messagingutilities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func batchReceivers(count int) []string {
+	receivers := make([]string, count)
+	for index := range receivers {
+		receivers[index] = fmt.Sprintf("+2547000%05d", index)
+	}
+
+	return receivers
+}
+
+// checkBatchAccounting verifies that every receiver is reported exactly
+// once, as sent, failed or not attempted, and that exactly the attempted
+// receivers were passed to send.
+func checkBatchAccounting(t *testing.T, result *BatchResult, receivers []string, calls map[string]int) {
+	t.Helper()
+
+	if len(result.Items) != len(receivers) {
+		t.Fatalf("got %d items, want %d", len(result.Items), len(receivers))
+	}
+	if total := result.Sent + result.Failed + result.NotAttempted; total != len(receivers) {
+		t.Fatalf("sent %d + failed %d + not attempted %d = %d, want %d",
+			result.Sent, result.Failed, result.NotAttempted, total, len(receivers))
+	}
+
+	seen := map[string]string{}
+	record := func(state string, list []string) {
+		for _, receiver := range list {
+			if previous, ok := seen[receiver]; ok {
+				t.Errorf("%s reported both %s and %s", receiver, previous, state)
+			}
+			seen[receiver] = state
+		}
+	}
+	record("sent", result.SentReceivers())
+	record("failed", result.FailedReceivers())
+	record("not attempted", result.NotAttemptedReceivers())
+	if len(seen) != len(receivers) {
+		t.Errorf("got %d reported receivers, want %d", len(seen), len(receivers))
+	}
+
+	for _, item := range result.Items {
+		if item.Attempted != (calls[item.Receiver] == 1) {
+			t.Errorf("%s attempted %t but sent %d times", item.Receiver, item.Attempted, calls[item.Receiver])
+		}
+		if !item.Attempted && !errors.Is(item.Err, ErrNotAttempted) {
+			t.Errorf("%s not attempted with error %v", item.Receiver, item.Err)
+		}
+	}
+}
+
+func TestSendBatchCancellation(t *testing.T) {
+	const count = 200
+
+	for _, concurrency := range []int{1, 4, 16} {
+		for _, cancelAfter := range []int{0, 1, 50, count - 1, count} {
+			t.Run(fmt.Sprintf("concurrency=%d/cancelAfter=%d", concurrency, cancelAfter), func(t *testing.T) {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				if cancelAfter == 0 {
+					cancel()
+				}
+
+				receivers := batchReceivers(count)
+				callsMutex := sync.Mutex{}
+				calls := map[string]int{}
+				sent := atomic.Int64{}
+
+				result := SendBatch(ctx, receivers, concurrency, func(ctx context.Context, receiver string) error {
+					callsMutex.Lock()
+					calls[receiver]++
+					callsMutex.Unlock()
+
+					if sent.Add(1) == int64(cancelAfter) {
+						cancel()
+					}
+					if receiver == receivers[3] {
+						return errors.New("Rejected")
+					}
+					return nil
+				})
+
+				checkBatchAccounting(t, result, receivers, calls)
+
+				if cancelAfter == 0 && result.NotAttempted != count {
+					t.Errorf("got %d not attempted after cancelling up front, want %d", result.NotAttempted, count)
+				}
+				if cancelAfter < count && result.NotAttempted > 0 && !errors.Is(result.Err, context.Canceled) {
+					t.Errorf("got batch error %v, want context.Canceled", result.Err)
+				}
+				if cancelAfter == count && result.NotAttempted != 0 {
+					t.Errorf("got %d not attempted after sending all, want 0", result.NotAttempted)
+				}
+			})
+		}
+	}
+}
+
+func TestSendEachMessageCancellation(t *testing.T) {
+	const count = 20
+
+	for _, cancelAfter := range []int{0, 1, 10, count} {
+		t.Run(fmt.Sprintf("cancelAfter=%d", cancelAfter), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if cancelAfter == 0 {
+				cancel()
+			}
+
+			receivers := batchReceivers(count)
+			calls := map[string]int{}
+			message := NewSMS().To(receivers...).Text("Hello").Build()
+
+			result, err := sendEachMessage(ctx, ChannelSMS, "test", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+				calls[receiver]++
+				if len(calls) == cancelAfter {
+					cancel()
+				}
+				return RecipientResult{MessageID: "id-" + receiver}, nil
+			})
+
+			if len(result.PerRecipient) != count {
+				t.Fatalf("got %d recipient results, want %d", len(result.PerRecipient), count)
+			}
+
+			sent := map[string]bool{}
+			for _, receiver := range result.Recipients {
+				sent[receiver] = true
+			}
+
+			notAttempted := 0
+			for _, recipient := range result.PerRecipient {
+				skipped := recipient.Error == ErrNotAttempted.Error()
+				if skipped {
+					notAttempted++
+				}
+				if skipped && (sent[recipient.Recipient] || calls[recipient.Recipient] > 0) {
+					t.Errorf("%s reported both sent and not attempted", recipient.Recipient)
+				}
+				if !skipped && calls[recipient.Recipient] != 1 {
+					t.Errorf("%s reported attempted but sent %d times", recipient.Recipient, calls[recipient.Recipient])
+				}
+			}
+
+			if want := count - cancelAfter; notAttempted != want {
+				t.Errorf("got %d not attempted, want %d", notAttempted, want)
+			}
+			if notAttempted > 0 && (!errors.Is(err, ErrNotAttempted) || !errors.Is(err, context.Canceled)) {
+				t.Errorf("got error %v, want ErrNotAttempted and context.Canceled", err)
+			}
+			if notAttempted == 0 && err != nil {
+				t.Errorf("got error %v, want nil", err)
+			}
+		})
+	}
+}
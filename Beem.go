@@ -0,0 +1,109 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// BeemCredentials sends from SourceAddress, a registered sender name.
+type BeemCredentials struct {
+	APIKey        string
+	SecretKey     string
+	SourceAddress string
+}
+
+type BeemSender struct {
+	Credentials *BeemCredentials
+	BaseURL     string
+}
+
+func NewBeemSender(credentials *BeemCredentials) *BeemSender {
+	return &BeemSender{
+		Credentials: credentials,
+		BaseURL:     "https://apisms.beem.africa",
+	}
+}
+
+type beemRecipient struct {
+	RecipientID int    `json:"recipient_id"`
+	DestAddress string `json:"dest_addr"`
+}
+
+type beemRequest struct {
+	SourceAddress string          `json:"source_addr"`
+	Encoding      int             `json:"encoding"`
+	Message       string          `json:"message"`
+	Recipients    []beemRecipient `json:"recipients"`
+}
+
+type beemResponse struct {
+	Successful bool   `json:"successful"`
+	RequestID  int64  `json:"request_id"`
+	Code       int    `json:"code"`
+	Message    string `json:"message"`
+	Valid      int    `json:"valid"`
+	Invalid    int    `json:"invalid"`
+}
+
+func (sender *BeemSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	return sendEachSms(ctx, "beem", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sender.send(ctx, text, receiver)
+	})
+}
+
+func (sender *BeemSender) send(ctx context.Context, text, receiver string) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("beem", "sms", err) }()
+
+	// Beem expects numbers in international format without the leading plus
+	// sign.
+	body, err := json.Marshal(beemRequest{
+		SourceAddress: sender.Credentials.SourceAddress,
+		Message:       text,
+		Recipients: []beemRecipient{{
+			RecipientID: 1,
+			DestAddress: strings.TrimPrefix(strings.TrimSpace(receiver), "+"),
+		}},
+	})
+	if err != nil {
+		return result, fmt.Errorf("Failed to encode Beem request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		strings.TrimSuffix(sender.BaseURL, "/")+"/v1/send",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return result, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	request.SetBasicAuth(sender.Credentials.APIKey, sender.Credentials.SecretKey)
+
+	_, responseBody, err := doProviderRequest("beem", "Beem", request)
+	result.Raw = string(responseBody)
+	if err != nil {
+		return result, err
+	}
+
+	var beemResp beemResponse
+	if err := json.Unmarshal(responseBody, &beemResp); err != nil {
+		return result, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	if !beemResp.Successful || beemResp.Valid == 0 {
+		return result, fmt.Errorf("Beem rejected the message (%d): %s", beemResp.Code, beemResp.Message)
+	}
+
+	result.MessageID = strconv.FormatInt(beemResp.RequestID, 10)
+	result.Status = beemResp.Message
+
+	return result, nil
+}
@@ -0,0 +1,444 @@
+package messagingutilities
+
+import (
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"strings"
+	"time"
+)
+
+type MessageBuilder struct {
+	message Message
+}
+
+func NewEmail() *MessageBuilder {
+	return &MessageBuilder{message: Message{Channel: ChannelEmail}}
+}
+
+func NewSMS() *MessageBuilder {
+	return &MessageBuilder{message: Message{Channel: ChannelSMS}}
+}
+
+func NewWhatsApp() *MessageBuilder {
+	return &MessageBuilder{message: Message{Channel: ChannelWhatsApp}}
+}
+
+func NewChat() *MessageBuilder {
+	return &MessageBuilder{message: Message{Channel: ChannelChat}}
+}
+
+func NewPush() *MessageBuilder {
+	return &MessageBuilder{message: Message{Channel: ChannelPush}}
+}
+
+func (builder *MessageBuilder) To(receivers ...string) *MessageBuilder {
+	builder.message.To = append(builder.message.To, receivers...)
+	return builder
+}
+
+func (builder *MessageBuilder) Cc(receivers ...string) *MessageBuilder {
+	builder.message.Cc = append(builder.message.Cc, receivers...)
+	return builder
+}
+
+func (builder *MessageBuilder) Bcc(receivers ...string) *MessageBuilder {
+	builder.message.Bcc = append(builder.message.Bcc, receivers...)
+	return builder
+}
+
+func (builder *MessageBuilder) ReplyTo(address string) *MessageBuilder {
+	builder.message.ReplyTo = address
+	return builder
+}
+
+func (builder *MessageBuilder) MessageID(id string) *MessageBuilder {
+	builder.message.MessageID = id
+	return builder
+}
+
+// InReplyTo threads the message as a reply to the message with id, which is
+// also used as References unless those are set.
+func (builder *MessageBuilder) InReplyTo(id string) *MessageBuilder {
+	builder.message.InReplyTo = id
+	return builder
+}
+
+func (builder *MessageBuilder) References(ids ...string) *MessageBuilder {
+	builder.message.References = append(builder.message.References, ids...)
+	return builder
+}
+
+func (builder *MessageBuilder) Header(name string, values ...string) *MessageBuilder {
+	if builder.message.Headers == nil {
+		builder.message.Headers = map[string][]string{}
+	}
+	builder.message.Headers[name] = append(builder.message.Headers[name], values...)
+	return builder
+}
+
+func (builder *MessageBuilder) Subject(subject string) *MessageBuilder {
+	builder.message.Subject = subject
+	return builder
+}
+
+func (builder *MessageBuilder) Text(text string) *MessageBuilder {
+	builder.message.Text = text
+	return builder
+}
+
+func (builder *MessageBuilder) HTML(html string) *MessageBuilder {
+	builder.message.HTML = html
+	return builder
+}
+
+// AMP sets an AMP for Email document, sent as a text/x-amp-html part
+// alongside the HTML body, which is required as a fallback.
+func (builder *MessageBuilder) AMP(amp string) *MessageBuilder {
+	builder.message.AMP = amp
+	return builder
+}
+
+func (builder *MessageBuilder) Category(category MessageCategory) *MessageBuilder {
+	builder.message.Category = category
+	return builder
+}
+
+func (builder *MessageBuilder) Tag(tags ...string) *MessageBuilder {
+	builder.message.Tags = append(builder.message.Tags, tags...)
+	return builder
+}
+
+func (builder *MessageBuilder) Metadata(key, value string) *MessageBuilder {
+	if builder.message.Metadata == nil {
+		builder.message.Metadata = map[string]string{}
+	}
+	builder.message.Metadata[key] = value
+	return builder
+}
+
+func (builder *MessageBuilder) SendAt(at time.Time) *MessageBuilder {
+	builder.message.SendAt = &at
+	return builder
+}
+
+func (builder *MessageBuilder) Attach(name string, data io.Reader) *MessageBuilder {
+	builder.message.Attachments = append(builder.message.Attachments, EmailAttachment{
+		Name: &name,
+		Data: data,
+	})
+	return builder
+}
+
+// Embed adds an inline attachment that HTML can reference as "cid:<name>".
+func (builder *MessageBuilder) Embed(name string, data io.Reader) *MessageBuilder {
+	builder.message.Inline = append(builder.message.Inline, InlineAttachment{
+		Name: name,
+		Data: data,
+	})
+	return builder
+}
+
+func (builder *MessageBuilder) InlineAttachments(attachments ...InlineAttachment) *MessageBuilder {
+	builder.message.Inline = append(builder.message.Inline, attachments...)
+	return builder
+}
+
+// AttachVCard attaches contact as a vCard, so recipients can add it to
+// their address book.
+func (builder *MessageBuilder) AttachVCard(contact Contact) *MessageBuilder {
+	name := contact.vCardFileName()
+	builder.message.Attachments = append(builder.message.Attachments, EmailAttachment{
+		Name:        &name,
+		Data:        strings.NewReader(contact.VCard()),
+		ContentType: "text/vcard; charset=utf-8",
+	})
+	return builder
+}
+
+func (builder *MessageBuilder) Attachments(attachments ...EmailAttachment) *MessageBuilder {
+	builder.message.Attachments = append(builder.message.Attachments, attachments...)
+	return builder
+}
+
+// Media adds files to send as MMS, by URLs the provider can download.
+func (builder *MessageBuilder) Media(urls ...string) *MessageBuilder {
+	builder.message.MediaURLs = append(builder.message.MediaURLs, urls...)
+	return builder
+}
+
+func (builder *MessageBuilder) StatusCallback(url string) *MessageBuilder {
+	builder.message.StatusCallback = url
+	return builder
+}
+
+// ContentTemplate sends the provider template with sid, filling its numbered
+// placeholders from variables.
+func (builder *MessageBuilder) ContentTemplate(sid string, variables map[string]string) *MessageBuilder {
+	builder.message.ContentTemplate = &ContentTemplate{SID: sid, Variables: maps.Clone(variables)}
+	return builder
+}
+
+// WhatsAppTemplate sends the WhatsApp Cloud API template name in language,
+// such as "en_US", filling its parameters from components.
+func (builder *MessageBuilder) WhatsAppTemplate(name, language string, components ...TemplateComponent) *MessageBuilder {
+	builder.message.ContentTemplate = &ContentTemplate{Name: name, Language: language, Components: components}
+	return builder
+}
+
+// Payload sets key in the JSON body posted by chat and push senders, such
+// as "blocks" for Slack.
+func (builder *MessageBuilder) Payload(key string, value any) *MessageBuilder {
+	if builder.message.Payload == nil {
+		builder.message.Payload = map[string]any{}
+	}
+	builder.message.Payload[key] = value
+	return builder
+}
+
+func (builder *MessageBuilder) Priority(priority EmailPriority) *MessageBuilder {
+	builder.message.Priority = priority
+	return builder
+}
+
+func (builder *MessageBuilder) Calendar(event CalendarEvent) *MessageBuilder {
+	event.Attendees = append([]string(nil), event.Attendees...)
+	builder.message.Calendar = &event
+	return builder
+}
+
+func (builder *MessageBuilder) Build() *Message {
+	message := builder.message
+	message.To = append([]string{}, builder.message.To...)
+	message.Cc = append([]string(nil), builder.message.Cc...)
+	message.Bcc = append([]string(nil), builder.message.Bcc...)
+	message.Attachments = append([]EmailAttachment{}, builder.message.Attachments...)
+	message.Inline = append([]InlineAttachment(nil), builder.message.Inline...)
+	message.Tags = append([]string(nil), builder.message.Tags...)
+	message.References = append([]string(nil), builder.message.References...)
+	message.MediaURLs = append([]string(nil), builder.message.MediaURLs...)
+	message.Metadata = maps.Clone(builder.message.Metadata)
+	message.Payload = maps.Clone(builder.message.Payload)
+	message.Headers = maps.Clone(builder.message.Headers)
+	if builder.message.ContentTemplate != nil {
+		template := *builder.message.ContentTemplate
+		template.Variables = maps.Clone(template.Variables)
+		template.Components = append([]TemplateComponent(nil), template.Components...)
+		for index := range template.Components {
+			component := &template.Components[index]
+			component.Parameters = append([]TemplateParameter(nil), component.Parameters...)
+		}
+		message.ContentTemplate = &template
+	}
+	if builder.message.Calendar != nil {
+		calendar := *builder.message.Calendar
+		calendar.Attendees = append([]string(nil), calendar.Attendees...)
+		message.Calendar = &calendar
+	}
+	for name, values := range message.Headers {
+		message.Headers[name] = append([]string(nil), values...)
+	}
+
+	return &message
+}
+
+type sendOptions struct {
+	timeout       time.Duration
+	inlineCSS     bool
+	textFallback  bool
+	validator     *EmailValidator
+	openTracking  *TrackingHandler
+	clickTracking *TrackingHandler
+	unsubscribe   *UnsubscribeHandler
+	sendAt        *time.Time
+}
+
+type SendOption func(options *sendOptions)
+
+func WithTimeout(timeout time.Duration) SendOption {
+	return func(options *sendOptions) {
+		options.timeout = timeout
+	}
+}
+
+// WithInlineCSS runs InlineCSS over the HTML body before it is sent. The
+// caller's message is left unchanged.
+func WithInlineCSS() SendOption {
+	return func(options *sendOptions) {
+		options.inlineCSS = true
+	}
+}
+
+// WithTextFallback fills in a plain-text body generated by HTMLToText when a
+// message only carries HTML, so it is sent as multipart/alternative.
+func WithTextFallback() SendOption {
+	return func(options *sendOptions) {
+		options.textFallback = true
+	}
+}
+
+// WithAddressValidation checks the To, Cc and Bcc addresses of an email with
+// validator before sending and fails with ValidationErrors naming every
+// invalid address. DNS failures that do not prove an address invalid are
+// ignored so a resolver outage does not block sending.
+func WithAddressValidation(validator *EmailValidator) SendOption {
+	return func(options *sendOptions) {
+		options.validator = validator
+	}
+}
+
+// WithOpenTracking adds an open-tracking pixel served by tracker to the HTML
+// body of an email. The message is tracked under its "trackingId" metadata,
+// which is generated when missing.
+func WithOpenTracking(tracker *TrackingHandler) SendOption {
+	return func(options *sendOptions) {
+		options.openTracking = tracker
+	}
+}
+
+// WithClickTracking rewrites the links of an email's HTML body to redirect
+// through tracker, see TrackingHandler.RewriteLinks.
+func WithClickTracking(tracker *TrackingHandler) SendOption {
+	return func(options *sendOptions) {
+		options.clickTracking = tracker
+	}
+}
+
+// WithListUnsubscribe adds List-Unsubscribe headers generated by handler for
+// the message category to an email. The email must have a single To address.
+func WithListUnsubscribe(handler *UnsubscribeHandler) SendOption {
+	return func(options *sendOptions) {
+		options.unsubscribe = handler
+	}
+}
+
+// WithSendAt schedules the message for delivery at the given time with
+// providers that support scheduling, such as Twilio with a Messaging Service.
+func WithSendAt(at time.Time) SendOption {
+	return func(options *sendOptions) {
+		options.sendAt = &at
+	}
+}
+
+func Send(sender Sender, message *Message, options ...SendOption) (*SendResult, error) {
+	return SendContext(context.Background(), sender, message, options...)
+}
+
+// SendContext applies options to message and sends it with sender. A
+// WithTimeout option bounds the send within ctx.
+func SendContext(ctx context.Context, sender Sender, message *Message, options ...SendOption) (*SendResult, error) {
+	settings := newSendOptions(options)
+
+	if settings.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, settings.timeout)
+		defer cancel()
+	}
+
+	if settings.validator != nil && message != nil && message.Channel == ChannelEmail {
+		addresses := append(append(append([]string{}, message.To...), message.Cc...), message.Bcc...)
+		invalid := settings.validator.Invalid(ctx, addresses)
+
+		errs := ValidationErrors{}
+		for _, address := range addresses {
+			var validationError *ValidationError
+			if errors.As(invalid[address], &validationError) {
+				errs = append(errs, validationError)
+				delete(invalid, address)
+			}
+		}
+		if len(errs) > 0 {
+			return nil, errs
+		}
+	}
+
+	message, err := settings.prepareMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	return sender.Send(ctx, message)
+}
+
+func newSendOptions(options []SendOption) sendOptions {
+	settings := sendOptions{}
+	for _, option := range options {
+		option(&settings)
+	}
+
+	return settings
+}
+
+// prepareMessage applies the options that change the content of a message,
+// leaving the caller's message unchanged. PreviewEmail and RenderEML share
+// it with Send so that previews match what is sent.
+func (settings *sendOptions) prepareMessage(message *Message) (*Message, error) {
+	if settings.sendAt != nil && message != nil {
+		copied := *message
+		copied.SendAt = settings.sendAt
+		message = &copied
+	}
+
+	if settings.textFallback && message != nil && message.HTML != "" && message.Text == "" {
+		text, err := HTMLToText(message.HTML)
+		if err != nil {
+			return nil, err
+		}
+		copied := *message
+		copied.Text = text
+		message = &copied
+	}
+
+	if settings.inlineCSS && message != nil && message.HTML != "" {
+		inlined, err := InlineCSS(message.HTML)
+		if err != nil {
+			return nil, err
+		}
+		copied := *message
+		copied.HTML = inlined
+		message = &copied
+	}
+
+	if settings.unsubscribe != nil && message != nil && message.Channel == ChannelEmail {
+		if len(message.To) != 1 {
+			return nil, &ValidationError{Field: "to", Message: "List-Unsubscribe requires a single recipient"}
+		}
+
+		copied := *message
+		copied.Headers = maps.Clone(message.Headers)
+		if copied.Headers == nil {
+			copied.Headers = map[string][]string{}
+		}
+		maps.Copy(copied.Headers, settings.unsubscribe.Headers(message.To[0], message.Category))
+		message = &copied
+	}
+
+	if settings.clickTracking != nil && message != nil && message.Channel == ChannelEmail && message.HTML != "" {
+		copied := *message
+		messageID, err := trackingMessageID(&copied)
+		if err != nil {
+			return nil, err
+		}
+		copied.HTML, err = settings.clickTracking.RewriteLinks(copied.HTML, messageID, trackingRecipient(&copied))
+		if err != nil {
+			return nil, err
+		}
+		message = &copied
+	}
+
+	if settings.openTracking != nil && message != nil && message.Channel == ChannelEmail && message.HTML != "" {
+		copied := *message
+		messageID, err := trackingMessageID(&copied)
+		if err != nil {
+			return nil, err
+		}
+		copied.HTML, err = settings.openTracking.InjectOpenPixel(copied.HTML, messageID, trackingRecipient(&copied))
+		if err != nil {
+			return nil, err
+		}
+		message = &copied
+	}
+
+	return message, nil
+}
@@ -0,0 +1,344 @@
+package messagingutilities
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var cssCommentPattern = regexp.MustCompile(`(?s)/\*.*?\*/`)
+
+type cssCompoundSelector struct {
+	tag     string
+	id      string
+	classes []string
+}
+
+type cssSelector struct {
+	compounds   []cssCompoundSelector
+	combinators []byte
+	specificity [3]int
+}
+
+type cssRule struct {
+	selector     cssSelector
+	declarations [][2]string
+	order        int
+}
+
+// InlineCSS moves the rules of <style> blocks into style attributes of the
+// elements they match. Type, class, id, descendant and child selectors are
+// inlined; at-rules such as @media and rules using other selectors stay in a
+// <style> block since they cannot be expressed inline. Existing style
+// attributes take precedence over inlined rules.
+func InlineCSS(document string) (string, error) {
+	root, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return "", fmt.Errorf("Failed to parse html: %w", err)
+	}
+
+	rules := []cssRule{}
+	styleNodes := []*html.Node{}
+	for node := range root.Descendants() {
+		if node.Type == html.ElementNode && node.Data == "style" {
+			styleNodes = append(styleNodes, node)
+		}
+	}
+
+	for _, styleNode := range styleNodes {
+		text := ""
+		for child := range styleNode.ChildNodes() {
+			text += child.Data
+		}
+
+		parsed, remaining := parseCSSRules(text, len(rules))
+		rules = append(rules, parsed...)
+
+		for styleNode.FirstChild != nil {
+			styleNode.RemoveChild(styleNode.FirstChild)
+		}
+		if strings.TrimSpace(remaining) == "" {
+			styleNode.Parent.RemoveChild(styleNode)
+			continue
+		}
+		styleNode.AppendChild(&html.Node{Type: html.TextNode, Data: remaining})
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].selector.specificity != rules[j].selector.specificity {
+			return slices.Compare(rules[i].selector.specificity[:], rules[j].selector.specificity[:]) < 0
+		}
+		return rules[i].order < rules[j].order
+	})
+
+	for node := range root.Descendants() {
+		if node.Type != html.ElementNode {
+			continue
+		}
+
+		properties := []string{}
+		values := map[string]string{}
+		apply := func(declarations [][2]string) {
+			for _, declaration := range declarations {
+				if _, ok := values[declaration[0]]; !ok {
+					properties = append(properties, declaration[0])
+				}
+				values[declaration[0]] = declaration[1]
+			}
+		}
+
+		for _, rule := range rules {
+			if rule.selector.matches(node, len(rule.selector.compounds)-1) {
+				apply(rule.declarations)
+			}
+		}
+		if len(properties) == 0 {
+			continue
+		}
+
+		styleIndex := -1
+		for index, attribute := range node.Attr {
+			if attribute.Key == "style" {
+				styleIndex = index
+				apply(parseCSSDeclarations(attribute.Val))
+			}
+		}
+
+		declarations := []string{}
+		for _, property := range properties {
+			declarations = append(declarations, property+": "+values[property])
+		}
+		style := strings.Join(declarations, "; ")
+
+		if styleIndex >= 0 {
+			node.Attr[styleIndex].Val = style
+		} else {
+			node.Attr = append(node.Attr, html.Attribute{Key: "style", Val: style})
+		}
+	}
+
+	builder := &strings.Builder{}
+	if err := html.Render(builder, root); err != nil {
+		return "", fmt.Errorf("Failed to render html: %w", err)
+	}
+
+	return builder.String(), nil
+}
+
+// parseCSSRules returns the inlinable rules of a stylesheet and the text of
+// everything that has to stay in a <style> block.
+func parseCSSRules(stylesheet string, order int) ([]cssRule, string) {
+	stylesheet = cssCommentPattern.ReplaceAllString(stylesheet, "")
+
+	rules := []cssRule{}
+	remaining := &strings.Builder{}
+	for {
+		stylesheet = strings.TrimSpace(stylesheet)
+		if stylesheet == "" {
+			break
+		}
+
+		if strings.HasPrefix(stylesheet, "@") {
+			end := cssBlockEnd(stylesheet)
+			remaining.WriteString(stylesheet[:end] + "\n")
+			stylesheet = stylesheet[end:]
+			continue
+		}
+
+		open := strings.Index(stylesheet, "{")
+		if open < 0 {
+			break
+		}
+		closing := strings.Index(stylesheet[open:], "}")
+		if closing < 0 {
+			break
+		}
+		closing += open
+
+		selectorText := strings.TrimSpace(stylesheet[:open])
+		body := stylesheet[open+1 : closing]
+		stylesheet = stylesheet[closing+1:]
+
+		declarations := parseCSSDeclarations(body)
+		for _, selectorPart := range strings.Split(selectorText, ",") {
+			selector, ok := parseCSSSelector(strings.TrimSpace(selectorPart))
+			if !ok {
+				remaining.WriteString(strings.TrimSpace(selectorPart) + " {" + body + "}\n")
+				continue
+			}
+			rules = append(rules, cssRule{
+				selector:     selector,
+				declarations: declarations,
+				order:        order,
+			})
+			order++
+		}
+	}
+
+	return rules, remaining.String()
+}
+
+// cssBlockEnd returns the index just past an at-rule, which ends either at a
+// semicolon or at the brace closing its block.
+func cssBlockEnd(stylesheet string) int {
+	depth := 0
+	for index, character := range stylesheet {
+		switch character {
+		case ';':
+			if depth == 0 {
+				return index + 1
+			}
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return index + 1
+			}
+		}
+	}
+
+	return len(stylesheet)
+}
+
+func parseCSSDeclarations(body string) [][2]string {
+	declarations := [][2]string{}
+	for _, declaration := range strings.Split(body, ";") {
+		property, value, found := strings.Cut(declaration, ":")
+		property = strings.ToLower(strings.TrimSpace(property))
+		value = strings.TrimSpace(value)
+		if !found || property == "" || value == "" {
+			continue
+		}
+		declarations = append(declarations, [2]string{property, value})
+	}
+
+	return declarations
+}
+
+func parseCSSSelector(text string) (cssSelector, bool) {
+	selector := cssSelector{}
+	if text == "" || strings.ContainsAny(text, ":[*+~") {
+		return selector, false
+	}
+
+	text = strings.ReplaceAll(text, ">", " > ")
+	combinator := byte(0)
+	for _, token := range strings.Fields(text) {
+		if token == ">" {
+			if combinator != ' ' || len(selector.compounds) == 0 {
+				return selector, false
+			}
+			combinator = '>'
+			continue
+		}
+
+		compound, ok := parseCSSCompound(token)
+		if !ok {
+			return selector, false
+		}
+		if len(selector.compounds) > 0 {
+			selector.combinators = append(selector.combinators, combinator)
+		}
+		selector.compounds = append(selector.compounds, compound)
+		combinator = ' '
+
+		if compound.id != "" {
+			selector.specificity[0]++
+		}
+		selector.specificity[1] += len(compound.classes)
+		if compound.tag != "" {
+			selector.specificity[2]++
+		}
+	}
+
+	return selector, combinator == ' '
+}
+
+func parseCSSCompound(token string) (cssCompoundSelector, bool) {
+	compound := cssCompoundSelector{}
+
+	index := strings.IndexAny(token, ".#")
+	if index < 0 {
+		index = len(token)
+	}
+	compound.tag = strings.ToLower(token[:index])
+	token = token[index:]
+
+	for token != "" {
+		marker := token[0]
+		token = token[1:]
+		end := strings.IndexAny(token, ".#")
+		if end < 0 {
+			end = len(token)
+		}
+		name := token[:end]
+		token = token[end:]
+		if name == "" {
+			return compound, false
+		}
+
+		if marker == '#' {
+			compound.id = name
+		} else {
+			compound.classes = append(compound.classes, name)
+		}
+	}
+
+	return compound, true
+}
+
+func (selector cssSelector) matches(node *html.Node, index int) bool {
+	if node == nil || node.Type != html.ElementNode || !selector.compounds[index].matches(node) {
+		return false
+	}
+
+	if index == 0 {
+		return true
+	}
+
+	if selector.combinators[index-1] == '>' {
+		return selector.matches(node.Parent, index-1)
+	}
+
+	for ancestor := node.Parent; ancestor != nil; ancestor = ancestor.Parent {
+		if selector.matches(ancestor, index-1) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (compound cssCompoundSelector) matches(node *html.Node) bool {
+	if compound.tag != "" && compound.tag != node.Data {
+		return false
+	}
+
+	id := ""
+	classes := []string{}
+	for _, attribute := range node.Attr {
+		switch attribute.Key {
+		case "id":
+			id = attribute.Val
+		case "class":
+			classes = strings.Fields(attribute.Val)
+		}
+	}
+
+	if compound.id != "" && compound.id != id {
+		return false
+	}
+
+	for _, class := range compound.classes {
+		if !slices.Contains(classes, class) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,305 @@
+package messagingutilities
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"iter"
+	"strings"
+	"text/template"
+)
+
+type AddressKind string
+
+const (
+	AddressKindPhone AddressKind = "phone"
+	AddressKindEmail AddressKind = "email"
+)
+
+type ColumnMapping struct {
+	AddressColumn      string
+	AddressKind        AddressKind
+	MergeFields        []string
+	DefaultCountryCode string
+	Comma              rune
+	MaxRowErrors       int
+}
+
+type CSVRecipient struct {
+	Line        int
+	Address     string
+	MergeFields map[string]string
+}
+
+type CSVRowError struct {
+	Line int
+	Err  error
+}
+
+type CSVImportSummary struct {
+	RowsRead          int
+	Valid             int
+	Invalid           int
+	Duplicates        int
+	Sent              int
+	Failed            int
+	NotAttempted      int
+	RowErrors         []CSVRowError
+	DroppedRowErrors  int
+	SendErrors        []CSVRowError
+	DroppedSendErrors int
+	Err               error
+}
+
+func (summary *CSVImportSummary) addRowError(limit int, line int, err error) {
+	if len(summary.RowErrors) < limit {
+		summary.RowErrors = append(summary.RowErrors, CSVRowError{Line: line, Err: err})
+	} else {
+		summary.DroppedRowErrors++
+	}
+}
+
+// ImportRecipientsCSV streams recipients out of a CSV file with a header row.
+// Rows are validated and deduplicated as they are read and the summary is
+// updated in place, so it is complete once the returned sequence finishes.
+// Deduplication keeps a 64-bit hash per unique address rather than the address
+// itself.
+func ImportRecipientsCSV(r io.Reader, mapping ColumnMapping) (iter.Seq[CSVRecipient], *CSVImportSummary) {
+	summary := &CSVImportSummary{}
+	if mapping.MaxRowErrors <= 0 {
+		mapping.MaxRowErrors = 1000
+	}
+
+	sequence := func(yield func(CSVRecipient) bool) {
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.ReuseRecord = true
+		if mapping.Comma != 0 {
+			reader.Comma = mapping.Comma
+		}
+
+		header, err := reader.Read()
+		if err != nil {
+			summary.Err = fmt.Errorf("Failed to read CSV header: %w", err)
+			return
+		}
+
+		columns := map[string]int{}
+		for index, name := range header {
+			columns[strings.TrimSpace(name)] = index
+		}
+
+		addressIndex, ok := columns[mapping.AddressColumn]
+		if !ok {
+			summary.Err = fmt.Errorf("Address column %q not found in CSV header", mapping.AddressColumn)
+			return
+		}
+
+		mergeFields := mapping.MergeFields
+		if mergeFields == nil {
+			for _, name := range header {
+				if name = strings.TrimSpace(name); name != mapping.AddressColumn {
+					mergeFields = append(mergeFields, name)
+				}
+			}
+		}
+		for _, field := range mergeFields {
+			if _, ok := columns[field]; !ok {
+				summary.Err = fmt.Errorf("Merge field column %q not found in CSV header", field)
+				return
+			}
+		}
+
+		seen := map[uint64]struct{}{}
+		for {
+			record, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+
+			summary.RowsRead++
+
+			if err != nil {
+				var parseError *csv.ParseError
+				if !errors.As(err, &parseError) {
+					summary.Err = fmt.Errorf("Failed to read CSV: %w", err)
+					return
+				}
+				summary.Invalid++
+				summary.addRowError(mapping.MaxRowErrors, parseError.Line, err)
+				continue
+			}
+
+			line, _ := reader.FieldPos(0)
+			if addressIndex >= len(record) {
+				summary.Invalid++
+				summary.addRowError(mapping.MaxRowErrors, line, fmt.Errorf("Row is missing the address column"))
+				continue
+			}
+
+			var address string
+			if mapping.AddressKind == AddressKindEmail {
+				address, err = NormalizeEmailAddress(record[addressIndex])
+			} else {
+				address, err = NormalizePhoneNumber(record[addressIndex], mapping.DefaultCountryCode)
+			}
+			if err != nil {
+				summary.Invalid++
+				summary.addRowError(mapping.MaxRowErrors, line, err)
+				continue
+			}
+
+			hash := fnv.New64a()
+			hash.Write([]byte(address))
+			if _, duplicate := seen[hash.Sum64()]; duplicate {
+				summary.Duplicates++
+				continue
+			}
+			seen[hash.Sum64()] = struct{}{}
+
+			recipient := CSVRecipient{
+				Line:        line,
+				Address:     address,
+				MergeFields: make(map[string]string, len(mergeFields)),
+			}
+			for _, field := range mergeFields {
+				if index := columns[field]; index < len(record) {
+					recipient.MergeFields[field] = record[index]
+				}
+			}
+
+			summary.Valid++
+			if !yield(recipient) {
+				return
+			}
+		}
+	}
+
+	return sequence, summary
+}
+
+type CSVSendOptions struct {
+	Concurrency int
+	// ChunkSize is the number of recipients read ahead and sent as one
+	// batch, 1000 by default. Memory use grows with it rather than with the
+	// size of the file.
+	ChunkSize int
+	// Subject is the subject template of email messages, rendered with the
+	// same merge fields as the body.
+	Subject string
+}
+
+// SendFromCSV sends the rendered body template to every valid recipient of
+// the file, as an SMS or, for AddressKindEmail, an email. Recipients are
+// sent in chunks through the batch pipeline, so a cancelled ctx stops the
+// import after the current chunk, and its unsent recipients are counted as
+// not attempted.
+func SendFromCSV(
+	ctx context.Context,
+	sender Sender,
+	csvReader io.Reader,
+	bodyTemplate string,
+	mapping ColumnMapping,
+	options CSVSendOptions,
+) (*CSVImportSummary, error) {
+	body, err := template.New("body").Option("missingkey=error").Parse(bodyTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid message template: %w", err)
+	}
+
+	subject, err := template.New("subject").Option("missingkey=error").Parse(options.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid subject template: %w", err)
+	}
+
+	if options.Concurrency <= 0 {
+		options.Concurrency = 1
+	}
+	if options.ChunkSize <= 0 {
+		options.ChunkSize = 1000
+	}
+	if mapping.MaxRowErrors <= 0 {
+		mapping.MaxRowErrors = 1000
+	}
+
+	recipients, summary := ImportRecipientsCSV(csvReader, mapping)
+
+	chunk := make([]CSVRecipient, 0, options.ChunkSize)
+	receivers := make([]string, 0, options.ChunkSize)
+	sendChunk := func() {
+		receivers = receivers[:0]
+		for _, recipient := range chunk {
+			receivers = append(receivers, recipient.Address)
+		}
+
+		result := sendIndexedBatch(ctx, receivers, options.Concurrency, func(ctx context.Context, index int) error {
+			message, err := csvMessage(chunk[index], mapping.AddressKind, subject, body)
+			if err != nil {
+				return err
+			}
+
+			_, err = sender.Send(ctx, message)
+			return err
+		})
+
+		for _, item := range result.Items {
+			switch {
+			case !item.Attempted:
+				summary.NotAttempted++
+			case item.Err == nil:
+				summary.Sent++
+			default:
+				summary.Failed++
+				if len(summary.SendErrors) < mapping.MaxRowErrors {
+					summary.SendErrors = append(summary.SendErrors, CSVRowError{Line: chunk[item.Index].Line, Err: item.Err})
+				} else {
+					summary.DroppedSendErrors++
+				}
+			}
+		}
+
+		chunk = chunk[:0]
+	}
+
+	for recipient := range recipients {
+		chunk = append(chunk, recipient)
+		if len(chunk) < options.ChunkSize {
+			continue
+		}
+
+		sendChunk()
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if len(chunk) > 0 {
+		sendChunk()
+	}
+
+	if summary.Err == nil {
+		summary.Err = ctx.Err()
+	}
+
+	return summary, summary.Err
+}
+
+func csvMessage(recipient CSVRecipient, kind AddressKind, subject, body *template.Template) (*Message, error) {
+	text := strings.Builder{}
+	if err := body.Execute(&text, recipient.MergeFields); err != nil {
+		return nil, fmt.Errorf("Failed to render message: %w", err)
+	}
+
+	if kind != AddressKindEmail {
+		return NewSMS().To(recipient.Address).Text(text.String()).Build(), nil
+	}
+
+	subjectText := strings.Builder{}
+	if err := subject.Execute(&subjectText, recipient.MergeFields); err != nil {
+		return nil, fmt.Errorf("Failed to render subject: %w", err)
+	}
+
+	return NewEmail().To(recipient.Address).Subject(subjectText.String()).Text(text.String()).Build(), nil
+}
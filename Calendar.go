@@ -0,0 +1,155 @@
+package messagingutilities
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CalendarMethod string
+
+const (
+	CalendarMethodRequest CalendarMethod = "REQUEST"
+	CalendarMethodCancel  CalendarMethod = "CANCEL"
+)
+
+// CalendarEvent is sent as an iCalendar invitation. UID identifies the event
+// across updates; resend it with a higher Sequence to change or cancel an
+// earlier invite. Organizer defaults to the message sender and Attendees to
+// its To and Cc recipients.
+type CalendarEvent struct {
+	Method      CalendarMethod `json:"method"`
+	UID         string         `json:"uid"`
+	Sequence    int            `json:"sequence,omitempty"`
+	Summary     string         `json:"summary"`
+	Description string         `json:"description,omitempty"`
+	Location    string         `json:"location,omitempty"`
+	Start       time.Time      `json:"start"`
+	End         time.Time      `json:"end"`
+	Organizer   string         `json:"organizer,omitempty"`
+	Attendees   []string       `json:"attendees,omitempty"`
+}
+
+func (event *CalendarEvent) validate() error {
+	switch event.Method {
+	case CalendarMethodRequest, CalendarMethodCancel:
+	default:
+		return &ValidationError{Field: "calendar", Message: "Unsupported calendar method " + string(event.Method)}
+	}
+
+	if event.UID == "" {
+		return &ValidationError{Field: "calendar", Message: "Calendar events must have a UID"}
+	}
+
+	if event.Start.IsZero() || !event.End.After(event.Start) {
+		return &ValidationError{Field: "calendar", Message: "Calendar events must end after they start"}
+	}
+
+	if event.Sequence < 0 {
+		return &ValidationError{Field: "calendar", Message: "Calendar sequence cannot be negative"}
+	}
+
+	return nil
+}
+
+func (method CalendarMethod) contentType() string {
+	return "text/calendar; method=" + string(method)
+}
+
+// ICS renders the event as an iCalendar object for the given organizer and
+// attendees.
+func (event *CalendarEvent) ICS(organizer string, attendees []string) string {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//DerrohXy//MessagingUtilities//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:" + string(event.Method),
+		"BEGIN:VEVENT",
+		"UID:" + escapeICSText(event.UID),
+		"SEQUENCE:" + strconv.Itoa(event.Sequence),
+		"DTSTAMP:" + formatICSTime(time.Now()),
+		"DTSTART:" + formatICSTime(event.Start),
+		"DTEND:" + formatICSTime(event.End),
+		"SUMMARY:" + escapeICSText(event.Summary),
+	}
+
+	if event.Description != "" {
+		lines = append(lines, "DESCRIPTION:"+escapeICSText(event.Description))
+	}
+	if event.Location != "" {
+		lines = append(lines, "LOCATION:"+escapeICSText(event.Location))
+	}
+
+	if event.Method == CalendarMethodCancel {
+		lines = append(lines, "STATUS:CANCELLED")
+	} else {
+		lines = append(lines, "STATUS:CONFIRMED")
+	}
+
+	name, address := splitEmailAddress(organizer)
+	lines = append(lines, "ORGANIZER"+icsCommonName(name)+":mailto:"+address)
+
+	for _, attendee := range attendees {
+		name, address := splitEmailAddress(attendee)
+		lines = append(lines, "ATTENDEE"+icsCommonName(name)+
+			";ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE:mailto:"+address)
+	}
+
+	lines = append(lines, "END:VEVENT", "END:VCALENDAR")
+
+	builder := &strings.Builder{}
+	for _, line := range lines {
+		builder.WriteString(foldICSLine(line))
+		builder.WriteString("\r\n")
+	}
+
+	return builder.String()
+}
+
+func formatICSTime(value time.Time) string {
+	return value.UTC().Format("20060102T150405Z")
+}
+
+func escapeICSText(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(value)
+}
+
+func icsCommonName(name string) string {
+	name = strings.Map(func(character rune) rune {
+		if character == '"' || character < ' ' {
+			return -1
+		}
+		return character
+	}, name)
+	if name == "" {
+		return ""
+	}
+
+	return `;CN="` + name + `"`
+}
+
+// foldICSLine splits lines longer than 75 octets as RFC 5545 requires,
+// without breaking multi-byte characters.
+func foldICSLine(line string) string {
+	builder := &strings.Builder{}
+	length := 0
+	for _, character := range line {
+		size := len(string(character))
+		if length+size > 75 {
+			builder.WriteString("\r\n ")
+			length = 1
+		}
+		builder.WriteRune(character)
+		length += size
+	}
+
+	return builder.String()
+}
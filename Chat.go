@@ -0,0 +1,99 @@
+package messagingutilities
+
+import (
+	"encoding/json"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// attachmentProviders are the chat senders that accept Message.Attachments.
+var attachmentProviders = map[string]bool{
+	"telegram": true,
+	"discord":  true,
+	"signal":   true,
+}
+
+// mediaTypeFromURL guesses whether a media file is an "image", "video" or
+// "audio" file from the extension of its URL, returning "document" for
+// anything else.
+func mediaTypeFromURL(mediaURL string) string {
+	extension := ""
+	if parsed, err := url.Parse(mediaURL); err == nil {
+		extension = strings.ToLower(path.Ext(parsed.Path))
+	}
+
+	switch extension {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp":
+		return "image"
+	case ".mp4", ".3gp", ".mov":
+		return "video"
+	case ".mp3", ".ogg", ".aac", ".amr", ".m4a", ".opus":
+		return "audio"
+	default:
+		return "document"
+	}
+}
+
+// encodeChatPayload encodes request with the keys of payload added,
+// replacing any it already has.
+func encodeChatPayload(request any, payload map[string]any) ([]byte, error) {
+	body, err := json.Marshal(request)
+	if err != nil || len(payload) == 0 {
+		return body, err
+	}
+
+	merged := map[string]any{}
+	if err := json.Unmarshal(body, &merged); err != nil {
+		return nil, err
+	}
+	for key, value := range payload {
+		merged[key] = value
+	}
+
+	return json.Marshal(merged)
+}
+
+// chatText returns the plain text of a chat message for senders without
+// HTML support.
+func chatText(message *Message, text string) (string, error) {
+	if text != "" || message.HTML == "" {
+		return text, nil
+	}
+
+	return HTMLToText(message.HTML)
+}
+
+// withDefaultReceiver returns message sent to receiver when it has no
+// receivers of its own.
+func withDefaultReceiver(message *Message, receiver string) *Message {
+	if message == nil || len(message.To) > 0 || receiver == "" {
+		return message
+	}
+
+	copied := *message
+	copied.To = []string{receiver}
+
+	return &copied
+}
+
+// readMessageAttachments reads the attachments of a chat message once, so
+// they can be uploaded to every receiver.
+func readMessageAttachments(message *Message) ([]emailAttachmentData, error) {
+	if message == nil {
+		return nil, nil
+	}
+
+	for index := range message.Attachments {
+		if message.Attachments[index].Name == nil || message.Attachments[index].Data == nil {
+			return nil, &ValidationError{Field: "attachments", Message: "Attachments must have a name and data"}
+		}
+		if err := message.Attachments[index].detectContentType(); err != nil {
+			return nil, err
+		}
+	}
+
+	email := &preparedEmail{Attachments: append([]EmailAttachment(nil), message.Attachments...)}
+
+	return email.readAttachments()
+}
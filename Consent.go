@@ -0,0 +1,309 @@
+package messagingutilities
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+var ErrConsentRequired = errors.New("Recipient has not consented to this message category")
+
+type ConsentStatus string
+
+const (
+	ConsentStatusUnknown ConsentStatus = "unknown"
+	ConsentStatusGranted ConsentStatus = "granted"
+	ConsentStatusRevoked ConsentStatus = "revoked"
+)
+
+type ConsentRecord struct {
+	Channel   Channel         `json:"channel"`
+	Address   string          `json:"address"`
+	Category  MessageCategory `json:"category"`
+	Status    ConsentStatus   `json:"status"`
+	Source    string          `json:"source"`
+	Timestamp time.Time       `json:"timestamp"`
+	ExpiresAt time.Time       `json:"expiresAt,omitzero"`
+}
+
+func (record *ConsentRecord) Valid(now time.Time) bool {
+	return record.Status == ConsentStatusGranted &&
+		(record.ExpiresAt.IsZero() || now.Before(record.ExpiresAt))
+}
+
+// ConsentStore keeps consent per channel, address and category. Stores
+// match email addresses in any case and phone numbers in local and
+// international formats, as suppression lists do.
+type ConsentStore interface {
+	GetConsent(ctx context.Context, channel Channel, address string, category MessageCategory) (ConsentRecord, error)
+	RecordConsent(ctx context.Context, record ConsentRecord) error
+}
+
+type memoryConsentKey struct {
+	channel  Channel
+	address  string
+	category MessageCategory
+}
+
+type MemoryConsentStore struct {
+	mutex   sync.RWMutex
+	records map[memoryConsentKey]ConsentRecord
+}
+
+func NewMemoryConsentStore() *MemoryConsentStore {
+	return &MemoryConsentStore{records: map[memoryConsentKey]ConsentRecord{}}
+}
+
+func (store *MemoryConsentStore) GetConsent(
+	ctx context.Context,
+	channel Channel,
+	address string,
+	category MessageCategory,
+) (ConsentRecord, error) {
+	address = normalizeSuppressedAddress(channel, address)
+
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	record, ok := store.records[memoryConsentKey{channel, address, category}]
+	if !ok {
+		return ConsentRecord{
+			Channel:  channel,
+			Address:  address,
+			Category: category,
+			Status:   ConsentStatusUnknown,
+		}, nil
+	}
+
+	return record, nil
+}
+
+func (store *MemoryConsentStore) RecordConsent(ctx context.Context, record ConsentRecord) error {
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now()
+	}
+	record.Address = normalizeSuppressedAddress(record.Channel, record.Address)
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.records[memoryConsentKey{record.Channel, record.Address, record.Category}] = record
+
+	return nil
+}
+
+// SQLConsentStore keeps every consent change as a row and reads the latest
+// one, so the table doubles as the consent history required for audits.
+// Addresses are stored in the normalized form used for suppressions, so
+// rows written before that are not matched. The expected table is:
+//
+//	CREATE TABLE consent_records (
+//		channel     VARCHAR(32)  NOT NULL,
+//		address     VARCHAR(320) NOT NULL,
+//		category    VARCHAR(64)  NOT NULL,
+//		status      VARCHAR(16)  NOT NULL,
+//		source      VARCHAR(255) NOT NULL,
+//		recorded_at TIMESTAMP    NOT NULL,
+//		expires_at  TIMESTAMP    NULL
+//	);
+type SQLConsentStore struct {
+	DB          *sql.DB
+	Table       string
+	Placeholder func(index int) string
+}
+
+func NewSQLConsentStore(db *sql.DB, table string) *SQLConsentStore {
+	return &SQLConsentStore{
+		DB:    db,
+		Table: table,
+		Placeholder: func(index int) string {
+			return "?"
+		},
+	}
+}
+
+func DollarPlaceholder(index int) string {
+	return fmt.Sprintf("$%d", index)
+}
+
+func (store *SQLConsentStore) GetConsent(
+	ctx context.Context,
+	channel Channel,
+	address string,
+	category MessageCategory,
+) (ConsentRecord, error) {
+	query := fmt.Sprintf(
+		"SELECT status, source, recorded_at, expires_at FROM %s "+
+			"WHERE channel = %s AND address = %s AND category = %s "+
+			"ORDER BY recorded_at DESC LIMIT 1",
+		store.Table,
+		store.Placeholder(1),
+		store.Placeholder(2),
+		store.Placeholder(3),
+	)
+
+	address = normalizeSuppressedAddress(channel, address)
+	record := ConsentRecord{
+		Channel:  channel,
+		Address:  address,
+		Category: category,
+		Status:   ConsentStatusUnknown,
+	}
+
+	var status string
+	var expiresAt sql.NullTime
+	err := store.DB.QueryRowContext(ctx, query, string(channel), address, string(category)).
+		Scan(&status, &record.Source, &record.Timestamp, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return record, nil
+	}
+	if err != nil {
+		return record, fmt.Errorf("Failed to read consent record: %w", err)
+	}
+
+	record.Status = ConsentStatus(status)
+	if expiresAt.Valid {
+		record.ExpiresAt = expiresAt.Time
+	}
+
+	return record, nil
+}
+
+func (store *SQLConsentStore) RecordConsent(ctx context.Context, record ConsentRecord) error {
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now()
+	}
+	record.Address = normalizeSuppressedAddress(record.Channel, record.Address)
+
+	query := fmt.Sprintf(
+		"INSERT INTO %s (channel, address, category, status, source, recorded_at, expires_at) "+
+			"VALUES (%s, %s, %s, %s, %s, %s, %s)",
+		store.Table,
+		store.Placeholder(1),
+		store.Placeholder(2),
+		store.Placeholder(3),
+		store.Placeholder(4),
+		store.Placeholder(5),
+		store.Placeholder(6),
+		store.Placeholder(7),
+	)
+
+	expiresAt := sql.NullTime{Time: record.ExpiresAt, Valid: !record.ExpiresAt.IsZero()}
+	_, err := store.DB.ExecContext(
+		ctx,
+		query,
+		string(record.Channel),
+		record.Address,
+		string(record.Category),
+		string(record.Status),
+		record.Source,
+		record.Timestamp,
+		expiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to record consent: %w", err)
+	}
+
+	return nil
+}
+
+type MessageEnvelope struct {
+	Channel   Channel
+	Category  MessageCategory
+	Recipient string
+	Payload   any
+}
+
+type ConsentDecision struct {
+	Envelope MessageEnvelope `json:"envelope"`
+	Required bool            `json:"required"`
+	Allowed  bool            `json:"allowed"`
+	Consent  ConsentRecord   `json:"consent"`
+}
+
+type ConsentChecker struct {
+	Store              ConsentStore
+	RequiredCategories []MessageCategory
+	OnDecision         func(decision ConsentDecision)
+}
+
+func WithConsentCheck(store ConsentStore) *ConsentChecker {
+	return &ConsentChecker{
+		Store:              store,
+		RequiredCategories: []MessageCategory{MessageCategoryMarketing},
+	}
+}
+
+func (checker *ConsentChecker) Check(ctx context.Context, envelope MessageEnvelope) (ConsentDecision, error) {
+	decision := ConsentDecision{
+		Envelope: envelope,
+		Required: slices.Contains(checker.RequiredCategories, envelope.Category),
+		Allowed:  true,
+	}
+
+	record, err := checker.Store.GetConsent(ctx, envelope.Channel, envelope.Recipient, envelope.Category)
+	if err != nil {
+		return decision, err
+	}
+	decision.Consent = record
+
+	if decision.Required && !record.Valid(time.Now()) {
+		decision.Allowed = false
+	}
+
+	if checker.OnDecision != nil {
+		checker.OnDecision(decision)
+	}
+
+	return decision, nil
+}
+
+func (checker *ConsentChecker) Send(
+	ctx context.Context,
+	envelope MessageEnvelope,
+	send func(ctx context.Context) error,
+) (ConsentDecision, error) {
+	decision, err := checker.Check(ctx, envelope)
+	if err != nil {
+		return decision, err
+	}
+
+	if !decision.Allowed {
+		return decision, ErrConsentRequired
+	}
+
+	return decision, send(ctx)
+}
+
+func (checker *ConsentChecker) FilterRecipients(
+	ctx context.Context,
+	channel Channel,
+	category MessageCategory,
+	recipients []string,
+) ([]string, []ConsentDecision, error) {
+	allowed := []string{}
+	skipped := []ConsentDecision{}
+
+	for _, recipient := range recipients {
+		decision, err := checker.Check(ctx, MessageEnvelope{
+			Channel:   channel,
+			Category:  category,
+			Recipient: recipient,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if decision.Allowed {
+			allowed = append(allowed, recipient)
+		} else {
+			skipped = append(skipped, decision)
+		}
+	}
+
+	return allowed, skipped, nil
+}
@@ -0,0 +1,219 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var defaultDKIMHeaders = []string{
+	"From",
+	"To",
+	"Cc",
+	"Reply-To",
+	"Subject",
+	"Date",
+	"Message-ID",
+	"In-Reply-To",
+	"References",
+	"MIME-Version",
+	"Content-Type",
+	"List-Unsubscribe",
+	"List-Unsubscribe-Post",
+}
+
+// DKIMOptions signs with relaxed/relaxed canonicalization using rsa-sha256
+// for RSA keys and ed25519-sha256 for Ed25519 keys. Headers defaults to the
+// common addressing and content headers; only those present are signed.
+type DKIMOptions struct {
+	Domain     string
+	Selector   string
+	PrivateKey crypto.Signer
+	Headers    []string
+}
+
+func ParseDKIMPrivateKey(data []byte) (crypto.Signer, error) {
+	return parsePEMPrivateKey(data, "DKIM")
+}
+
+func parsePEMPrivateKey(data []byte, purpose string) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s private key is not PEM encoded", purpose)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse %s private key: %w", purpose, err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s private key type is not supported", purpose)
+	}
+
+	return signer, nil
+}
+
+var dkimWhitespacePattern = regexp.MustCompile(`[ \t]+`)
+
+func signDKIM(message io.WriterTo, options *DKIMOptions) (*bytes.Buffer, error) {
+	if options.Domain == "" || options.Selector == "" {
+		return nil, &ValidationError{Field: "dkim", Message: "DKIM domain and selector are required"}
+	}
+
+	var algorithm string
+	switch options.PrivateKey.(type) {
+	case *rsa.PrivateKey:
+		algorithm = "rsa-sha256"
+	case ed25519.PrivateKey:
+		algorithm = "ed25519-sha256"
+	default:
+		return nil, errors.New("DKIM private key type is not supported")
+	}
+
+	raw := &bytes.Buffer{}
+	if _, err := message.WriteTo(raw); err != nil {
+		return nil, err
+	}
+
+	content := strings.ReplaceAll(raw.String(), "\r\n", "\n")
+	headerBlock, body, found := strings.Cut(content, "\n\n")
+	if !found {
+		headerBlock, body = strings.TrimSuffix(content, "\n"), ""
+	}
+
+	headers := splitDKIMHeaders(headerBlock)
+
+	bodyHash := sha256.Sum256([]byte(canonicalizeDKIMBody(body)))
+
+	names := options.Headers
+	if len(names) == 0 {
+		names = defaultDKIMHeaders
+	}
+
+	signedNames := []string{}
+	canonical := &strings.Builder{}
+	used := map[int]bool{}
+	for _, name := range names {
+		for index := len(headers) - 1; index >= 0; index-- {
+			headerName, _, _ := strings.Cut(headers[index], ":")
+			if used[index] || !strings.EqualFold(strings.TrimSpace(headerName), name) {
+				continue
+			}
+			used[index] = true
+			signedNames = append(signedNames, name)
+			canonical.WriteString(canonicalizeDKIMHeader(headers[index]))
+			canonical.WriteString("\r\n")
+			break
+		}
+	}
+
+	if !containsFold(signedNames, "From") {
+		return nil, errors.New("DKIM signing requires a From header")
+	}
+
+	signature := fmt.Sprintf(
+		"DKIM-Signature: v=1; a=%s; c=relaxed/relaxed; d=%s; s=%s; t=%s; h=%s; bh=%s; b=",
+		algorithm,
+		options.Domain,
+		options.Selector,
+		strconv.FormatInt(time.Now().Unix(), 10),
+		strings.Join(signedNames, ":"),
+		base64.StdEncoding.EncodeToString(bodyHash[:]),
+	)
+	canonical.WriteString(canonicalizeDKIMHeader(signature))
+
+	hashed := sha256.Sum256([]byte(canonical.String()))
+
+	var signed []byte
+	var err error
+	if algorithm == "rsa-sha256" {
+		signed, err = options.PrivateKey.Sign(rand.Reader, hashed[:], crypto.SHA256)
+	} else {
+		signed, err = options.PrivateKey.Sign(rand.Reader, hashed[:], crypto.Hash(0))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to sign message: %w", err)
+	}
+
+	result := &bytes.Buffer{}
+	result.WriteString(signature)
+	result.WriteString(base64.StdEncoding.EncodeToString(signed))
+	result.WriteString("\r\n")
+	result.Write(raw.Bytes())
+
+	return result, nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitDKIMHeaders returns each header field with its folded continuation
+// lines rejoined by "\r\n".
+func splitDKIMHeaders(block string) []string {
+	headers := []string{}
+	for _, line := range strings.Split(block, "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(headers) > 0 {
+			headers[len(headers)-1] += "\r\n" + line
+			continue
+		}
+		headers = append(headers, line)
+	}
+
+	return headers
+}
+
+func canonicalizeDKIMHeader(header string) string {
+	name, value, _ := strings.Cut(header, ":")
+	value = strings.ReplaceAll(value, "\r\n", "")
+	value = dkimWhitespacePattern.ReplaceAllString(value, " ")
+
+	return strings.ToLower(strings.TrimSpace(name)) + ":" + strings.TrimSpace(value)
+}
+
+func canonicalizeDKIMBody(body string) string {
+	lines := strings.Split(body, "\n")
+	for index, line := range lines {
+		line = dkimWhitespacePattern.ReplaceAllString(line, " ")
+		lines[index] = strings.TrimRight(line, " ")
+	}
+
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
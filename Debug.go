@@ -0,0 +1,396 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+)
+
+type DebugOptions struct {
+	Writer       io.Writer
+	Logger       *slog.Logger
+	MaxBodyBytes int
+}
+
+type debugDumper struct {
+	options DebugOptions
+	mutex   sync.Mutex
+}
+
+var activeDebugDumper atomic.Pointer[debugDumper]
+
+var sensitiveHeaders = map[string]bool{
+	"authorization":           true,
+	"proxy-authorization":     true,
+	"apikey":                  true,
+	"x-api-key":               true,
+	"cookie":                  true,
+	"set-cookie":              true,
+	"x-postmark-server-token": true,
+	"x-viber-auth-token":      true,
+}
+
+func EnableDebug(options DebugOptions) {
+	if options.Writer == nil && options.Logger == nil {
+		activeDebugDumper.Store(nil)
+		return
+	}
+
+	if options.MaxBodyBytes <= 0 {
+		options.MaxBodyBytes = 2048
+	}
+
+	activeDebugDumper.Store(&debugDumper{options: options})
+}
+
+func DisableDebug() {
+	activeDebugDumper.Store(nil)
+}
+
+func RedactSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	if scheme, credential, found := strings.Cut(value, " "); found && credential != "" {
+		return scheme + " " + RedactSecret(credential)
+	}
+
+	if len(value) < 12 {
+		return "********"
+	}
+
+	return "********" + value[len(value)-4:]
+}
+
+func (dumper *debugDumper) dump(provider, kind, text string) {
+	if dumper.options.Logger != nil {
+		dumper.options.Logger.Debug(
+			"messaging debug dump",
+			slog.String("provider", provider),
+			slog.String("kind", kind),
+			slog.String("dump", text),
+		)
+	}
+
+	if dumper.options.Writer != nil {
+		dumper.mutex.Lock()
+		defer dumper.mutex.Unlock()
+
+		fmt.Fprintf(dumper.options.Writer, "--- %s %s ---\n%s\n", provider, kind, text)
+	}
+}
+
+// Secret fields of JSON and form bodies, such as "api_key" or
+// "client_secret". The closing quote of a JSON value is optional so that
+// values cut off by truncation are masked too.
+var (
+	jsonSecretPattern = regexp.MustCompile(
+		`(?i)("[a-z_-]*(?:api_?key|secret|password|token|private_?key)"\s*:\s*")((?:[^"\\]|\\.)*)`,
+	)
+	formSecretPattern = regexp.MustCompile(
+		`(?i)((?:^|&)[a-z_-]*(?:api_?key|secret|password|token)=)([^&]*)`,
+	)
+)
+
+// redactBody masks the values of secret fields in a JSON or form body.
+func redactBody(body string) string {
+	for _, pattern := range []*regexp.Regexp{jsonSecretPattern, formSecretPattern} {
+		body = pattern.ReplaceAllStringFunc(body, func(match string) string {
+			groups := pattern.FindStringSubmatch(match)
+			return groups[1] + RedactSecret(groups[2])
+		})
+	}
+
+	return body
+}
+
+// redactURL masks credentials in URL userinfo, every query value and path
+// segments that look like tokens, such as the bot token of Telegram URLs
+// and the secret part of Slack and Discord webhook URLs.
+func redactURL(requestURL *url.URL) string {
+	redacted := *requestURL
+
+	if redacted.User != nil {
+		redacted.User = url.User("********")
+	}
+
+	segments := strings.Split(redacted.Path, "/")
+	for index, segment := range segments {
+		if isTokenLike(segment) {
+			segments[index] = RedactSecret(segment)
+		}
+	}
+	redacted.Path = strings.Join(segments, "/")
+	redacted.RawPath = ""
+
+	query := redacted.Query()
+	for _, values := range query {
+		for index := range values {
+			values[index] = RedactSecret(values[index])
+		}
+	}
+	redacted.RawQuery = query.Encode()
+
+	// The masks are escaped by String.
+	return strings.ReplaceAll(redacted.String(), "%2A%2A%2A%2A%2A%2A%2A%2A", "********")
+}
+
+// isTokenLike reports whether a path segment is long and mixes letters with
+// digits, as tokens do and ids and API paths usually do not.
+func isTokenLike(segment string) bool {
+	if len(segment) < 20 {
+		return false
+	}
+
+	hasLetter, hasDigit := false, false
+	for _, character := range segment {
+		hasLetter = hasLetter || unicode.IsLetter(character)
+		hasDigit = hasDigit || unicode.IsDigit(character)
+	}
+
+	return hasLetter && hasDigit
+}
+
+// redactError masks the URL of a failed request.
+func redactError(err error) string {
+	var urlError *url.Error
+	if !errors.As(err, &urlError) {
+		return err.Error()
+	}
+
+	if parsed, parseErr := url.Parse(urlError.URL); parseErr == nil {
+		return (&url.Error{Op: urlError.Op, URL: redactURL(parsed), Err: urlError.Err}).Error()
+	}
+
+	return (&url.Error{Op: urlError.Op, URL: "********", Err: urlError.Err}).Error()
+}
+
+// readBody reads one byte more than MaxBodyBytes of body, so that
+// formatBody can tell whether it was truncated.
+func (dumper *debugDumper) readBody(body io.Reader) ([]byte, error) {
+	return io.ReadAll(io.LimitReader(body, int64(dumper.options.MaxBodyBytes)+1))
+}
+
+// formatBody redacts the first MaxBodyBytes of body, marking it when there
+// was more.
+func (dumper *debugDumper) formatBody(body []byte) string {
+	if len(body) <= dumper.options.MaxBodyBytes {
+		return redactBody(string(body))
+	}
+
+	return redactBody(string(body[:dumper.options.MaxBodyBytes])) + "... (truncated)"
+}
+
+func (dumper *debugDumper) formatHeaders(builder *strings.Builder, headers http.Header) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, value := range headers[name] {
+			if sensitiveHeaders[strings.ToLower(name)] {
+				value = RedactSecret(value)
+			}
+			fmt.Fprintf(builder, "%s: %s\n", name, value)
+		}
+	}
+}
+
+type debugTransport struct {
+	provider string
+	base     http.RoundTripper
+	dumper   *debugDumper
+}
+
+func (transport *debugTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if request.Body != nil && request.GetBody != nil {
+		if bodyCopy, err := request.GetBody(); err == nil {
+			requestBody, _ = transport.dumper.readBody(bodyCopy)
+			bodyCopy.Close()
+		}
+	}
+
+	builder := strings.Builder{}
+	fmt.Fprintf(&builder, "%s %s\n", request.Method, redactURL(request.URL))
+	transport.dumper.formatHeaders(&builder, request.Header)
+	if len(requestBody) > 0 {
+		fmt.Fprintf(&builder, "\n%s", transport.dumper.formatBody(requestBody))
+	}
+	transport.dumper.dump(transport.provider, "http request", builder.String())
+
+	response, err := transport.base.RoundTrip(request)
+	if err != nil {
+		transport.dumper.dump(transport.provider, "http error", redactError(err))
+		return response, err
+	}
+
+	// Only the dumped part of the body is read here, and it is put back in
+	// front of the rest for the caller.
+	responseBody, err := transport.dumper.readBody(response.Body)
+	response.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(responseBody), response.Body), response.Body}
+	if err != nil {
+		transport.dumper.dump(transport.provider, "http error", redactError(err))
+		return response, err
+	}
+
+	builder.Reset()
+	fmt.Fprintf(&builder, "%s\n", response.Status)
+	transport.dumper.formatHeaders(&builder, response.Header)
+	if len(responseBody) > 0 {
+		fmt.Fprintf(&builder, "\n%s", transport.dumper.formatBody(responseBody))
+	}
+	transport.dumper.dump(transport.provider, "http response", builder.String())
+
+	return response, nil
+}
+
+func debugHTTPClient(provider string, client *http.Client) *http.Client {
+	dumper := activeDebugDumper.Load()
+	if dumper == nil {
+		return client
+	}
+
+	debugClient := *client
+	base := debugClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	debugClient.Transport = &debugTransport{
+		provider: provider,
+		base:     base,
+		dumper:   dumper,
+	}
+
+	return &debugClient
+}
+
+type smtpTranscript struct {
+	dumper      *debugDumper
+	builder     strings.Builder
+	inAuth      bool
+	inData      bool
+	dataWritten int
+}
+
+func (transcript *smtpTranscript) clientLine(line string) {
+	if transcript.inData {
+		if line == "." {
+			transcript.inData = false
+			if transcript.dataWritten > transcript.dumper.options.MaxBodyBytes {
+				fmt.Fprintf(
+					&transcript.builder,
+					"C: ... (%d bytes truncated)\n",
+					transcript.dataWritten-transcript.dumper.options.MaxBodyBytes,
+				)
+			}
+			transcript.builder.WriteString("C: .\n")
+			return
+		}
+
+		remaining := transcript.dumper.options.MaxBodyBytes - transcript.dataWritten
+		transcript.dataWritten += len(line) + 2
+		if remaining > 0 {
+			if len(line) > remaining {
+				line = line[:remaining]
+			}
+			fmt.Fprintf(&transcript.builder, "C: %s\n", line)
+		}
+		return
+	}
+
+	upper := strings.ToUpper(line)
+	switch {
+	case transcript.inAuth:
+		line = "********"
+	case strings.HasPrefix(upper, "AUTH "):
+		transcript.inAuth = true
+		fields := strings.Fields(line)
+		line = strings.Join(fields[:min(2, len(fields))], " ")
+		if len(fields) > 2 {
+			line += " ********"
+		}
+	}
+
+	fmt.Fprintf(&transcript.builder, "C: %s\n", line)
+}
+
+func (transcript *smtpTranscript) serverLine(line string) {
+	if transcript.inAuth && !strings.HasPrefix(line, "334") {
+		transcript.inAuth = false
+	}
+	if strings.HasPrefix(line, "334") {
+		line = "334 ********"
+	}
+	if strings.HasPrefix(line, "354") {
+		transcript.inData = true
+		transcript.dataWritten = 0
+	}
+
+	fmt.Fprintf(&transcript.builder, "S: %s\n", line)
+}
+
+func (transcript *smtpTranscript) flush(host string) {
+	if transcript.builder.Len() == 0 {
+		return
+	}
+
+	transcript.dumper.dump("smtp", "transcript "+host, transcript.builder.String())
+	transcript.builder.Reset()
+}
+
+type debugConn struct {
+	io.ReadWriteCloser
+	transcript    *smtpTranscript
+	mutex         sync.Mutex
+	pendingClient []byte
+	pendingServer []byte
+}
+
+func (conn *debugConn) Read(buffer []byte) (int, error) {
+	count, err := conn.ReadWriteCloser.Read(buffer)
+
+	conn.mutex.Lock()
+	conn.pendingServer = conn.consumeLines(append(conn.pendingServer, buffer[:count]...), conn.transcript.serverLine)
+	conn.mutex.Unlock()
+
+	return count, err
+}
+
+func (conn *debugConn) Write(buffer []byte) (int, error) {
+	count, err := conn.ReadWriteCloser.Write(buffer)
+
+	conn.mutex.Lock()
+	conn.pendingClient = conn.consumeLines(append(conn.pendingClient, buffer[:count]...), conn.transcript.clientLine)
+	conn.mutex.Unlock()
+
+	return count, err
+}
+
+func (conn *debugConn) consumeLines(pending []byte, handle func(string)) []byte {
+	for {
+		index := bytes.Index(pending, []byte("\r\n"))
+		if index < 0 {
+			return pending
+		}
+
+		handle(string(pending[:index]))
+		pending = pending[index+2:]
+	}
+}
@@ -0,0 +1,145 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+)
+
+// DiscordCredentials posts through a channel webhook. Username and
+// AvatarURL override the name and avatar set on the webhook.
+type DiscordCredentials struct {
+	WebhookURL string
+	Username   string
+	AvatarURL  string
+}
+
+// DiscordSender posts Text as the content of a webhook message. Embeds are
+// passed through with Payload("embeds", ...) and attachments are uploaded
+// as files of the message.
+type DiscordSender struct {
+	Credentials *DiscordCredentials
+}
+
+func NewDiscordSender(credentials *DiscordCredentials) *DiscordSender {
+	return &DiscordSender{Credentials: credentials}
+}
+
+// discordMaxContent is the length limit of message content, in characters.
+const discordMaxContent = 2000
+
+type discordRequest struct {
+	Content   string `json:"content,omitempty"`
+	Username  string `json:"username,omitempty"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+}
+
+type discordResponse struct {
+	ID string `json:"id"`
+}
+
+func (sender *DiscordSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	if sender.Credentials.WebhookURL == "" {
+		return nil, &ValidationError{Field: "webhookUrl", Message: "Discord webhook URL cannot be empty"}
+	}
+	if message != nil && len(message.To) > 0 {
+		return nil, &ValidationError{Field: "to", Message: "Discord webhooks post to a fixed channel"}
+	}
+
+	attachments, err := readMessageAttachments(message)
+	if err != nil {
+		return nil, err
+	}
+
+	message = withDefaultReceiver(message, "webhook")
+
+	return sendEachMessage(ctx, ChannelChat, "discord", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sender.send(ctx, message, attachments, text)
+	})
+}
+
+func (sender *DiscordSender) send(
+	ctx context.Context,
+	message *Message,
+	attachments []emailAttachmentData,
+	text string,
+) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("discord", "chat", err) }()
+
+	if text, err = chatText(message, text); err != nil {
+		return result, err
+	}
+	if len([]rune(text)) > discordMaxContent {
+		return result, &ValidationError{Field: "text", Message: "Discord messages cannot exceed 2000 characters"}
+	}
+
+	payload, err := encodeChatPayload(discordRequest{
+		Content:   text,
+		Username:  sender.Credentials.Username,
+		AvatarURL: sender.Credentials.AvatarURL,
+	}, message.Payload)
+	if err != nil {
+		return result, fmt.Errorf("Failed to encode Discord request: %w", err)
+	}
+
+	// wait makes Discord answer with the created message.
+	webhookURL, err := url.Parse(sender.Credentials.WebhookURL)
+	if err != nil {
+		return result, fmt.Errorf("Invalid Discord webhook URL: %w", err)
+	}
+	query := webhookURL.Query()
+	query.Set("wait", "true")
+	webhookURL.RawQuery = query.Encode()
+
+	body, contentType := &bytes.Buffer{}, "application/json"
+	if len(attachments) == 0 {
+		body.Write(payload)
+	} else {
+		writer := multipart.NewWriter(body)
+		writer.WriteField("payload_json", string(payload))
+		for index, attachment := range attachments {
+			header := textproto.MIMEHeader{}
+			header.Set("Content-Disposition", fmt.Sprintf(
+				`form-data; name="files[%d]"; filename=%s`, index, strconv.Quote(attachment.Name),
+			))
+			header.Set("Content-Type", attachment.ContentType)
+			part, err := writer.CreatePart(header)
+			if err != nil {
+				return result, fmt.Errorf("Failed to encode Discord request: %w", err)
+			}
+			part.Write(attachment.Data)
+		}
+		if err := writer.Close(); err != nil {
+			return result, fmt.Errorf("Failed to encode Discord request: %w", err)
+		}
+		contentType = writer.FormDataContentType()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "POST", webhookURL.String(), body)
+	if err != nil {
+		return result, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", contentType)
+
+	_, responseBody, err := doProviderRequest("discord", "Discord", request)
+	result.Raw = string(responseBody)
+	if err != nil {
+		return result, err
+	}
+
+	var discordResp discordResponse
+	if err := json.Unmarshal(responseBody, &discordResp); err != nil {
+		return result, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	result.MessageID = discordResp.ID
+
+	return result, nil
+}
@@ -0,0 +1,373 @@
+package messagingutilities
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+var ErrShuttingDown = errors.New("Dispatcher is shutting down")
+var ErrQueueFull = errors.New("Dispatcher queue is full")
+var ErrEvicted = errors.New("Message was evicted from the dispatcher queue")
+
+type DispatchPriority int
+
+const (
+	DispatchPriorityInteractive DispatchPriority = iota
+	DispatchPriorityBulk
+)
+
+type OverflowPolicy int
+
+const (
+	OverflowBlock OverflowPolicy = iota
+	OverflowReject
+	OverflowDropOldest
+	// OverflowShedBulk rejects new bulk jobs and evicts queued bulk jobs to
+	// admit interactive ones. Interactive jobs that find no bulk job to evict
+	// wait for space as with OverflowBlock.
+	OverflowShedBulk
+)
+
+func (policy OverflowPolicy) String() string {
+	switch policy {
+	case OverflowBlock:
+		return "block"
+	case OverflowReject:
+		return "reject"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowShedBulk:
+		return "shed-bulk"
+	default:
+		return "unknown"
+	}
+}
+
+type DispatchJob struct {
+	// ID identifies the job in the eviction log.
+	ID       string
+	Receiver string
+	Priority DispatchPriority
+	Send     func(ctx context.Context) error
+}
+
+type DispatcherOptions struct {
+	Workers        int
+	Capacity       int
+	OverflowPolicy OverflowPolicy
+	OnResult       func(job DispatchJob, err error)
+	OnAbandoned    func(job DispatchJob)
+	OnEvicted      func(job DispatchJob)
+	OnRejected     func(job DispatchJob, err error)
+	// EvictionLogSize is the number of most recent evictions kept for
+	// Evictions, 1000 by default.
+	EvictionLogSize int
+}
+
+// DispatchEviction records a job evicted from the queue to admit another.
+type DispatchEviction struct {
+	JobID    string           `json:"jobId"`
+	Receiver string           `json:"receiver"`
+	Priority DispatchPriority `json:"priority"`
+	Policy   string           `json:"policy"`
+	Time     time.Time        `json:"time"`
+}
+
+type DispatcherStats struct {
+	OverflowPolicy string `json:"overflowPolicy"`
+	Capacity       int    `json:"capacity"`
+	Queued         int    `json:"queued"`
+	Sent           int    `json:"sent"`
+	Failed         int    `json:"failed"`
+	Rejected       int    `json:"rejected"`
+	Evicted        int    `json:"evicted"`
+}
+
+// ShutdownReport accounts for every enqueued job. Abandoned jobs were never
+// attempted, and Interrupted jobs were still sending when the deadline
+// passed; their sends are cancelled and their outcome is only reported to
+// OnResult.
+type ShutdownReport struct {
+	Sent        int
+	Failed      int
+	Evicted     int
+	Abandoned   []DispatchJob
+	Interrupted []DispatchJob
+	Err         error
+}
+
+type Dispatcher struct {
+	options    DispatcherOptions
+	mutex      sync.Mutex
+	condition  *sync.Cond
+	space      *sync.Cond
+	queue      []DispatchJob
+	closing    bool
+	abandoning bool
+	sent       int
+	failed     int
+	rejected   int
+	evicted    int
+	evictions  []DispatchEviction
+	workers    sync.WaitGroup
+
+	// sendCtx is cancelled when a shutdown deadline passes, interrupting the
+	// jobs in inFlight.
+	sendCtx    context.Context
+	cancelSend context.CancelFunc
+	started    uint64
+	inFlight   map[uint64]DispatchJob
+}
+
+func NewDispatcher(options DispatcherOptions) *Dispatcher {
+	if options.Workers <= 0 {
+		options.Workers = 1
+	}
+	if options.EvictionLogSize <= 0 {
+		options.EvictionLogSize = 1000
+	}
+
+	dispatcher := &Dispatcher{options: options, inFlight: map[uint64]DispatchJob{}}
+	dispatcher.sendCtx, dispatcher.cancelSend = context.WithCancel(context.Background())
+	dispatcher.condition = sync.NewCond(&dispatcher.mutex)
+	dispatcher.space = sync.NewCond(&dispatcher.mutex)
+
+	for range options.Workers {
+		dispatcher.workers.Add(1)
+		go dispatcher.work()
+	}
+
+	return dispatcher
+}
+
+func (dispatcher *Dispatcher) Enqueue(job DispatchJob) error {
+	return dispatcher.EnqueueContext(context.Background(), job)
+}
+
+func (dispatcher *Dispatcher) EnqueueContext(ctx context.Context, job DispatchJob) error {
+	if job.Send == nil {
+		return &ValidationError{Field: "send", Message: "Dispatch job has no send function"}
+	}
+
+	stopWaking := context.AfterFunc(ctx, func() {
+		dispatcher.mutex.Lock()
+		dispatcher.space.Broadcast()
+		dispatcher.mutex.Unlock()
+	})
+	defer stopWaking()
+
+	dispatcher.mutex.Lock()
+
+	var evicted *DispatchJob
+	for {
+		if dispatcher.closing {
+			dispatcher.mutex.Unlock()
+			return ErrShuttingDown
+		}
+
+		if dispatcher.options.Capacity <= 0 || len(dispatcher.queue) < dispatcher.options.Capacity {
+			break
+		}
+
+		policy := dispatcher.options.OverflowPolicy
+		if policy == OverflowReject ||
+			(policy == OverflowShedBulk && job.Priority == DispatchPriorityBulk) {
+			return dispatcher.reject(job, ErrQueueFull)
+		}
+
+		if policy == OverflowDropOldest || policy == OverflowShedBulk {
+			if evicted = dispatcher.evictOldestBulk(); evicted != nil {
+				break
+			}
+			if policy == OverflowDropOldest {
+				return dispatcher.reject(job, ErrQueueFull)
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			return dispatcher.reject(job, err)
+		}
+		dispatcher.space.Wait()
+	}
+
+	dispatcher.queue = append(dispatcher.queue, job)
+	dispatcher.condition.Signal()
+	dispatcher.mutex.Unlock()
+
+	if evicted != nil {
+		if dispatcher.options.OnEvicted != nil {
+			dispatcher.options.OnEvicted(*evicted)
+		}
+		if dispatcher.options.OnResult != nil {
+			dispatcher.options.OnResult(*evicted, ErrEvicted)
+		}
+	}
+
+	return nil
+}
+
+func (dispatcher *Dispatcher) reject(job DispatchJob, err error) error {
+	dispatcher.rejected++
+	dispatcher.mutex.Unlock()
+
+	if dispatcher.options.OnRejected != nil {
+		dispatcher.options.OnRejected(job, err)
+	}
+
+	return err
+}
+
+func (dispatcher *Dispatcher) evictOldestBulk() *DispatchJob {
+	for index, queued := range dispatcher.queue {
+		if queued.Priority == DispatchPriorityBulk {
+			dispatcher.queue = append(dispatcher.queue[:index], dispatcher.queue[index+1:]...)
+			dispatcher.evicted++
+
+			if len(dispatcher.evictions) == dispatcher.options.EvictionLogSize {
+				dispatcher.evictions = slices.Delete(dispatcher.evictions, 0, 1)
+			}
+			dispatcher.evictions = append(dispatcher.evictions, DispatchEviction{
+				JobID:    queued.ID,
+				Receiver: queued.Receiver,
+				Priority: queued.Priority,
+				Policy:   dispatcher.options.OverflowPolicy.String(),
+				Time:     time.Now(),
+			})
+
+			return &queued
+		}
+	}
+
+	return nil
+}
+
+func (dispatcher *Dispatcher) Stats() DispatcherStats {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	return DispatcherStats{
+		OverflowPolicy: dispatcher.options.OverflowPolicy.String(),
+		Capacity:       dispatcher.options.Capacity,
+		Queued:         len(dispatcher.queue),
+		Sent:           dispatcher.sent,
+		Failed:         dispatcher.failed,
+		Rejected:       dispatcher.rejected,
+		Evicted:        dispatcher.evicted,
+	}
+}
+
+// Evictions returns the most recent evictions, oldest first.
+func (dispatcher *Dispatcher) Evictions() []DispatchEviction {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	return slices.Clone(dispatcher.evictions)
+}
+
+// next takes the oldest queued job and records it as in flight under the
+// returned sequence number.
+func (dispatcher *Dispatcher) next() (DispatchJob, uint64, bool) {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	for len(dispatcher.queue) == 0 && !dispatcher.closing {
+		dispatcher.condition.Wait()
+	}
+
+	if dispatcher.abandoning || len(dispatcher.queue) == 0 {
+		return DispatchJob{}, 0, false
+	}
+
+	job := dispatcher.queue[0]
+	dispatcher.queue[0] = DispatchJob{}
+	dispatcher.queue = dispatcher.queue[1:]
+	dispatcher.space.Signal()
+
+	dispatcher.started++
+	dispatcher.inFlight[dispatcher.started] = job
+
+	return job, dispatcher.started, true
+}
+
+func (dispatcher *Dispatcher) work() {
+	defer dispatcher.workers.Done()
+
+	for {
+		job, sequence, ok := dispatcher.next()
+		if !ok {
+			return
+		}
+
+		err := job.Send(dispatcher.sendCtx)
+
+		dispatcher.mutex.Lock()
+		delete(dispatcher.inFlight, sequence)
+		if err == nil {
+			dispatcher.sent++
+		} else {
+			dispatcher.failed++
+		}
+		dispatcher.mutex.Unlock()
+
+		if dispatcher.options.OnResult != nil {
+			dispatcher.options.OnResult(job, err)
+		}
+	}
+}
+
+func (dispatcher *Dispatcher) Shutdown(ctx context.Context) (*ShutdownReport, error) {
+	dispatcher.mutex.Lock()
+	if dispatcher.closing {
+		dispatcher.mutex.Unlock()
+		return nil, ErrShuttingDown
+	}
+	dispatcher.closing = true
+	dispatcher.condition.Broadcast()
+	dispatcher.space.Broadcast()
+	dispatcher.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		dispatcher.workers.Wait()
+		close(done)
+	}()
+
+	report := &ShutdownReport{}
+
+	// Past the deadline, queued jobs are abandoned and in-flight sends are
+	// cancelled, but the report is returned without waiting for sends that
+	// ignore their context.
+	select {
+	case <-done:
+	case <-ctx.Done():
+		report.Err = ctx.Err()
+	}
+
+	dispatcher.mutex.Lock()
+	dispatcher.abandoning = true
+	dispatcher.cancelSend()
+	for _, sequence := range slices.Sorted(maps.Keys(dispatcher.inFlight)) {
+		report.Interrupted = append(report.Interrupted, dispatcher.inFlight[sequence])
+	}
+	report.Sent = dispatcher.sent
+	report.Failed = dispatcher.failed
+	report.Evicted = dispatcher.evicted
+	report.Abandoned = dispatcher.queue
+	dispatcher.queue = nil
+	dispatcher.mutex.Unlock()
+
+	for _, job := range report.Abandoned {
+		if dispatcher.options.OnAbandoned != nil {
+			dispatcher.options.OnAbandoned(job)
+		}
+		if dispatcher.options.OnResult != nil {
+			dispatcher.options.OnResult(job, ErrNotAttempted)
+		}
+	}
+
+	return report, nil
+}
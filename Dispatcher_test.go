@@ -0,0 +1,182 @@
+package messagingutilities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDispatcherShutdownAccounting(t *testing.T) {
+	const count = 1000
+	const deadline = 100 * time.Millisecond
+
+	mutex := sync.Mutex{}
+	attempted := map[string]int{}
+	results := map[string][]error{}
+	resultsDone := make(chan struct{})
+
+	dispatcher := NewDispatcher(DispatcherOptions{
+		Workers: 8,
+		OnResult: func(job DispatchJob, err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			results[job.Receiver] = append(results[job.Receiver], err)
+			if len(results) == count {
+				close(resultsDone)
+			}
+		},
+	})
+
+	// The first job hangs until the test releases it, ignoring its context,
+	// and the rest are slow but stop when their context is cancelled.
+	release := make(chan struct{})
+	defer close(release)
+	for index := range count {
+		receiver := fmt.Sprintf("receiver-%04d", index)
+		err := dispatcher.Enqueue(DispatchJob{
+			Receiver: receiver,
+			Send: func(ctx context.Context) error {
+				mutex.Lock()
+				attempted[receiver]++
+				mutex.Unlock()
+
+				if index == 0 {
+					<-release
+					return nil
+				}
+
+				select {
+				case <-time.After(5 * time.Millisecond):
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			},
+		})
+		if err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deadline)
+	defer cancel()
+
+	start := time.Now()
+	report, err := dispatcher.Shutdown(ctx)
+	if err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > deadline+time.Second {
+		t.Fatalf("Shutdown returned after %v, want about %v", elapsed, deadline)
+	}
+	if !errors.Is(report.Err, context.DeadlineExceeded) {
+		t.Errorf("got report error %v, want context.DeadlineExceeded", report.Err)
+	}
+	if err := dispatcher.Enqueue(DispatchJob{Send: func(context.Context) error { return nil }}); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("got Enqueue error %v after shutdown, want ErrShuttingDown", err)
+	}
+
+	total := report.Sent + report.Failed + len(report.Abandoned) + len(report.Interrupted)
+	if total != count {
+		t.Fatalf("sent %d + failed %d + abandoned %d + interrupted %d = %d, want %d",
+			report.Sent, report.Failed, len(report.Abandoned), len(report.Interrupted), total, count)
+	}
+	if len(report.Abandoned) == 0 {
+		t.Errorf("got no abandoned jobs, want the deadline to leave some queued")
+	}
+
+	interrupted := map[string]bool{}
+	for _, job := range report.Interrupted {
+		interrupted[job.Receiver] = true
+	}
+	if !interrupted["receiver-0000"] {
+		t.Errorf("hung job is not reported as interrupted")
+	}
+
+	// Releasing the hung job lets every job report exactly one result.
+	release <- struct{}{}
+	select {
+	case <-resultsDone:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for results")
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	// No job starts once the deadline has passed, so the attempted jobs are
+	// exactly those counted as sent, failed or interrupted.
+	if want := report.Sent + report.Failed + len(report.Interrupted); len(attempted) != want {
+		t.Errorf("got %d attempted jobs, want %d", len(attempted), want)
+	}
+	for receiver, sends := range attempted {
+		if sends != 1 {
+			t.Errorf("%s sent %d times", receiver, sends)
+		}
+	}
+	for _, job := range report.Abandoned {
+		if attempted[job.Receiver] > 0 {
+			t.Errorf("%s reported abandoned but was sent", job.Receiver)
+		}
+		if errs := results[job.Receiver]; len(errs) != 1 || !errors.Is(errs[0], ErrNotAttempted) {
+			t.Errorf("%s abandoned with results %v, want ErrNotAttempted", job.Receiver, errs)
+		}
+	}
+	for receiver, errs := range results {
+		if len(errs) != 1 {
+			t.Errorf("%s got %d results, want 1", receiver, len(errs))
+		}
+	}
+}
+
+func TestDispatcherShutdownDrains(t *testing.T) {
+	dispatcher := NewDispatcher(DispatcherOptions{Workers: 4})
+	for range 100 {
+		dispatcher.Enqueue(DispatchJob{Send: func(ctx context.Context) error { return nil }})
+	}
+
+	report, err := dispatcher.Shutdown(context.Background())
+	if err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if report.Sent != 100 || len(report.Abandoned) != 0 || len(report.Interrupted) != 0 || report.Err != nil {
+		t.Errorf("got report %+v, want all 100 sent", report)
+	}
+}
+
+func TestDispatcherEvictionLog(t *testing.T) {
+	release := make(chan struct{})
+	dispatcher := NewDispatcher(DispatcherOptions{Workers: 1, Capacity: 2, OverflowPolicy: OverflowShedBulk})
+	defer dispatcher.Shutdown(context.Background())
+	defer close(release)
+
+	started := make(chan struct{})
+	dispatcher.Enqueue(DispatchJob{ID: "busy", Send: func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}})
+	<-started
+
+	blocked := func(ctx context.Context) error { <-release; return nil }
+	dispatcher.Enqueue(DispatchJob{ID: "bulk-1", Receiver: "a", Priority: DispatchPriorityBulk, Send: blocked})
+	dispatcher.Enqueue(DispatchJob{ID: "bulk-2", Receiver: "b", Priority: DispatchPriorityBulk, Send: blocked})
+	if err := dispatcher.Enqueue(DispatchJob{ID: "urgent", Send: blocked}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	evictions := dispatcher.Evictions()
+	if len(evictions) != 1 {
+		t.Fatalf("got %d evictions, want 1", len(evictions))
+	}
+	if eviction := evictions[0]; eviction.JobID != "bulk-1" || eviction.Receiver != "a" ||
+		eviction.Policy != "shed-bulk" || eviction.Time.IsZero() {
+		t.Errorf("got eviction %+v, want bulk-1 evicted by shed-bulk", eviction)
+	}
+	if stats := dispatcher.Stats(); stats.Evicted != 1 || stats.OverflowPolicy != "shed-bulk" {
+		t.Errorf("got stats %+v, want 1 eviction under shed-bulk", stats)
+	}
+}
@@ -0,0 +1,528 @@
+package messagingutilities
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SNSWebhookOptions accepts SES bounce, complaint and delivery notifications
+// delivered through an SNS HTTPS subscription.
+type SNSWebhookOptions struct {
+	// TopicARNs limits notifications to these topics. Any topic is accepted
+	// when it is empty.
+	TopicARNs []string
+	// ConfirmSubscriptions visits the SubscribeURL of subscription
+	// confirmations so the subscription does not need to be confirmed by
+	// hand.
+	ConfirmSubscriptions bool
+}
+
+type snsMessage struct {
+	Type             string
+	MessageId        string
+	Token            string
+	TopicArn         string
+	Subject          string
+	Message          string
+	Timestamp        string
+	SubscribeURL     string
+	SignatureVersion string
+	Signature        string
+	SigningCertURL   string
+}
+
+// signedString builds the string SNS signs for the message type.
+func (message *snsMessage) signedString() string {
+	fields := [][2]string{{"Message", message.Message}, {"MessageId", message.MessageId}}
+	if message.Type == "Notification" {
+		if message.Subject != "" {
+			fields = append(fields, [2]string{"Subject", message.Subject})
+		}
+		fields = append(fields, [][2]string{
+			{"Timestamp", message.Timestamp},
+			{"TopicArn", message.TopicArn},
+			{"Type", message.Type},
+		}...)
+	} else {
+		fields = append(fields, [][2]string{
+			{"SubscribeURL", message.SubscribeURL},
+			{"Timestamp", message.Timestamp},
+			{"Token", message.Token},
+			{"TopicArn", message.TopicArn},
+			{"Type", message.Type},
+		}...)
+	}
+
+	builder := &strings.Builder{}
+	for _, field := range fields {
+		builder.WriteString(field[0] + "\n" + field[1] + "\n")
+	}
+
+	return builder.String()
+}
+
+// isSNSURL reports whether rawURL is an https URL of an SNS endpoint, so
+// neither certificates nor confirmations are fetched from elsewhere.
+func isSNSURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme != "https" {
+		return false
+	}
+
+	host := parsed.Hostname()
+	return strings.HasPrefix(host, "sns.") &&
+		(strings.HasSuffix(host, ".amazonaws.com") || strings.HasSuffix(host, ".amazonaws.com.cn"))
+}
+
+var snsCertificatePathPattern = regexp.MustCompile(`^/SimpleNotificationService-[0-9a-f]+\.pem$`)
+
+// snsCertificateURL returns rawURL in a canonical form when it names an SNS
+// signing certificate, so that variants of the URL cannot each trigger a
+// download and a cache entry.
+func snsCertificateURL(rawURL string) (string, bool) {
+	if !isSNSURL(rawURL) {
+		return "", false
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.User != nil || parsed.Port() != "" || parsed.RawQuery != "" || parsed.ForceQuery ||
+		parsed.Fragment != "" || parsed.RawPath != "" || !snsCertificatePathPattern.MatchString(parsed.Path) {
+		return "", false
+	}
+
+	return "https://" + parsed.Host + parsed.Path, true
+}
+
+func (handler *DeliveryWebhookHandler) snsCertificate(ctx context.Context, certificateURL string) (*x509.Certificate, error) {
+	handler.snsMutex.Lock()
+	certificate, ok := handler.snsCertificates[certificateURL]
+	handler.snsMutex.Unlock()
+	if ok {
+		return certificate, nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "GET", certificateURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := newProviderHTTPClient("sns").Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to download SNS certificate: %w", err)
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to download SNS certificate: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("SNS certificate download failed with status %d", response.StatusCode)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("Invalid SNS certificate")
+	}
+	certificate, err = x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid SNS certificate: %w", err)
+	}
+
+	handler.snsMutex.Lock()
+	handler.snsCertificates[certificateURL] = certificate
+	handler.snsMutex.Unlock()
+
+	return certificate, nil
+}
+
+func (handler *DeliveryWebhookHandler) verifySNSMessage(ctx context.Context, message *snsMessage) error {
+	certificateURL, ok := snsCertificateURL(message.SigningCertURL)
+	if !ok {
+		return fmt.Errorf("Untrusted SNS certificate URL")
+	}
+
+	hash := crypto.SHA1
+	var digest []byte
+	switch message.SignatureVersion {
+	case "1":
+		sum := sha1.Sum([]byte(message.signedString()))
+		digest = sum[:]
+	case "2":
+		hash = crypto.SHA256
+		sum := sha256.Sum256([]byte(message.signedString()))
+		digest = sum[:]
+	default:
+		return fmt.Errorf("Unsupported SNS signature version %s", message.SignatureVersion)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(message.Signature)
+	if err != nil {
+		return fmt.Errorf("Invalid SNS signature: %w", err)
+	}
+
+	certificate, err := handler.snsCertificate(ctx, certificateURL)
+	if err != nil {
+		return err
+	}
+	publicKey, ok := certificate.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("Unsupported SNS certificate key")
+	}
+
+	return rsa.VerifyPKCS1v15(publicKey, hash, digest, signature)
+}
+
+// handleSNS verifies an SNS message and returns the delivery events of a
+// notification. Other message types are answered directly, in which case
+// ok is false.
+func (handler *DeliveryWebhookHandler) handleSNS(
+	writer http.ResponseWriter,
+	request *http.Request,
+	body []byte,
+) ([]DeliveryEvent, bool) {
+	message := &snsMessage{}
+	if err := json.Unmarshal(body, message); err != nil {
+		http.Error(writer, "Invalid JSON payload", http.StatusBadRequest)
+		return nil, false
+	}
+
+	if len(handler.options.SES.TopicARNs) > 0 && !slices.Contains(handler.options.SES.TopicARNs, message.TopicArn) {
+		http.Error(writer, "Unknown topic", http.StatusForbidden)
+		return nil, false
+	}
+
+	if err := handler.verifySNSMessage(request.Context(), message); err != nil {
+		http.Error(writer, "Invalid signature", http.StatusForbidden)
+		return nil, false
+	}
+
+	switch message.Type {
+	case "Notification":
+		events, err := parseSESDeliveryEvents(message.Message)
+		if err != nil {
+			http.Error(writer, "Invalid SES notification", http.StatusBadRequest)
+			return nil, false
+		}
+		return events, true
+	case "SubscriptionConfirmation":
+		if handler.options.SES.ConfirmSubscriptions {
+			if !isSNSURL(message.SubscribeURL) {
+				http.Error(writer, "Untrusted subscribe URL", http.StatusForbidden)
+				return nil, false
+			}
+
+			confirmation, err := http.NewRequestWithContext(request.Context(), "GET", message.SubscribeURL, nil)
+			if err != nil {
+				http.Error(writer, "Invalid subscribe URL", http.StatusBadRequest)
+				return nil, false
+			}
+			response, err := newProviderHTTPClient("sns").Do(confirmation)
+			if err != nil {
+				http.Error(writer, "Could not confirm subscription", http.StatusBadGateway)
+				return nil, false
+			}
+			response.Body.Close()
+		}
+	}
+
+	writer.WriteHeader(http.StatusOK)
+	return nil, false
+}
+
+type sesRecipient struct {
+	EmailAddress   string `json:"emailAddress"`
+	Status         string `json:"status"`
+	DiagnosticCode string `json:"diagnosticCode"`
+}
+
+type sesNotification struct {
+	NotificationType string `json:"notificationType"`
+	EventType        string `json:"eventType"`
+	Mail             struct {
+		MessageID   string   `json:"messageId"`
+		Destination []string `json:"destination"`
+	} `json:"mail"`
+	Bounce struct {
+		BounceType        string         `json:"bounceType"`
+		BounceSubType     string         `json:"bounceSubType"`
+		BouncedRecipients []sesRecipient `json:"bouncedRecipients"`
+	} `json:"bounce"`
+	Complaint struct {
+		ComplaintFeedbackType string         `json:"complaintFeedbackType"`
+		ComplainedRecipients  []sesRecipient `json:"complainedRecipients"`
+	} `json:"complaint"`
+	Delivery struct {
+		Recipients   []string `json:"recipients"`
+		SMTPResponse string   `json:"smtpResponse"`
+	} `json:"delivery"`
+	Reject struct {
+		Reason string `json:"reason"`
+	} `json:"reject"`
+}
+
+// parseSESDeliveryEvents reads an SES notification or event publishing
+// record and returns one event per affected recipient.
+func parseSESDeliveryEvents(payload string) ([]DeliveryEvent, error) {
+	notification := sesNotification{}
+	if err := json.Unmarshal([]byte(payload), &notification); err != nil {
+		return nil, err
+	}
+
+	notificationType := notification.NotificationType
+	if notificationType == "" {
+		notificationType = notification.EventType
+	}
+
+	event := func(recipient string, status DeliveryStatus, providerStatus string) DeliveryEvent {
+		return DeliveryEvent{
+			Provider:       "ses",
+			Channel:        "email",
+			MessageID:      notification.Mail.MessageID,
+			Recipient:      recipient,
+			Status:         status,
+			ProviderStatus: providerStatus,
+			ReceivedAt:     time.Now(),
+			Raw:            map[string]string{"notification": payload},
+		}
+	}
+
+	events := []DeliveryEvent{}
+	switch notificationType {
+	case "Bounce":
+		providerStatus := notificationType + ":" + notification.Bounce.BounceType + ":" + notification.Bounce.BounceSubType
+		for _, recipient := range notification.Bounce.BouncedRecipients {
+			bounced := event(recipient.EmailAddress, DeliveryStatusBounced, providerStatus)
+			bounced.Permanent = notification.Bounce.BounceType == "Permanent"
+			bounced.ErrorCode = recipient.Status
+			bounced.ErrorMessage = recipient.DiagnosticCode
+			events = append(events, bounced)
+		}
+	case "Complaint":
+		providerStatus := notificationType + ":" + notification.Complaint.ComplaintFeedbackType
+		for _, recipient := range notification.Complaint.ComplainedRecipients {
+			events = append(events, event(recipient.EmailAddress, DeliveryStatusComplained, providerStatus))
+		}
+	case "Delivery":
+		for _, recipient := range notification.Delivery.Recipients {
+			delivered := event(recipient, DeliveryStatusDelivered, notificationType)
+			delivered.ErrorMessage = notification.Delivery.SMTPResponse
+			events = append(events, delivered)
+		}
+	case "Send":
+		for _, recipient := range notification.Mail.Destination {
+			events = append(events, event(recipient, DeliveryStatusSent, notificationType))
+		}
+	case "Reject":
+		for _, recipient := range notification.Mail.Destination {
+			rejected := event(recipient, DeliveryStatusFailed, notificationType)
+			rejected.ErrorMessage = notification.Reject.Reason
+			events = append(events, rejected)
+		}
+	}
+
+	return events, nil
+}
+
+// parseSendGridPublicKey reads the verification key of the SendGrid event
+// webhook, given either as base64 DER or PEM.
+func parseSendGridPublicKey(key string) (*ecdsa.PublicKey, error) {
+	var der []byte
+	if block, _ := pem.Decode([]byte(key)); block != nil {
+		der = block.Bytes
+	} else {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid SendGrid verification key: %w", err)
+		}
+		der = decoded
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid SendGrid verification key: %w", err)
+	}
+	publicKey, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("SendGrid verification key must be an ECDSA key")
+	}
+
+	return publicKey, nil
+}
+
+func verifySendGridSignature(publicKey *ecdsa.PublicKey, request *http.Request, body []byte) bool {
+	signature, err := base64.StdEncoding.DecodeString(request.Header.Get("X-Twilio-Email-Event-Webhook-Signature"))
+	if err != nil || len(signature) == 0 {
+		return false
+	}
+
+	digest := sha256.Sum256(append([]byte(request.Header.Get("X-Twilio-Email-Event-Webhook-Timestamp")), body...))
+	return ecdsa.VerifyASN1(publicKey, digest[:], signature)
+}
+
+type sendGridEvent struct {
+	Email        string `json:"email"`
+	Event        string `json:"event"`
+	SGMessageID  string `json:"sg_message_id"`
+	Type         string `json:"type"`
+	Reason       string `json:"reason"`
+	Status       string `json:"status"`
+	ResponseText string `json:"response"`
+}
+
+// parseSendGridDeliveryEvents reads a batch of SendGrid events. Engagement
+// events such as opens and clicks are ignored.
+func parseSendGridDeliveryEvents(body []byte) ([]DeliveryEvent, error) {
+	raws := []json.RawMessage{}
+	if err := json.Unmarshal(body, &raws); err != nil {
+		return nil, err
+	}
+
+	events := []DeliveryEvent{}
+	for _, raw := range raws {
+		sendGrid := sendGridEvent{}
+		if err := json.Unmarshal(raw, &sendGrid); err != nil {
+			return nil, err
+		}
+
+		event := DeliveryEvent{
+			Provider:       "sendgrid",
+			Channel:        "email",
+			Recipient:      sendGrid.Email,
+			ProviderStatus: sendGrid.Event,
+			ReceivedAt:     time.Now(),
+			Raw:            map[string]string{"event": string(raw)},
+		}
+		// Sends report the X-Message-Id header, which is the first part of
+		// sg_message_id.
+		event.MessageID, _, _ = strings.Cut(sendGrid.SGMessageID, ".")
+
+		switch sendGrid.Event {
+		case "processed":
+			event.Status = DeliveryStatusQueued
+		case "deferred":
+			event.Status = DeliveryStatusSent
+			event.ErrorMessage = sendGrid.ResponseText
+		case "delivered":
+			event.Status = DeliveryStatusDelivered
+		case "bounce":
+			event.Status = DeliveryStatusBounced
+			event.Permanent = sendGrid.Type != "blocked"
+			event.ErrorCode = sendGrid.Status
+			event.ErrorMessage = sendGrid.Reason
+		case "dropped":
+			event.Status = DeliveryStatusFailed
+			event.ErrorMessage = sendGrid.Reason
+		case "spamreport":
+			event.Status = DeliveryStatusComplained
+		case "unsubscribe":
+			event.Status = DeliveryStatusUnsubscribed
+		default:
+			continue
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+type mailgunWebhook struct {
+	Signature struct {
+		Timestamp string `json:"timestamp"`
+		Token     string `json:"token"`
+		Signature string `json:"signature"`
+	} `json:"signature"`
+	EventData struct {
+		Event          string `json:"event"`
+		Severity       string `json:"severity"`
+		Reason         string `json:"reason"`
+		Recipient      string `json:"recipient"`
+		DeliveryStatus struct {
+			Code        int    `json:"code"`
+			Message     string `json:"message"`
+			Description string `json:"description"`
+		} `json:"delivery-status"`
+		Message struct {
+			Headers struct {
+				MessageID string `json:"message-id"`
+			} `json:"headers"`
+		} `json:"message"`
+	} `json:"event-data"`
+}
+
+// parseMailgunDeliveryEvent verifies and reads a Mailgun webhook. ok is
+// false for events that are not about delivery.
+func parseMailgunDeliveryEvent(signingKey string, body []byte) (event DeliveryEvent, ok bool, err error) {
+	webhook := mailgunWebhook{}
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		return event, false, err
+	}
+
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte(webhook.Signature.Timestamp + webhook.Signature.Token))
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(webhook.Signature.Signature)) {
+		return event, false, errInvalidWebhookSignature
+	}
+
+	data := webhook.EventData
+	event = DeliveryEvent{
+		Provider:       "mailgun",
+		Channel:        "email",
+		Recipient:      data.Recipient,
+		ProviderStatus: data.Event,
+		ReceivedAt:     time.Now(),
+		Raw:            map[string]string{"event": string(body)},
+	}
+	// Sends report the id in angle brackets as it appears in the header.
+	if data.Message.Headers.MessageID != "" {
+		event.MessageID = "<" + strings.Trim(data.Message.Headers.MessageID, "<>") + ">"
+	}
+
+	switch data.Event {
+	case "accepted":
+		event.Status = DeliveryStatusQueued
+	case "delivered":
+		event.Status = DeliveryStatusDelivered
+	case "failed":
+		event.Status = DeliveryStatusBounced
+		event.Permanent = data.Severity == "permanent"
+		if data.DeliveryStatus.Code != 0 {
+			event.ErrorCode = fmt.Sprint(data.DeliveryStatus.Code)
+		}
+		event.ErrorMessage = data.DeliveryStatus.Description
+		if event.ErrorMessage == "" {
+			event.ErrorMessage = data.DeliveryStatus.Message
+		}
+		if event.ErrorMessage == "" {
+			event.ErrorMessage = data.Reason
+		}
+	case "complained":
+		event.Status = DeliveryStatusComplained
+	case "unsubscribed":
+		event.Status = DeliveryStatusUnsubscribed
+	default:
+		return event, false, nil
+	}
+
+	return event, true, nil
+}
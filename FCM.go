@@ -0,0 +1,180 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
+)
+
+// FCMCredentials holds the JSON key of a service account of the Firebase
+// project, as downloaded from the Google Cloud console.
+type FCMCredentials struct {
+	ServiceAccountJSON []byte
+}
+
+// FCMSender sends push notifications with the Firebase Cloud Messaging HTTP
+// v1 API. Receivers are registration tokens, or topics when they start with
+// "/topics/". Subject and Text become the notification title and body and
+// Metadata the data payload, and messages without either are sent as data
+// messages. Payload is merged into the FCM message, for platform options
+// such as "android" and "apns".
+type FCMSender struct {
+	Credentials *FCMCredentials
+	BaseURL     string
+
+	tokenMutex  sync.Mutex
+	tokenSource oauth2.TokenSource
+	projectID   string
+}
+
+func NewFCMSender(credentials *FCMCredentials) *FCMSender {
+	return &FCMSender{
+		Credentials: credentials,
+		BaseURL:     "https://fcm.googleapis.com/v1",
+	}
+}
+
+type fcmServiceAccount struct {
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body,omitempty"`
+}
+
+type fcmMessage struct {
+	Token        string            `json:"token,omitempty"`
+	Topic        string            `json:"topic,omitempty"`
+	Notification *fcmNotification  `json:"notification,omitempty"`
+	Data         map[string]string `json:"data,omitempty"`
+}
+
+type fcmRequest struct {
+	Message json.RawMessage `json:"message"`
+}
+
+type fcmResponse struct {
+	Name string `json:"name"`
+}
+
+func (sender *FCMSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	if len(sender.Credentials.ServiceAccountJSON) == 0 {
+		return nil, &ValidationError{Field: "serviceAccountJson", Message: "Service account JSON cannot be empty"}
+	}
+
+	return sendEachMessage(ctx, ChannelPush, "fcm", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sender.send(ctx, message, text, receiver)
+	})
+}
+
+func (sender *FCMSender) token() (*oauth2.Token, string, error) {
+	sender.tokenMutex.Lock()
+	defer sender.tokenMutex.Unlock()
+
+	if sender.tokenSource == nil {
+		var account fcmServiceAccount
+		if err := json.Unmarshal(sender.Credentials.ServiceAccountJSON, &account); err != nil {
+			return nil, "", &ValidationError{Field: "serviceAccountJson", Message: "Invalid service account JSON: " + err.Error()}
+		}
+		if account.ProjectID == "" || account.ClientEmail == "" || account.PrivateKey == "" {
+			return nil, "", &ValidationError{
+				Field:   "serviceAccountJson",
+				Message: "Service account JSON must have a project id, client email and private key",
+			}
+		}
+		if account.TokenURI == "" {
+			account.TokenURI = "https://oauth2.googleapis.com/token"
+		}
+
+		config := &jwt.Config{
+			Email:        account.ClientEmail,
+			PrivateKey:   []byte(account.PrivateKey),
+			PrivateKeyID: account.PrivateKeyID,
+			Scopes:       []string{"https://www.googleapis.com/auth/firebase.messaging"},
+			TokenURL:     account.TokenURI,
+		}
+		// The token source outlives any single send, so it is not bound to
+		// the context of the send that created it.
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, newProviderHTTPClient("fcm"))
+		sender.tokenSource = config.TokenSource(ctx)
+		sender.projectID = account.ProjectID
+	}
+
+	token, err := sender.tokenSource.Token()
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to get FCM access token: %w", err)
+	}
+
+	return token, sender.projectID, nil
+}
+
+func (sender *FCMSender) send(ctx context.Context, message *Message, text, receiver string) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("fcm", "push", err) }()
+
+	token, projectID, err := sender.token()
+	if err != nil {
+		return result, err
+	}
+
+	payload := fcmMessage{Data: message.Metadata}
+	if topic, ok := strings.CutPrefix(receiver, "/topics/"); ok {
+		payload.Topic = topic
+	} else {
+		payload.Token = receiver
+	}
+	if message.Subject != "" || text != "" {
+		payload.Notification = &fcmNotification{Title: message.Subject, Body: text}
+	}
+
+	body, err := encodeChatPayload(payload, message.Payload)
+	if err != nil {
+		return result, fmt.Errorf("Failed to encode FCM request: %w", err)
+	}
+	body, err = json.Marshal(fcmRequest{Message: body})
+	if err != nil {
+		return result, fmt.Errorf("Failed to encode FCM request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		strings.TrimSuffix(sender.BaseURL, "/")+"/projects/"+url.PathEscape(projectID)+"/messages:send",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return result, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	token.SetAuthHeader(request)
+
+	_, responseBody, err := doProviderRequest("fcm", "FCM", request)
+	result.Raw = string(responseBody)
+	if err != nil {
+		return result, err
+	}
+
+	var fcmResp fcmResponse
+	if err := json.Unmarshal(responseBody, &fcmResp); err != nil {
+		return result, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	// Messages are identified by their resource name,
+	// "projects/{project}/messages/{message}".
+	result.MessageID = fcmResp.Name
+
+	return result, nil
+}
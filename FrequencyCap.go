@@ -0,0 +1,330 @@
+package messagingutilities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+var ErrFrequencyCapped = errors.New("Recipient frequency cap reached")
+
+type MessageCategory string
+
+const (
+	MessageCategoryTransactional MessageCategory = "transactional"
+	MessageCategoryOTP           MessageCategory = "otp"
+	MessageCategoryNotification  MessageCategory = "notification"
+	MessageCategoryMarketing     MessageCategory = "marketing"
+)
+
+// FrequencyCounter stores the sends counted against frequency caps, keyed by
+// rule and recipient. Reserve must check the limit and record the send in
+// one atomic step, so that concurrent sends cannot both take the last slot;
+// with Redis, a sorted set updated by a Lua script does this.
+type FrequencyCounter interface {
+	// Reserve records a send at at unless key already has limit sends within
+	// window. It returns the number of sends within window before the call.
+	Reserve(ctx context.Context, key string, at time.Time, limit int, window time.Duration) (int, bool, error)
+	// Release removes a send recorded by Reserve when it did not go out.
+	Release(ctx context.Context, key string, at time.Time) error
+}
+
+type MemoryFrequencyCounter struct {
+	mutex  sync.Mutex
+	events map[string][]time.Time
+}
+
+func NewMemoryFrequencyCounter() *MemoryFrequencyCounter {
+	return &MemoryFrequencyCounter{events: map[string][]time.Time{}}
+}
+
+func (counter *MemoryFrequencyCounter) Reserve(
+	ctx context.Context,
+	key string,
+	at time.Time,
+	limit int,
+	window time.Duration,
+) (int, bool, error) {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+
+	oldest := at.Add(-window)
+	events := counter.events[key][:0]
+	for _, existing := range counter.events[key] {
+		if existing.After(oldest) {
+			events = append(events, existing)
+		}
+	}
+	counter.events[key] = events
+
+	if len(events) >= limit {
+		return len(events), false, nil
+	}
+	counter.events[key] = append(events, at)
+
+	return len(events), true, nil
+}
+
+func (counter *MemoryFrequencyCounter) Release(ctx context.Context, key string, at time.Time) error {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+
+	events := counter.events[key]
+	if index := slices.IndexFunc(events, at.Equal); index >= 0 {
+		counter.events[key] = slices.Delete(events, index, index+1)
+	}
+
+	return nil
+}
+
+type FrequencyCapRule struct {
+	Channel  Channel
+	Category MessageCategory
+	Limit    int
+	Window   time.Duration
+}
+
+func (rule *FrequencyCapRule) matches(message *Message) bool {
+	return (rule.Channel == "" || rule.Channel == message.Channel) &&
+		(rule.Category == "" || rule.Category == message.Category)
+}
+
+// frequencyRecipient is the form recipients are counted under, so that a
+// number in local and international formats or an address in any case
+// share one cap.
+func frequencyRecipient(channel Channel, recipient string) string {
+	if channel == ChannelEmail {
+		_, recipient = splitEmailAddress(recipient)
+	}
+
+	return normalizeSuppressedAddress(channel, recipient)
+}
+
+func (rule *FrequencyCapRule) key(recipient string) string {
+	channel, category := string(rule.Channel), string(rule.Category)
+	if channel == "" {
+		channel = "*"
+	}
+	if category == "" {
+		category = "*"
+	}
+
+	return fmt.Sprintf("%s|%s|%s", channel, category, recipient)
+}
+
+// DigestQueue holds the messages capped recipients were not sent, each
+// addressed to that recipient alone.
+type DigestQueue struct {
+	mutex    sync.Mutex
+	messages map[string][]*Message
+}
+
+func NewDigestQueue() *DigestQueue {
+	return &DigestQueue{messages: map[string][]*Message{}}
+}
+
+func (queue *DigestQueue) Add(recipient string, message *Message) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	queue.messages[recipient] = append(queue.messages[recipient], message)
+}
+
+func (queue *DigestQueue) Flush() map[string][]*Message {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	messages := queue.messages
+	queue.messages = map[string][]*Message{}
+
+	return messages
+}
+
+type FrequencyDecision string
+
+const (
+	FrequencyDecisionAllowed  FrequencyDecision = "allowed"
+	FrequencyDecisionExempt   FrequencyDecision = "exempt"
+	FrequencyDecisionCapped   FrequencyDecision = "capped"
+	FrequencyDecisionDigested FrequencyDecision = "digested"
+)
+
+// FrequencyCapResult is the decision for one recipient. Count includes the
+// send when it was allowed, and Limit and Window are those of the last rule
+// checked, which is the rule that capped the recipient.
+type FrequencyCapResult struct {
+	Recipient string
+	Decision  FrequencyDecision
+	Count     int
+	Limit     int
+	Window    time.Duration
+}
+
+type FrequencyCapper struct {
+	Rules            []FrequencyCapRule
+	Counter          FrequencyCounter
+	ExemptCategories []MessageCategory
+	Digest           *DigestQueue
+}
+
+func NewFrequencyCapper(counter FrequencyCounter, rules ...FrequencyCapRule) *FrequencyCapper {
+	return &FrequencyCapper{
+		Rules:            rules,
+		Counter:          counter,
+		ExemptCategories: []MessageCategory{MessageCategoryTransactional, MessageCategoryOTP},
+	}
+}
+
+type frequencyReservation struct {
+	key string
+	at  time.Time
+}
+
+// reserve takes a slot for recipient under every matching rule, releasing
+// the slots already taken when a rule caps the recipient.
+func (capper *FrequencyCapper) reserve(
+	ctx context.Context,
+	message *Message,
+	recipient string,
+	at time.Time,
+) (FrequencyCapResult, []frequencyReservation, error) {
+	result := FrequencyCapResult{Recipient: recipient, Decision: FrequencyDecisionAllowed}
+	reservations := []frequencyReservation{}
+
+	for index := range capper.Rules {
+		rule := &capper.Rules[index]
+		if !rule.matches(message) {
+			continue
+		}
+
+		key := rule.key(frequencyRecipient(message.Channel, recipient))
+		count, reserved, err := capper.Counter.Reserve(ctx, key, at, rule.Limit, rule.Window)
+		if err != nil {
+			capper.release(ctx, reservations)
+			return result, nil, fmt.Errorf("Failed to update frequency counter: %w", err)
+		}
+
+		result.Count, result.Limit, result.Window = count, rule.Limit, rule.Window
+		if !reserved {
+			capper.release(ctx, reservations)
+			result.Decision = FrequencyDecisionCapped
+			return result, nil, nil
+		}
+		result.Count++
+		reservations = append(reservations, frequencyReservation{key: key, at: at})
+	}
+
+	return result, reservations, nil
+}
+
+func (capper *FrequencyCapper) release(ctx context.Context, reservations []frequencyReservation) {
+	for _, reservation := range reservations {
+		capper.Counter.Release(ctx, reservation.key, reservation.at)
+	}
+}
+
+// Send reserves a slot for each To recipient of message before sending it
+// to the recipients under their caps. Capped recipients are added to Digest
+// when it is set and otherwise reported with ErrFrequencyCapped, which is
+// only returned when no recipient could be sent the message. The slots of
+// recipients the sender did not report as sent are released.
+func (capper *FrequencyCapper) Send(
+	ctx context.Context,
+	sender Sender,
+	message *Message,
+) (*SendResult, []FrequencyCapResult, error) {
+	if message == nil {
+		return nil, nil, &ValidationError{Field: "message", Message: "Message cannot be empty"}
+	}
+
+	results := []FrequencyCapResult{}
+
+	if slices.Contains(capper.ExemptCategories, message.Category) {
+		for _, recipient := range message.To {
+			results = append(results, FrequencyCapResult{Recipient: recipient, Decision: FrequencyDecisionExempt})
+		}
+		result, err := sender.Send(ctx, message)
+		return result, results, err
+	}
+
+	now := time.Now()
+	allowed := []string{}
+	reservations := map[string][]frequencyReservation{}
+	capped := false
+	for _, recipient := range message.To {
+		result, reserved, err := capper.reserve(ctx, message, recipient, now)
+		if err != nil {
+			for _, taken := range reservations {
+				capper.release(ctx, taken)
+			}
+			return nil, results, err
+		}
+
+		if result.Decision == FrequencyDecisionCapped {
+			if capper.Digest != nil {
+				digested := *message
+				digested.To = []string{recipient}
+				digested.Cc, digested.Bcc = nil, nil
+				capper.Digest.Add(recipient, &digested)
+				result.Decision = FrequencyDecisionDigested
+			} else {
+				capped = true
+			}
+		} else {
+			allowed = append(allowed, recipient)
+			reservations[recipient] = reserved
+		}
+		results = append(results, result)
+	}
+
+	if len(allowed) == 0 {
+		if capped {
+			return nil, results, ErrFrequencyCapped
+		}
+		return nil, results, nil
+	}
+
+	copied := *message
+	copied.To = allowed
+	result, err := sender.Send(ctx, &copied)
+	if err != nil {
+		sent := map[string]bool{}
+		if result != nil {
+			for _, recipient := range result.Recipients {
+				sent[frequencyRecipient(message.Channel, recipient)] = true
+			}
+		}
+		for recipient, taken := range reservations {
+			if !sent[frequencyRecipient(message.Channel, recipient)] {
+				capper.release(ctx, taken)
+			}
+		}
+	}
+
+	return result, results, err
+}
+
+// FrequencyCappedSender is a Sender that applies the caps of Capper to the
+// messages it sends with Sender. OnDecision, when set, is called with the
+// decisions for the recipients of every message.
+type FrequencyCappedSender struct {
+	Capper     *FrequencyCapper
+	Sender     Sender
+	OnDecision func(message *Message, results []FrequencyCapResult)
+}
+
+func NewFrequencyCappedSender(capper *FrequencyCapper, sender Sender) *FrequencyCappedSender {
+	return &FrequencyCappedSender{Capper: capper, Sender: sender}
+}
+
+func (sender *FrequencyCappedSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	result, decisions, err := sender.Capper.Send(ctx, sender.Sender, message)
+	if sender.OnDecision != nil && len(decisions) > 0 {
+		sender.OnDecision(message, decisions)
+	}
+
+	return result, err
+}
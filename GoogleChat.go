@@ -0,0 +1,119 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// GoogleChatCredentials posts through the incoming webhook URL of a space,
+// including its key and token parameters.
+type GoogleChatCredentials struct {
+	WebhookURL string
+}
+
+// GoogleChatSender posts Text as a text message. Messages with a Subject are
+// sent as a card with the subject as its header, and cards of your own are
+// passed through with Payload("cardsV2", ...).
+type GoogleChatSender struct {
+	Credentials *GoogleChatCredentials
+}
+
+func NewGoogleChatSender(credentials *GoogleChatCredentials) *GoogleChatSender {
+	return &GoogleChatSender{Credentials: credentials}
+}
+
+type googleChatWidget struct {
+	TextParagraph struct {
+		Text string `json:"text"`
+	} `json:"textParagraph"`
+}
+
+type googleChatSection struct {
+	Widgets []googleChatWidget `json:"widgets"`
+}
+
+type googleChatCard struct {
+	CardID string `json:"cardId"`
+	Card   struct {
+		Header struct {
+			Title string `json:"title"`
+		} `json:"header"`
+		Sections []googleChatSection `json:"sections,omitempty"`
+	} `json:"card"`
+}
+
+type googleChatRequest struct {
+	Text    string           `json:"text,omitempty"`
+	CardsV2 []googleChatCard `json:"cardsV2,omitempty"`
+}
+
+type googleChatResponse struct {
+	Name string `json:"name"`
+}
+
+func (sender *GoogleChatSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	if sender.Credentials.WebhookURL == "" {
+		return nil, &ValidationError{Field: "webhookUrl", Message: "Google Chat webhook URL cannot be empty"}
+	}
+	if message != nil && len(message.To) > 0 {
+		return nil, &ValidationError{Field: "to", Message: "Google Chat webhooks post to a fixed space"}
+	}
+
+	message = withDefaultReceiver(message, "webhook")
+
+	return sendEachMessage(ctx, ChannelChat, "googlechat", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sender.send(ctx, message, text)
+	})
+}
+
+func (sender *GoogleChatSender) send(ctx context.Context, message *Message, text string) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("googlechat", "chat", err) }()
+
+	if text, err = chatText(message, text); err != nil {
+		return result, err
+	}
+
+	payload := googleChatRequest{Text: text}
+	if message.Subject != "" {
+		card := googleChatCard{CardID: "message"}
+		card.Card.Header.Title = message.Subject
+		if text != "" {
+			widget := googleChatWidget{}
+			widget.TextParagraph.Text = text
+			card.Card.Sections = []googleChatSection{{Widgets: []googleChatWidget{widget}}}
+		}
+		payload = googleChatRequest{CardsV2: []googleChatCard{card}}
+	}
+
+	body, err := encodeChatPayload(payload, message.Payload)
+	if err != nil {
+		return result, fmt.Errorf("Failed to encode Google Chat request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "POST", sender.Credentials.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return result, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+	_, responseBody, err := doProviderRequest("googlechat", "Google Chat", request)
+	result.Raw = string(responseBody)
+	if err != nil {
+		return result, err
+	}
+
+	var chatResp googleChatResponse
+	if err := json.Unmarshal(responseBody, &chatResp); err != nil {
+		return result, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	// Messages are identified by their resource name,
+	// "spaces/{space}/messages/{message}".
+	result.MessageID = chatResp.Name
+
+	return result, nil
+}
@@ -0,0 +1,160 @@
+package messagingutilities
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var htmlTextSkippedElements = map[string]bool{
+	"head":     true,
+	"script":   true,
+	"style":    true,
+	"template": true,
+	"noscript": true,
+}
+
+var htmlTextBlockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true,
+	"div": true, "dl": true, "fieldset": true,
+	"figure": true, "footer": true, "form": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true,
+	"hr": true, "main": true, "nav": true, "ol": true,
+	"p": true, "pre": true, "section": true, "table": true, "ul": true,
+}
+
+var htmlTextLineElements = map[string]bool{
+	"dd": true,
+	"dt": true,
+	"li": true,
+	"tr": true,
+}
+
+type htmlTextWriter struct {
+	builder  strings.Builder
+	newlines int
+	space    bool
+}
+
+func (writer *htmlTextWriter) text(value string) {
+	if strings.TrimLeft(value, " \t\r\n\f") != value {
+		writer.space = true
+	}
+	for index, word := range strings.Fields(value) {
+		if (index > 0 || writer.space) && writer.newlines == 0 && writer.builder.Len() > 0 {
+			writer.builder.WriteByte(' ')
+		}
+		writer.builder.WriteString(word)
+		writer.newlines = 0
+		writer.space = false
+	}
+	if strings.TrimRight(value, " \t\r\n\f") != value {
+		writer.space = true
+	}
+}
+
+func (writer *htmlTextWriter) newline(count int) {
+	if writer.builder.Len() == 0 {
+		return
+	}
+	for writer.newlines < count {
+		writer.builder.WriteByte('\n')
+		writer.newlines++
+	}
+	writer.space = false
+}
+
+// HTMLToText renders an HTML body as readable plain text. Paragraphs and
+// other blocks are separated by blank lines, list items are prefixed with a
+// dash and links keep their target in parentheses after the link text.
+func HTMLToText(document string) (string, error) {
+	root, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return "", fmt.Errorf("Failed to parse html: %w", err)
+	}
+
+	writer := &htmlTextWriter{}
+	writer.walk(root)
+
+	return strings.TrimSpace(writer.builder.String()), nil
+}
+
+func (writer *htmlTextWriter) walk(node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		writer.text(node.Data)
+		return
+	case html.ElementNode:
+	default:
+		for child := range node.ChildNodes() {
+			writer.walk(child)
+		}
+		return
+	}
+
+	if htmlTextSkippedElements[node.Data] {
+		return
+	}
+
+	switch node.Data {
+	case "br":
+		writer.builder.WriteByte('\n')
+		writer.newlines++
+		writer.space = false
+		return
+	case "img":
+		writer.text(htmlAttribute(node, "alt"))
+		return
+	case "pre":
+		writer.newline(2)
+		for descendant := range node.Descendants() {
+			if descendant.Type == html.TextNode {
+				writer.builder.WriteString(descendant.Data)
+			}
+		}
+		writer.newlines = 0
+		writer.newline(2)
+		return
+	case "td", "th":
+		writer.text(" ")
+	}
+
+	if htmlTextLineElements[node.Data] {
+		writer.newline(1)
+	} else if htmlTextBlockElements[node.Data] {
+		writer.newline(2)
+	}
+	if node.Data == "li" {
+		writer.text("- ")
+	}
+
+	start := writer.builder.Len()
+	for child := range node.ChildNodes() {
+		writer.walk(child)
+	}
+
+	if node.Data == "a" {
+		href := strings.TrimSpace(htmlAttribute(node, "href"))
+		label := strings.TrimSpace(writer.builder.String()[start:])
+		if href != "" && !strings.HasPrefix(href, "#") && href != label && "mailto:"+label != href {
+			writer.text(" (" + href + ")")
+		}
+	}
+
+	if htmlTextLineElements[node.Data] {
+		writer.newline(1)
+	} else if htmlTextBlockElements[node.Data] {
+		writer.newline(2)
+	}
+}
+
+func htmlAttribute(node *html.Node, key string) string {
+	for _, attribute := range node.Attr {
+		if attribute.Key == key {
+			return attribute.Val
+		}
+	}
+
+	return ""
+}
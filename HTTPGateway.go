@@ -0,0 +1,251 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// HTTPGatewayOptions describes the API of an SMS gateway without a dedicated
+// sender. URL, the header values, the parameter values and Body are
+// text/template templates executed with HTTPGatewayData; a "json" function
+// quotes a value for use in JSON bodies. The fields are escaped with
+// url.QueryEscape when URL is executed, so they can be placed in its query
+// string as they are. For example:
+//
+//	HTTPGatewayOptions{
+//		URL:            "https://sms.example.com/api/send",
+//		Params:         map[string]string{"to": "{{.To}}", "from": "{{.From}}", "text": "{{.Text}}"},
+//		MessageIDField: "data.id",
+//	}
+type HTTPGatewayOptions struct {
+	// Name identifies the gateway in send results and statistics. It
+	// defaults to "http".
+	Name string
+	// Method defaults to POST.
+	Method  string
+	URL     string
+	Headers map[string]string
+	// Params are sent in the query string of GET requests and as a form
+	// body otherwise. They cannot be combined with Body.
+	Params map[string]string
+	// Body is sent as is, with ContentType defaulting to application/json.
+	Body        string
+	ContentType string
+	// From is passed to the templates as the sender.
+	From string
+	// MessageIDField is the dot-separated path of the message id in a JSON
+	// response, such as "messages.0.id".
+	MessageIDField string
+}
+
+type HTTPGatewayData struct {
+	To   string
+	From string
+	Text string
+}
+
+type HTTPGatewaySender struct {
+	options HTTPGatewayOptions
+	url     *template.Template
+	headers map[string]*template.Template
+	params  map[string]*template.Template
+	body    *template.Template
+}
+
+func NewHTTPGatewaySender(options HTTPGatewayOptions) (*HTTPGatewaySender, error) {
+	if options.URL == "" {
+		return nil, fmt.Errorf("A gateway URL is required")
+	}
+	if options.Body != "" && len(options.Params) > 0 {
+		return nil, fmt.Errorf("Gateway parameters and body cannot both be set")
+	}
+
+	if options.Name == "" {
+		options.Name = "http"
+	}
+	if options.Method == "" {
+		options.Method = http.MethodPost
+	}
+	options.Method = strings.ToUpper(options.Method)
+	if options.Body != "" && options.Method == http.MethodGet {
+		return nil, fmt.Errorf("A gateway body cannot be sent with GET")
+	}
+	if options.ContentType == "" {
+		options.ContentType = "application/json"
+	}
+
+	parse := func(name, source string) (*template.Template, error) {
+		parsed, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{
+			"json": func(value string) (string, error) {
+				encoded, err := json.Marshal(value)
+				return string(encoded), err
+			},
+		}).Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse gateway %s template: %w", name, err)
+		}
+
+		return parsed, nil
+	}
+
+	sender := &HTTPGatewaySender{
+		options: options,
+		headers: map[string]*template.Template{},
+		params:  map[string]*template.Template{},
+	}
+
+	var err error
+	if sender.url, err = parse("url", options.URL); err != nil {
+		return nil, err
+	}
+	if options.Body != "" {
+		if sender.body, err = parse("body", options.Body); err != nil {
+			return nil, err
+		}
+	}
+	for name, value := range options.Headers {
+		if sender.headers[name], err = parse("header "+name, value); err != nil {
+			return nil, err
+		}
+	}
+	for name, value := range options.Params {
+		if sender.params[name], err = parse("parameter "+name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return sender, nil
+}
+
+func (sender *HTTPGatewaySender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	return sendEachSms(ctx, sender.options.Name, message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sender.send(ctx, text, receiver)
+	})
+}
+
+func (sender *HTTPGatewaySender) send(ctx context.Context, text, receiver string) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult(sender.options.Name, "sms", err) }()
+
+	data := HTTPGatewayData{To: receiver, From: sender.options.From, Text: text}
+	execute := func(parsed *template.Template, data HTTPGatewayData) (string, error) {
+		builder := &strings.Builder{}
+		if err := parsed.Execute(builder, data); err != nil {
+			return "", fmt.Errorf("Failed to render gateway request: %w", err)
+		}
+
+		return builder.String(), nil
+	}
+	requestURL, err := execute(sender.url, HTTPGatewayData{
+		To:   url.QueryEscape(data.To),
+		From: url.QueryEscape(data.From),
+		Text: url.QueryEscape(data.Text),
+	})
+	if err != nil {
+		return result, err
+	}
+
+	params := url.Values{}
+	for name, parsed := range sender.params {
+		value, err := execute(parsed, data)
+		if err != nil {
+			return result, err
+		}
+		params.Set(name, value)
+	}
+
+	body, contentType := "", ""
+	switch {
+	case sender.body != nil:
+		if body, err = execute(sender.body, data); err != nil {
+			return result, err
+		}
+		contentType = sender.options.ContentType
+	case len(params) > 0 && sender.options.Method == http.MethodGet:
+		separator := "?"
+		if strings.Contains(requestURL, "?") {
+			separator = "&"
+		}
+		requestURL += separator + params.Encode()
+	case len(params) > 0:
+		body = params.Encode()
+		contentType = "application/x-www-form-urlencoded"
+	}
+
+	var bodyReader io.Reader
+	if body != "" {
+		bodyReader = strings.NewReader(body)
+	}
+	request, err := http.NewRequestWithContext(ctx, sender.options.Method, requestURL, bodyReader)
+	if err != nil {
+		return result, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	for name, parsed := range sender.headers {
+		value, err := execute(parsed, data)
+		if err != nil {
+			return result, err
+		}
+		request.Header.Set(name, value)
+	}
+
+	_, responseBody, err := doProviderRequest(sender.options.Name, "Gateway "+sender.options.Name, request)
+	result.Raw = string(responseBody)
+	if err != nil {
+		return result, err
+	}
+
+	if sender.options.MessageIDField != "" {
+		var decoded any
+		decoder := json.NewDecoder(bytes.NewReader(responseBody))
+		decoder.UseNumber()
+		if err := decoder.Decode(&decoded); err != nil {
+			return result, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+		}
+		result.MessageID = jsonFieldString(decoded, sender.options.MessageIDField)
+	}
+
+	return result, nil
+}
+
+// jsonFieldString follows the dot-separated path through decoded JSON, using
+// numeric segments as array indexes, and returns the value found as a
+// string.
+func jsonFieldString(value any, path string) string {
+	for _, segment := range strings.Split(path, ".") {
+		switch typed := value.(type) {
+		case map[string]any:
+			value = typed[segment]
+		case []any:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(typed) {
+				return ""
+			}
+			value = typed[index]
+		default:
+			return ""
+		}
+	}
+
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case json.Number:
+		return typed.String()
+	case nil:
+		return ""
+	default:
+		encoded, _ := json.Marshal(typed)
+		return string(encoded)
+	}
+}
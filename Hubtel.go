@@ -0,0 +1,157 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HubtelCredentials sends from SenderID, a registered sender ID or phone
+// number.
+type HubtelCredentials struct {
+	ClientID     string
+	ClientSecret string
+	SenderID     string
+}
+
+type HubtelSender struct {
+	Credentials *HubtelCredentials
+	BaseURL     string
+}
+
+func NewHubtelSender(credentials *HubtelCredentials) *HubtelSender {
+	return &HubtelSender{
+		Credentials: credentials,
+		BaseURL:     "https://sms.hubtel.com",
+	}
+}
+
+type hubtelRequest struct {
+	From               string `json:"From"`
+	To                 string `json:"To"`
+	Content            string `json:"Content"`
+	RegisteredDelivery bool   `json:"RegisteredDelivery"`
+}
+
+type hubtelResponse struct {
+	MessageID         string  `json:"messageId"`
+	Rate              float64 `json:"rate"`
+	Status            int     `json:"status"`
+	StatusDescription string  `json:"statusDescription"`
+}
+
+func (sender *HubtelSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	return sendEachSms(ctx, "hubtel", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sender.send(ctx, text, receiver)
+	})
+}
+
+func (sender *HubtelSender) send(ctx context.Context, text, receiver string) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("hubtel", "sms", err) }()
+
+	body, err := json.Marshal(hubtelRequest{
+		From:               sender.Credentials.SenderID,
+		To:                 receiver,
+		Content:            text,
+		RegisteredDelivery: true,
+	})
+	if err != nil {
+		return result, fmt.Errorf("Failed to encode Hubtel request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		strings.TrimSuffix(sender.BaseURL, "/")+"/v1/messages/send",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return result, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	request.SetBasicAuth(sender.Credentials.ClientID, sender.Credentials.ClientSecret)
+
+	_, responseBody, err := doProviderRequest("hubtel", "Hubtel", request)
+	result.Raw = string(responseBody)
+	if err != nil {
+		return result, err
+	}
+
+	var hubtelResp hubtelResponse
+	if err := json.Unmarshal(responseBody, &hubtelResp); err != nil {
+		return result, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	// Hubtel reports an accepted message with status 0.
+	if hubtelResp.Status != 0 {
+		return result, fmt.Errorf("Hubtel rejected the message (%d): %s", hubtelResp.Status, hubtelResp.StatusDescription)
+	}
+
+	result.MessageID = hubtelResp.MessageID
+	result.Status = string(DeliveryStatusQueued)
+	if hubtelResp.Rate > 0 {
+		result.Cost = strconv.FormatFloat(hubtelResp.Rate, 'f', -1, 64) + " GHS"
+	}
+
+	return result, nil
+}
+
+// hubtelDeliveryReport is the JSON body of a Hubtel delivery callback. Field
+// names are matched case-insensitively, as Hubtel has used both spellings.
+type hubtelDeliveryReport struct {
+	MessageID   string `json:"messageId"`
+	To          string `json:"to"`
+	Status      string `json:"status"`
+	Description string `json:"statusDescription"`
+	NetworkID   string `json:"networkId"`
+}
+
+func parseHubtelDeliveryEvent(body []byte) (DeliveryEvent, error) {
+	var report hubtelDeliveryReport
+	if err := json.Unmarshal(body, &report); err != nil {
+		return DeliveryEvent{}, err
+	}
+
+	status := DeliveryStatusUnknown
+	switch strings.ToLower(report.Status) {
+	case "pending", "submitted", "queued":
+		status = DeliveryStatusQueued
+	case "sent", "enroute", "buffered":
+		status = DeliveryStatusSent
+	case "delivered":
+		status = DeliveryStatusDelivered
+	case "undelivered", "undeliverable", "expired":
+		status = DeliveryStatusUndelivered
+	case "rejected", "failed", "deleted":
+		status = DeliveryStatusFailed
+	}
+
+	event := DeliveryEvent{
+		Provider:       "hubtel",
+		Channel:        "sms",
+		MessageID:      report.MessageID,
+		Recipient:      report.To,
+		Status:         status,
+		ProviderStatus: report.Status,
+		ReceivedAt:     time.Now(),
+		Raw: map[string]string{
+			"messageId":         report.MessageID,
+			"to":                report.To,
+			"status":            report.Status,
+			"statusDescription": report.Description,
+			"networkId":         report.NetworkID,
+		},
+	}
+	if status == DeliveryStatusUndelivered || status == DeliveryStatusFailed {
+		event.ErrorMessage = report.Description
+	}
+
+	return event, nil
+}
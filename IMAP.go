@@ -0,0 +1,565 @@
+package messagingutilities
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// IMAPCredentials picks a TLS mode from the port when TLSMode is empty:
+// implicit TLS on 993, otherwise STARTTLS, which is always required since
+// credentials are sent in the clear without it. Set TLSModeNone explicitly
+// for local test servers. When TokenSource is set the connection
+// authenticates with XOAUTH2 as User instead of a password.
+type IMAPCredentials struct {
+	Host        string
+	Port        string
+	User        string
+	Password    string
+	TLSMode     TLSMode
+	TLSConfig   *tls.Config
+	TokenSource oauth2.TokenSource
+}
+
+type IMAPReceiverOptions struct {
+	// Mailbox defaults to INBOX.
+	Mailbox string
+	// MarkSeen flags fetched messages as \Seen. Otherwise messages are left
+	// unread and only messages newer than the last one delivered are fetched.
+	MarkSeen bool
+	// PollInterval is the time between checks when the server does not
+	// support IDLE or UseIdle is false. It defaults to one minute.
+	PollInterval time.Duration
+	// UseIdle waits for new messages with IDLE when the server supports it.
+	UseIdle bool
+}
+
+// IMAPReceiver reads unseen messages from a mailbox.
+type IMAPReceiver struct {
+	Credentials *IMAPCredentials
+	options     IMAPReceiverOptions
+
+	mutex       sync.Mutex
+	uidValidity string
+	lastUID     uint32
+}
+
+func NewIMAPReceiver(credentials *IMAPCredentials, options IMAPReceiverOptions) *IMAPReceiver {
+	if options.Mailbox == "" {
+		options.Mailbox = "INBOX"
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = time.Minute
+	}
+
+	return &IMAPReceiver{Credentials: credentials, options: options}
+}
+
+// Fetch connects, returns the unseen messages of the mailbox and logs out.
+func (receiver *IMAPReceiver) Fetch(ctx context.Context) ([]*InboundEmail, error) {
+	conn, err := receiver.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.logout()
+
+	return receiver.fetch(ctx, conn)
+}
+
+// Listen delivers unseen messages to messages until ctx is done, waiting
+// for new ones with IDLE or by polling. It returns ctx.Err() when ctx is
+// done and any connection or protocol error otherwise; callers that need to
+// keep listening can call it again.
+func (receiver *IMAPReceiver) Listen(ctx context.Context, messages chan<- *InboundEmail) error {
+	conn, err := receiver.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.logout()
+
+	idle := receiver.options.UseIdle && conn.capabilities["IDLE"]
+	for {
+		emails, err := receiver.fetch(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, email := range emails {
+			select {
+			case messages <- email:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if idle {
+			err = conn.idle(ctx, receiver.options.PollInterval)
+		} else {
+			select {
+			case <-time.After(receiver.options.PollInterval):
+			case <-ctx.Done():
+			}
+			// NOOP keeps the connection alive between polls.
+			if ctx.Err() == nil {
+				_, err = conn.command(ctx, "NOOP", nil)
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (receiver *IMAPReceiver) open(ctx context.Context) (*imapConn, error) {
+	conn, err := dialIMAP(ctx, receiver.Credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	responses, err := conn.command(ctx, "SELECT "+imapQuote(receiver.options.Mailbox), nil)
+	if err != nil {
+		conn.close()
+		return nil, err
+	}
+
+	uidValidity := ""
+	for _, response := range responses {
+		if match := imapUIDValidityPattern.FindStringSubmatch(response.text); match != nil {
+			uidValidity = match[1]
+		}
+	}
+
+	receiver.mutex.Lock()
+	if uidValidity != receiver.uidValidity {
+		receiver.uidValidity = uidValidity
+		receiver.lastUID = 0
+	}
+	receiver.mutex.Unlock()
+
+	return conn, nil
+}
+
+func (receiver *IMAPReceiver) fetch(ctx context.Context, conn *imapConn) ([]*InboundEmail, error) {
+	lastUID := uint32(0)
+	search := "UID SEARCH UNSEEN"
+	if !receiver.options.MarkSeen {
+		receiver.mutex.Lock()
+		lastUID = receiver.lastUID
+		receiver.mutex.Unlock()
+		search = fmt.Sprintf("UID SEARCH UID %d:* UNSEEN", lastUID+1)
+	}
+	responses, err := conn.command(ctx, search, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	uids := []uint32{}
+	for _, response := range responses {
+		fields := strings.Fields(response.text)
+		if len(fields) < 2 || fields[1] != "SEARCH" {
+			continue
+		}
+		for _, field := range fields[2:] {
+			uid, err := strconv.ParseUint(field, 10, 32)
+			// "n:*" matches the highest UID even when it is below n.
+			if err == nil && uint32(uid) > lastUID {
+				uids = append(uids, uint32(uid))
+			}
+		}
+	}
+	if len(uids) == 0 {
+		return nil, nil
+	}
+	slices.Sort(uids)
+
+	set := []string{}
+	for _, uid := range uids {
+		set = append(set, strconv.FormatUint(uint64(uid), 10))
+	}
+	uidSet := strings.Join(set, ",")
+
+	responses, err = conn.command(ctx, "UID FETCH "+uidSet+" (UID BODY.PEEK[])", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	emails := []*InboundEmail{}
+	for _, response := range responses {
+		match := imapUIDPattern.FindStringSubmatch(response.text)
+		if !strings.Contains(response.text, " FETCH ") || match == nil || len(response.literals) == 0 {
+			continue
+		}
+
+		email := parseMailboxEmail(response.literals[len(response.literals)-1])
+		email.ID = match[1]
+		emails = append(emails, email)
+	}
+
+	if receiver.options.MarkSeen {
+		if _, err := conn.command(ctx, "UID STORE "+uidSet+" +FLAGS.SILENT (\\Seen)", nil); err != nil {
+			return nil, err
+		}
+	}
+
+	receiver.mutex.Lock()
+	receiver.lastUID = max(receiver.lastUID, uids[len(uids)-1])
+	receiver.mutex.Unlock()
+
+	return emails, nil
+}
+
+var (
+	imapLiteralPattern     = regexp.MustCompile(`\{(\d+)\}$`)
+	imapUIDPattern         = regexp.MustCompile(`[( ]UID (\d+)`)
+	imapUIDValidityPattern = regexp.MustCompile(`\[UIDVALIDITY (\d+)\]`)
+	imapCapabilityPattern  = regexp.MustCompile(`(?i)\[CAPABILITY ([^\]]*)\]`)
+)
+
+// maxIMAPLiteral bounds the memory a single response can claim.
+const maxIMAPLiteral = 64 << 20
+
+type imapResponse struct {
+	// text is the response without its literal data, which is marked by
+	// "{size}" where it appeared.
+	text     string
+	literals [][]byte
+}
+
+type imapConn struct {
+	conn         net.Conn
+	reader       *bufio.Reader
+	tag          int
+	capabilities map[string]bool
+}
+
+func dialIMAP(ctx context.Context, credentials *IMAPCredentials) (*imapConn, error) {
+	port, err := strconv.Atoi(credentials.Port)
+	if err != nil {
+		return nil, &ValidationError{Field: "port", Message: "Invalid IMAP port " + credentials.Port}
+	}
+
+	mode, tlsConfig, err := mailboxTLS(credentials.TLSMode, port, 993, credentials.Host, credentials.TLSConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	netConn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(credentials.Host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	if mode == TLSModeImplicit {
+		netConn = tls.Client(netConn, tlsConfig)
+	}
+
+	conn := &imapConn{conn: netConn, reader: bufio.NewReader(netConn)}
+	if err := conn.handshake(ctx, credentials, mode, tlsConfig); err != nil {
+		conn.close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func (conn *imapConn) handshake(ctx context.Context, credentials *IMAPCredentials, mode TLSMode, tlsConfig *tls.Config) error {
+	stop := context.AfterFunc(ctx, func() { conn.conn.Close() })
+	defer stop()
+	conn.conn.SetDeadline(time.Now().Add(mailboxCommandTimeout))
+
+	greeting, err := conn.readResponse()
+	if err != nil {
+		return fmt.Errorf("Failed to read IMAP greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting.text, "* OK") && !strings.HasPrefix(greeting.text, "* PREAUTH") {
+		return fmt.Errorf("IMAP server refused connection: %s", greeting.text)
+	}
+
+	if err := conn.refreshCapabilities(ctx); err != nil {
+		return err
+	}
+
+	if mode == TLSModeSTARTTLS {
+		if !conn.capabilities["STARTTLS"] {
+			return fmt.Errorf("IMAP server %s does not support STARTTLS", credentials.Host)
+		}
+		if _, err := conn.command(ctx, "STARTTLS", nil); err != nil {
+			return err
+		}
+
+		conn.conn = tls.Client(conn.conn, tlsConfig)
+		conn.reader = bufio.NewReader(conn.conn)
+		if err := conn.refreshCapabilities(ctx); err != nil {
+			return err
+		}
+	}
+
+	if strings.HasPrefix(greeting.text, "* PREAUTH") {
+		return nil
+	}
+
+	if credentials.TokenSource != nil {
+		token, err := credentials.TokenSource.Token()
+		if err != nil {
+			return fmt.Errorf("Failed to obtain OAuth2 token: %w", err)
+		}
+
+		response := base64.StdEncoding.EncodeToString(
+			[]byte(fmt.Sprintf("user=%s\x01auth=Bearer %s\x01\x01", credentials.User, token.AccessToken)),
+		)
+		// The server answers a failed XOAUTH2 attempt with a challenge
+		// carrying the error, which is acknowledged with an empty line.
+		answered := false
+		_, err = conn.command(ctx, "AUTHENTICATE XOAUTH2", func(challenge string) (string, error) {
+			if answered {
+				return "", nil
+			}
+			answered = true
+			return response, nil
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		if conn.capabilities["LOGINDISABLED"] {
+			return fmt.Errorf("IMAP server %s does not allow login on this connection", credentials.Host)
+		}
+		if _, err := conn.command(ctx, "LOGIN "+imapQuote(credentials.User)+" "+imapQuote(credentials.Password), nil); err != nil {
+			return err
+		}
+	}
+
+	return conn.refreshCapabilities(ctx)
+}
+
+func (conn *imapConn) refreshCapabilities(ctx context.Context) error {
+	responses, err := conn.command(ctx, "CAPABILITY", nil)
+	if err != nil {
+		return err
+	}
+
+	conn.capabilities = map[string]bool{}
+	for _, response := range responses {
+		fields := strings.Fields(response.text)
+		if len(fields) > 1 && strings.EqualFold(fields[1], "CAPABILITY") {
+			for _, capability := range fields[2:] {
+				conn.capabilities[strings.ToUpper(capability)] = true
+			}
+		}
+		if match := imapCapabilityPattern.FindStringSubmatch(response.text); match != nil {
+			for _, capability := range strings.Fields(match[1]) {
+				conn.capabilities[strings.ToUpper(capability)] = true
+			}
+		}
+	}
+
+	return nil
+}
+
+const mailboxCommandTimeout = 2 * time.Minute
+
+func (conn *imapConn) readResponse() (*imapResponse, error) {
+	response := &imapResponse{}
+	text := &strings.Builder{}
+	for {
+		line, err := conn.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		text.WriteString(line)
+
+		match := imapLiteralPattern.FindStringSubmatch(line)
+		if match == nil {
+			break
+		}
+
+		size, err := strconv.Atoi(match[1])
+		if err != nil || size > maxIMAPLiteral {
+			return nil, fmt.Errorf("IMAP literal of %s bytes is too large", match[1])
+		}
+		literal := make([]byte, size)
+		if _, err := io.ReadFull(conn.reader, literal); err != nil {
+			return nil, err
+		}
+		response.literals = append(response.literals, literal)
+	}
+	response.text = text.String()
+
+	return response, nil
+}
+
+// command sends a tagged command and returns the untagged responses that
+// preceded its completion. Continuation requests are answered by
+// continuation.
+func (conn *imapConn) command(
+	ctx context.Context,
+	command string,
+	continuation func(challenge string) (string, error),
+) ([]*imapResponse, error) {
+	stop := context.AfterFunc(ctx, func() { conn.conn.Close() })
+	defer stop()
+	conn.conn.SetDeadline(time.Now().Add(mailboxCommandTimeout))
+
+	conn.tag++
+	tag := fmt.Sprintf("A%03d", conn.tag)
+	if _, err := io.WriteString(conn.conn, tag+" "+command+"\r\n"); err != nil {
+		return nil, mailboxContextError(ctx, err)
+	}
+
+	name, _, _ := strings.Cut(command, " ")
+	untagged := []*imapResponse{}
+	for {
+		response, err := conn.readResponse()
+		if err != nil {
+			return nil, mailboxContextError(ctx, err)
+		}
+
+		switch {
+		case strings.HasPrefix(response.text, "+"):
+			if continuation == nil {
+				return nil, fmt.Errorf("Unexpected IMAP continuation for %s", name)
+			}
+			reply, err := continuation(strings.TrimSpace(strings.TrimPrefix(response.text, "+")))
+			if err != nil {
+				return nil, err
+			}
+			if _, err := io.WriteString(conn.conn, reply+"\r\n"); err != nil {
+				return nil, mailboxContextError(ctx, err)
+			}
+		case strings.HasPrefix(response.text, tag+" "):
+			status, detail, _ := strings.Cut(strings.TrimPrefix(response.text, tag+" "), " ")
+			if status != "OK" {
+				return untagged, fmt.Errorf("IMAP %s failed: %s %s", name, status, detail)
+			}
+			return untagged, nil
+		default:
+			untagged = append(untagged, response)
+		}
+	}
+}
+
+// idle waits until the server reports a new message or timeout passes. The
+// server drops idle clients after 30 minutes, so timeout is capped below
+// that.
+func (conn *imapConn) idle(ctx context.Context, timeout time.Duration) error {
+	timeout = min(max(timeout, time.Minute), 25*time.Minute)
+
+	stop := context.AfterFunc(ctx, func() { conn.conn.Close() })
+	defer stop()
+	conn.conn.SetDeadline(time.Now().Add(mailboxCommandTimeout))
+
+	conn.tag++
+	tag := fmt.Sprintf("A%03d", conn.tag)
+	if _, err := io.WriteString(conn.conn, tag+" IDLE\r\n"); err != nil {
+		return mailboxContextError(ctx, err)
+	}
+
+	response, err := conn.readResponse()
+	if err != nil {
+		return mailboxContextError(ctx, err)
+	}
+	if !strings.HasPrefix(response.text, "+") {
+		return fmt.Errorf("IMAP IDLE failed: %s", response.text)
+	}
+
+	conn.conn.SetReadDeadline(time.Now().Add(timeout))
+	for {
+		response, err := conn.readResponse()
+		var netError net.Error
+		if errors.As(err, &netError) && netError.Timeout() && ctx.Err() == nil {
+			break
+		}
+		if err != nil {
+			return mailboxContextError(ctx, err)
+		}
+		if strings.HasSuffix(response.text, " EXISTS") || strings.HasSuffix(response.text, " RECENT") {
+			break
+		}
+	}
+
+	conn.conn.SetDeadline(time.Now().Add(mailboxCommandTimeout))
+	if _, err := io.WriteString(conn.conn, "DONE\r\n"); err != nil {
+		return mailboxContextError(ctx, err)
+	}
+	for {
+		response, err := conn.readResponse()
+		if err != nil {
+			return mailboxContextError(ctx, err)
+		}
+		if strings.HasPrefix(response.text, tag+" ") {
+			status, detail, _ := strings.Cut(strings.TrimPrefix(response.text, tag+" "), " ")
+			if status != "OK" {
+				return fmt.Errorf("IMAP IDLE failed: %s %s", status, detail)
+			}
+			return nil
+		}
+	}
+}
+
+func (conn *imapConn) logout() {
+	conn.command(context.Background(), "LOGOUT", nil)
+	conn.close()
+}
+
+func (conn *imapConn) close() {
+	conn.conn.Close()
+}
+
+// mailboxTLS resolves the TLS mode of a mailbox connection, which is
+// implicit TLS on implicitPort and STARTTLS otherwise unless set.
+func mailboxTLS(mode TLSMode, port, implicitPort int, host string, config *tls.Config) (TLSMode, *tls.Config, error) {
+	if mode == "" {
+		mode = TLSModeSTARTTLS
+		if port == implicitPort {
+			mode = TLSModeImplicit
+		}
+	}
+	switch mode {
+	case TLSModeNone, TLSModeSTARTTLS, TLSModeImplicit:
+	default:
+		return "", nil, &ValidationError{Field: "tlsMode", Message: fmt.Sprintf("Unknown TLS mode %q", mode)}
+	}
+
+	tlsConfig := &tls.Config{}
+	if config != nil {
+		tlsConfig = config.Clone()
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = host
+	}
+
+	return mode, tlsConfig, nil
+}
+
+// mailboxContextError reports the context error when a read failed because the
+// context closed the connection.
+func mailboxContextError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+// imapQuote formats a quoted string. Line breaks are removed since they
+// cannot appear in a quoted string.
+func imapQuote(value string) string {
+	value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
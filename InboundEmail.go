@@ -0,0 +1,263 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+type InboundAttachment struct {
+	Name        string `json:"name"`
+	ContentType string `json:"contentType"`
+	ContentID   string `json:"contentId,omitempty"`
+	Inline      bool   `json:"inline,omitempty"`
+	Data        []byte `json:"-"`
+}
+
+// InboundEmail is a received message. Addresses are bare, with the display
+// name of the sender kept in FromName. Text and HTML hold the first body of
+// each type; every other part is an attachment. ID identifies the message in
+// the mailbox it was read from. Mailbox receivers return messages that
+// cannot be parsed with only ID, Raw and ParseError set.
+type InboundEmail struct {
+	ID          string              `json:"id"`
+	MessageID   string              `json:"messageId,omitempty"`
+	InReplyTo   string              `json:"inReplyTo,omitempty"`
+	References  []string            `json:"references,omitempty"`
+	From        string              `json:"from"`
+	FromName    string              `json:"fromName,omitempty"`
+	To          []string            `json:"to,omitempty"`
+	Cc          []string            `json:"cc,omitempty"`
+	ReplyTo     []string            `json:"replyTo,omitempty"`
+	Subject     string              `json:"subject"`
+	Date        time.Time           `json:"date,omitzero"`
+	Headers     map[string][]string `json:"headers"`
+	Text        string              `json:"text,omitempty"`
+	HTML        string              `json:"html,omitempty"`
+	Attachments []InboundAttachment `json:"attachments,omitempty"`
+	Raw         []byte              `json:"-"`
+	ParseError  string              `json:"parseError,omitempty"`
+}
+
+// parseMailboxEmail parses a message read from a mailbox, reporting a
+// malformed message in ParseError so that it does not stop the rest of the
+// mailbox from being read.
+func parseMailboxEmail(raw []byte) *InboundEmail {
+	email, err := ParseInboundEmail(raw)
+	if err != nil {
+		return &InboundEmail{Raw: raw, ParseError: err.Error()}
+	}
+
+	return email
+}
+
+var inboundWordDecoder = &mime.WordDecoder{CharsetReader: charset.NewReaderLabel}
+
+// ParseInboundEmail parses a raw RFC 5322 message, decoding encoded header
+// words, transfer encodings and body charsets.
+func ParseInboundEmail(raw []byte) (*InboundEmail, error) {
+	message, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse email: %w", err)
+	}
+
+	email := &InboundEmail{
+		MessageID:  strings.Trim(message.Header.Get("Message-Id"), " <>"),
+		InReplyTo:  strings.Trim(message.Header.Get("In-Reply-To"), " <>"),
+		References: parseMessageIDList(message.Header.Get("References")),
+		Headers:    map[string][]string{},
+		Raw:        raw,
+	}
+	for name, values := range message.Header {
+		for _, value := range values {
+			email.Headers[name] = append(email.Headers[name], decodeInboundHeader(value))
+		}
+	}
+
+	email.Subject = decodeInboundHeader(message.Header.Get("Subject"))
+	if date, err := message.Header.Date(); err == nil {
+		email.Date = date
+	}
+
+	parser := &mail.AddressParser{WordDecoder: inboundWordDecoder}
+	if from, err := parser.ParseList(message.Header.Get("From")); err == nil && len(from) > 0 {
+		email.From = from[0].Address
+		email.FromName = from[0].Name
+	}
+	email.To = parseInboundAddresses(parser, message.Header.Get("To"))
+	email.Cc = parseInboundAddresses(parser, message.Header.Get("Cc"))
+	email.ReplyTo = parseInboundAddresses(parser, message.Header.Get("Reply-To"))
+
+	if err := email.readPart(
+		message.Header.Get("Content-Type"),
+		message.Header.Get("Content-Transfer-Encoding"),
+		message.Header.Get("Content-Disposition"),
+		message.Header.Get("Content-Id"),
+		message.Body,
+	); err != nil {
+		return nil, err
+	}
+
+	return email, nil
+}
+
+func decodeInboundHeader(value string) string {
+	decoded, err := inboundWordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+
+	return decoded
+}
+
+func parseInboundAddresses(parser *mail.AddressParser, value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	parsed, err := parser.ParseList(value)
+	if err != nil {
+		return nil
+	}
+
+	addresses := []string{}
+	for _, address := range parsed {
+		addresses = append(addresses, address.Address)
+	}
+
+	return addresses
+}
+
+func parseMessageIDList(value string) []string {
+	ids := []string{}
+	for _, field := range strings.Fields(value) {
+		if id := strings.Trim(field, "<>,"); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return ids
+}
+
+func (email *InboundEmail) readPart(contentType, transferEncoding, disposition, contentID string, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "" {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("Failed to read email part: %w", err)
+			}
+
+			if err := email.readPart(
+				part.Header.Get("Content-Type"),
+				part.Header.Get("Content-Transfer-Encoding"),
+				part.Header.Get("Content-Disposition"),
+				part.Header.Get("Content-Id"),
+				part,
+			); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch strings.ToLower(strings.TrimSpace(transferEncoding)) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, &base64Cleaner{reader: body})
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+
+	dispositionType, dispositionParams, _ := mime.ParseMediaType(disposition)
+	name := dispositionParams["filename"]
+	if name == "" {
+		name = params["name"]
+	}
+	name = decodeInboundHeader(name)
+
+	isBody := dispositionType != "attachment" && name == "" &&
+		(mediaType == "text/plain" && email.Text == "" || mediaType == "text/html" && email.HTML == "")
+	if isBody {
+		if label := params["charset"]; label != "" && !strings.EqualFold(label, "utf-8") && !strings.EqualFold(label, "us-ascii") {
+			if decoded, err := charset.NewReaderLabel(label, body); err == nil {
+				body = decoded
+			}
+		}
+
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return fmt.Errorf("Failed to read email body: %w", err)
+		}
+		if mediaType == "text/plain" {
+			email.Text = string(data)
+		} else {
+			email.HTML = string(data)
+		}
+
+		return nil
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("Failed to read email attachment: %w", err)
+	}
+	if name == "" {
+		name = "attachment"
+		if extensions, _ := mime.ExtensionsByType(mediaType); len(extensions) > 0 {
+			name += extensions[0]
+		}
+	}
+
+	email.Attachments = append(email.Attachments, InboundAttachment{
+		Name:        name,
+		ContentType: mediaType,
+		ContentID:   strings.Trim(contentID, " <>"),
+		Inline:      dispositionType == "inline",
+		Data:        data,
+	})
+
+	return nil
+}
+
+// base64Cleaner drops the characters outside the base64 alphabet that mail
+// software leaves in bodies, such as trailing spaces, which the decoder
+// would otherwise reject.
+type base64Cleaner struct {
+	reader io.Reader
+}
+
+func (cleaner *base64Cleaner) Read(data []byte) (int, error) {
+	for {
+		read, err := cleaner.reader.Read(data)
+		kept := 0
+		for _, character := range data[:read] {
+			if character >= 'A' && character <= 'Z' || character >= 'a' && character <= 'z' ||
+				character >= '0' && character <= '9' || character == '+' || character == '/' || character == '=' {
+				data[kept] = character
+				kept++
+			}
+		}
+		if kept > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
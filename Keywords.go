@@ -0,0 +1,245 @@
+package messagingutilities
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+type InboundSMS struct {
+	Provider  string `json:"provider"`
+	MessageID string `json:"messageId"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Text      string `json:"text"`
+	// LinkID identifies an Africa's Talking premium message, to be passed
+	// back when replying to it.
+	LinkID     string            `json:"linkId,omitempty"`
+	ReceivedAt time.Time         `json:"receivedAt"`
+	Raw        map[string]string `json:"raw,omitempty"`
+}
+
+func ParseTwilioInboundSMS(form url.Values) InboundSMS {
+	return InboundSMS{
+		Provider:   "twilio",
+		MessageID:  form.Get("MessageSid"),
+		From:       form.Get("From"),
+		To:         form.Get("To"),
+		Text:       form.Get("Body"),
+		ReceivedAt: time.Now(),
+		Raw:        flattenForm(form),
+	}
+}
+
+func ParseAfricasTalkingInboundSMS(form url.Values) InboundSMS {
+	receivedAt, err := time.Parse(time.RFC3339, form.Get("date"))
+	if err != nil {
+		receivedAt = time.Now()
+	}
+
+	return InboundSMS{
+		Provider:   "africastalking",
+		MessageID:  form.Get("id"),
+		From:       form.Get("from"),
+		To:         form.Get("to"),
+		Text:       form.Get("text"),
+		LinkID:     form.Get("linkId"),
+		ReceivedAt: receivedAt,
+		Raw:        flattenForm(form),
+	}
+}
+
+type SMSSender interface {
+	SendSMS(ctx context.Context, receiver, text string) error
+}
+
+type SMSSenderFunc func(ctx context.Context, receiver, text string) error
+
+func (send SMSSenderFunc) SendSMS(ctx context.Context, receiver, text string) error {
+	return send(ctx, receiver, text)
+}
+
+func NewTwilioSMSSender(credentials *TwilioCredentials) SMSSender {
+	return SMSSenderFunc(func(ctx context.Context, receiver, text string) error {
+		return SendTwilioSmsMessageContext(ctx, credentials, &text, &receiver)
+	})
+}
+
+func NewAfricasTalkingSMSSender(credentials *AfricasTalkingCredentials) SMSSender {
+	return SMSSenderFunc(func(ctx context.Context, receiver, text string) error {
+		return SendAfricasTalkingSmsMessageContext(ctx, credentials, &text, &receiver)
+	})
+}
+
+type KeywordAction string
+
+const (
+	KeywordActionStop  KeywordAction = "stop"
+	KeywordActionStart KeywordAction = "start"
+	KeywordActionHelp  KeywordAction = "help"
+)
+
+type KeywordEvent struct {
+	Action     KeywordAction `json:"action"`
+	Keyword    string        `json:"keyword"`
+	Inbound    InboundSMS    `json:"inbound"`
+	Reply      string        `json:"reply,omitempty"`
+	ReplyError string        `json:"replyError,omitempty"`
+	At         time.Time     `json:"at"`
+}
+
+type KeywordReplyData struct {
+	Keyword string
+	From    string
+	To      string
+}
+
+// KeywordProcessor records STOP opt-outs in Suppressions, or in
+// DefaultSuppressionList when it is nil. Senders only consult
+// DefaultSuppressionList, so opt-outs recorded in any other list do not stop
+// sends.
+type KeywordProcessor struct {
+	Keywords     map[KeywordAction][]string
+	Replies      map[KeywordAction]string
+	Channel      Channel
+	Suppressions SuppressionList
+	Sender       SMSSender
+	OnEvent      func(event KeywordEvent)
+}
+
+// NewKeywordProcessor returns a processor with the common English and French
+// keywords. Pass DefaultSuppressionList, or nil, as suppressions for STOP to
+// stop further sends to the number.
+func NewKeywordProcessor(suppressions SuppressionList, sender SMSSender) *KeywordProcessor {
+	return &KeywordProcessor{
+		Keywords: map[KeywordAction][]string{
+			KeywordActionStop: {
+				"STOP", "STOPALL", "UNSUBSCRIBE", "CANCEL", "END", "QUIT", "ARRET", "ARRÊT",
+			},
+			KeywordActionStart: {"START", "YES", "UNSTOP"},
+			KeywordActionHelp:  {"HELP", "INFO", "AIDE"},
+		},
+		Replies: map[KeywordAction]string{
+			KeywordActionStop:  "You have been unsubscribed and will receive no further messages. Reply START to resubscribe.",
+			KeywordActionStart: "You have been resubscribed. Reply STOP to unsubscribe.",
+			KeywordActionHelp:  "Reply STOP to unsubscribe or START to resubscribe.",
+		},
+		Channel:      ChannelSMS,
+		Suppressions: suppressions,
+		Sender:       sender,
+	}
+}
+
+func normalizeKeyword(text string) string {
+	return strings.ToUpper(strings.TrimFunc(text, func(character rune) bool {
+		return unicode.IsSpace(character) || unicode.IsPunct(character)
+	}))
+}
+
+func (processor *KeywordProcessor) Match(text string) (KeywordAction, string, bool) {
+	normalized := normalizeKeyword(text)
+	if normalized == "" {
+		return "", "", false
+	}
+
+	for _, action := range []KeywordAction{KeywordActionStop, KeywordActionStart, KeywordActionHelp} {
+		for _, keyword := range processor.Keywords[action] {
+			if normalizeKeyword(keyword) == normalized {
+				return action, keyword, true
+			}
+		}
+	}
+
+	return "", "", false
+}
+
+func (processor *KeywordProcessor) Process(ctx context.Context, inbound InboundSMS) (*KeywordEvent, error) {
+	action, keyword, ok := processor.Match(inbound.Text)
+	if !ok {
+		return nil, nil
+	}
+
+	event := &KeywordEvent{
+		Action:  action,
+		Keyword: keyword,
+		Inbound: inbound,
+		At:      time.Now(),
+	}
+
+	suppressions := processor.Suppressions
+	if suppressions == nil {
+		suppressions = DefaultSuppressionList
+	}
+	if suppressions == nil && action != KeywordActionHelp {
+		return nil, &ValidationError{Field: "suppressions", Message: "No suppression list is configured"}
+	}
+
+	// The STOP confirmation goes out before the sender is suppressed, as
+	// the reply would otherwise be dropped as a send to a suppressed
+	// receiver.
+	switch action {
+	case KeywordActionStop:
+		processor.reply(ctx, event)
+		err := suppressions.Suppress(ctx, Suppression{
+			Channel: processor.Channel,
+			Address: inbound.From,
+			Reason:  SuppressionReasonUnsubscribe,
+			Detail:  "keyword:" + keyword,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to suppress sender: %w", err)
+		}
+	case KeywordActionStart:
+		if err := suppressions.Unsuppress(ctx, processor.Channel, inbound.From); err != nil {
+			return nil, fmt.Errorf("Failed to unsuppress sender: %w", err)
+		}
+	}
+
+	if action != KeywordActionStop {
+		processor.reply(ctx, event)
+	}
+
+	if processor.OnEvent != nil {
+		processor.OnEvent(*event)
+	}
+
+	return event, nil
+}
+
+func (processor *KeywordProcessor) reply(ctx context.Context, event *KeywordEvent) {
+	reply := processor.Replies[event.Action]
+	if reply == "" || processor.Sender == nil {
+		return
+	}
+
+	text, err := renderKeywordReply(reply, KeywordReplyData{
+		Keyword: event.Keyword,
+		From:    event.Inbound.From,
+		To:      event.Inbound.To,
+	})
+	if err == nil {
+		event.Reply = text
+		err = processor.Sender.SendSMS(ctx, event.Inbound.From, text)
+	}
+	if err != nil {
+		event.ReplyError = err.Error()
+	}
+}
+
+func renderKeywordReply(reply string, data KeywordReplyData) (string, error) {
+	parsed, err := template.New("reply").Parse(reply)
+	if err != nil {
+		return "", fmt.Errorf("Invalid keyword reply template: %w", err)
+	}
+
+	builder := strings.Builder{}
+	if err := parsed.Execute(&builder, data); err != nil {
+		return "", fmt.Errorf("Failed to render keyword reply: %w", err)
+	}
+
+	return builder.String(), nil
+}
@@ -0,0 +1,124 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+)
+
+// LineCredentials authenticates a LINE Messaging API channel. To is the user,
+// group or room id messages are pushed to when they have no receivers.
+type LineCredentials struct {
+	ChannelAccessToken string
+	To                 string
+}
+
+// LineSender pushes Text as a text message. Flex message contents passed
+// with Payload("flex", ...) are sent instead, with Text, or Subject, as the
+// alternative text shown in notifications.
+type LineSender struct {
+	Credentials *LineCredentials
+	BaseURL     string
+}
+
+func NewLineSender(credentials *LineCredentials) *LineSender {
+	return &LineSender{
+		Credentials: credentials,
+		BaseURL:     "https://api.line.me/v2/bot",
+	}
+}
+
+type lineMessage struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	AltText  string `json:"altText,omitempty"`
+	Contents any    `json:"contents,omitempty"`
+}
+
+type lineRequest struct {
+	To       string        `json:"to"`
+	Messages []lineMessage `json:"messages"`
+}
+
+type lineResponse struct {
+	SentMessages []struct {
+		ID string `json:"id"`
+	} `json:"sentMessages"`
+}
+
+func (sender *LineSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	if sender.Credentials.ChannelAccessToken == "" {
+		return nil, &ValidationError{Field: "channelAccessToken", Message: "Channel access token cannot be empty"}
+	}
+
+	message = withDefaultReceiver(message, sender.Credentials.To)
+
+	return sendEachMessage(ctx, ChannelChat, "line", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sender.send(ctx, message, text, receiver)
+	})
+}
+
+func (sender *LineSender) send(ctx context.Context, message *Message, text, receiver string) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("line", "chat", err) }()
+
+	if text, err = chatText(message, text); err != nil {
+		return result, err
+	}
+
+	payload := maps.Clone(message.Payload)
+	flex, hasFlex := payload["flex"]
+	delete(payload, "flex")
+
+	messages := []lineMessage{}
+	if hasFlex {
+		altText := text
+		if altText == "" {
+			altText = message.Subject
+		}
+		if altText == "" {
+			return result, &ValidationError{Field: "text", Message: "Flex messages need a text or subject as their alternative text"}
+		}
+		messages = append(messages, lineMessage{Type: "flex", AltText: altText, Contents: flex})
+	} else if text != "" {
+		messages = append(messages, lineMessage{Type: "text", Text: text})
+	}
+
+	body, err := encodeChatPayload(lineRequest{To: receiver, Messages: messages}, payload)
+	if err != nil {
+		return result, fmt.Errorf("Failed to encode LINE request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		strings.TrimSuffix(sender.BaseURL, "/")+"/message/push",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return result, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer "+sender.Credentials.ChannelAccessToken)
+
+	_, responseBody, err := doProviderRequest("line", "LINE", request)
+	result.Raw = string(responseBody)
+	if err != nil {
+		return result, err
+	}
+
+	var lineResp lineResponse
+	if err := json.Unmarshal(responseBody, &lineResp); err != nil {
+		return result, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	if len(lineResp.SentMessages) > 0 {
+		result.MessageID = lineResp.SentMessages[0].ID
+	}
+
+	return result, nil
+}
@@ -0,0 +1,190 @@
+package messagingutilities
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+type ClockTime struct {
+	Hour   int
+	Minute int
+	Second int
+}
+
+func (clock ClockTime) validate() error {
+	if clock.Hour < 0 || clock.Hour > 23 || clock.Minute < 0 || clock.Minute > 59 || clock.Second < 0 || clock.Second > 59 {
+		return &ValidationError{
+			Field:   "clock",
+			Message: fmt.Sprintf("Invalid time of day %02d:%02d:%02d", clock.Hour, clock.Minute, clock.Second),
+		}
+	}
+
+	return nil
+}
+
+type DSTAdjustment string
+
+const (
+	DSTAdjustmentNone DSTAdjustment = ""
+	// The requested wall clock time falls in a spring-forward gap. The send
+	// time is moved forward by the length of the gap, so 02:30 becomes 03:30.
+	DSTAdjustmentNonexistent DSTAdjustment = "nonexistent"
+	// The requested wall clock time occurs twice because of a fall-back
+	// transition. The earlier of the two instants is used.
+	DSTAdjustmentAmbiguous DSTAdjustment = "ambiguous"
+)
+
+type LocalTimeScheduleOptions struct {
+	DefaultLocation *time.Location
+}
+
+type ScheduledRecipient struct {
+	Recipient           string
+	Location            *time.Location
+	SendAt              time.Time
+	UsedDefaultLocation bool
+	DSTAdjustment       DSTAdjustment
+}
+
+type ScheduledBatch struct {
+	SendAt     time.Time
+	Recipients []ScheduledRecipient
+}
+
+type LocalTimeSchedule struct {
+	Batches              []ScheduledBatch
+	UnresolvedRecipients []string
+}
+
+func ScheduleAtLocalTime(
+	clock ClockTime,
+	date time.Time,
+	recipients []string,
+	resolver func(recipient string) *time.Location,
+	options LocalTimeScheduleOptions,
+) (*LocalTimeSchedule, error) {
+	if err := clock.validate(); err != nil {
+		return nil, err
+	}
+
+	defaultLocation := options.DefaultLocation
+	if defaultLocation == nil {
+		defaultLocation = time.UTC
+	}
+
+	schedule := &LocalTimeSchedule{}
+	batches := map[int64]*ScheduledBatch{}
+
+	for _, recipient := range recipients {
+		var location *time.Location
+		if resolver != nil {
+			location = resolver(recipient)
+		}
+
+		scheduled := ScheduledRecipient{Recipient: recipient, Location: location}
+		if location == nil {
+			scheduled.Location = defaultLocation
+			scheduled.UsedDefaultLocation = true
+			schedule.UnresolvedRecipients = append(schedule.UnresolvedRecipients, recipient)
+		}
+
+		scheduled.SendAt, scheduled.DSTAdjustment = resolveLocalTime(clock, date, scheduled.Location)
+
+		key := scheduled.SendAt.Unix()
+		batch, ok := batches[key]
+		if !ok {
+			batch = &ScheduledBatch{SendAt: scheduled.SendAt}
+			batches[key] = batch
+		}
+		batch.Recipients = append(batch.Recipients, scheduled)
+	}
+
+	for _, batch := range batches {
+		schedule.Batches = append(schedule.Batches, *batch)
+	}
+	sort.Slice(schedule.Batches, func(i, j int) bool {
+		return schedule.Batches[i].SendAt.Before(schedule.Batches[j].SendAt)
+	})
+
+	return schedule, nil
+}
+
+// Dispatch waits for the send time of each batch and then enqueues message
+// on dispatcher as a bulk job per recipient, addressed to that recipient
+// alone; the To, Cc and Bcc of message are ignored. Batches whose time has
+// passed are enqueued at once. It returns when the last batch is enqueued,
+// or with the error of ctx or of the first enqueue that fails, leaving the
+// remaining recipients unsent.
+func (schedule *LocalTimeSchedule) Dispatch(
+	ctx context.Context,
+	dispatcher *Dispatcher,
+	sender Sender,
+	message *Message,
+) error {
+	for _, batch := range schedule.Batches {
+		if wait := time.Until(batch.SendAt); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			}
+		}
+
+		for _, scheduled := range batch.Recipients {
+			copied := *message
+			copied.To = []string{scheduled.Recipient}
+			copied.Cc, copied.Bcc = nil, nil
+
+			err := dispatcher.EnqueueContext(ctx, DispatchJob{
+				Receiver: scheduled.Recipient,
+				Priority: DispatchPriorityBulk,
+				Send: func(ctx context.Context) error {
+					_, err := sender.Send(ctx, &copied)
+					return err
+				},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func resolveLocalTime(clock ClockTime, date time.Time, location *time.Location) (time.Time, DSTAdjustment) {
+	year, month, day := date.Date()
+	wallClock := time.Date(year, month, day, clock.Hour, clock.Minute, clock.Second, 0, time.UTC)
+
+	_, offsetBefore := wallClock.Add(-24 * time.Hour).In(location).Zone()
+	_, offsetAfter := wallClock.Add(24 * time.Hour).In(location).Zone()
+
+	matches := func(candidate time.Time) bool {
+		local := candidate.In(location)
+		localYear, localMonth, localDay := local.Date()
+		return localYear == year && localMonth == month && localDay == day &&
+			local.Hour() == clock.Hour && local.Minute() == clock.Minute && local.Second() == clock.Second
+	}
+
+	earlier := wallClock.Add(-time.Duration(offsetBefore) * time.Second)
+	later := wallClock.Add(-time.Duration(offsetAfter) * time.Second)
+	if later.Before(earlier) {
+		earlier, later = later, earlier
+	}
+
+	earlierMatches, laterMatches := matches(earlier), matches(later)
+	switch {
+	case earlierMatches && laterMatches && !earlier.Equal(later):
+		return earlier.In(location), DSTAdjustmentAmbiguous
+	case earlierMatches:
+		return earlier.In(location), DSTAdjustmentNone
+	case laterMatches:
+		return later.In(location), DSTAdjustmentNone
+	default:
+		return wallClock.Add(-time.Duration(offsetBefore) * time.Second).In(location), DSTAdjustmentNonexistent
+	}
+}
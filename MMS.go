@@ -0,0 +1,63 @@
+package messagingutilities
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Twilio accepts up to ten media files per message, totalling at most 5 MB.
+const (
+	twilioMaxMedia      = 10
+	twilioMaxMediaBytes = 5 << 20
+)
+
+// mediaProviders are the senders that accept Message.MediaURLs.
+var mediaProviders = map[string]bool{
+	"twilio":   true,
+	"whatsapp": true,
+	"telegram": true,
+	"viber":    true,
+}
+
+// validateMediaURLs checks the number of media files and, where the server
+// reports it, their combined size. Sizes are read with HEAD requests, and
+// files whose size is not reported are not counted.
+func validateMediaURLs(ctx context.Context, mediaURLs []string, maxCount int, maxBytes int64) error {
+	if len(mediaURLs) > maxCount {
+		return &ValidationError{Field: "mediaUrls", Message: fmt.Sprintf("At most %d media files can be sent", maxCount)}
+	}
+
+	client := newProviderHTTPClient("media")
+	total := int64(0)
+	for _, mediaURL := range mediaURLs {
+		parsed, err := url.Parse(mediaURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return &ValidationError{Field: "mediaUrls", Message: "Invalid media URL " + mediaURL}
+		}
+
+		request, err := http.NewRequestWithContext(ctx, http.MethodHead, mediaURL, nil)
+		if err != nil {
+			return fmt.Errorf("Failed to create http request: %w", err)
+		}
+		response, err := client.Do(request)
+		if err != nil {
+			return fmt.Errorf("Failed to check media %s: %w", mediaURL, err)
+		}
+		response.Body.Close()
+
+		if response.StatusCode >= 400 && response.StatusCode != http.StatusMethodNotAllowed {
+			return &ValidationError{Field: "mediaUrls", Message: fmt.Sprintf("Media %s returned status %d", mediaURL, response.StatusCode)}
+		}
+		if response.ContentLength > 0 {
+			total += response.ContentLength
+		}
+	}
+
+	if total > maxBytes {
+		return &ValidationError{Field: "mediaUrls", Message: fmt.Sprintf("Media files cannot exceed %d bytes in total", maxBytes)}
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package messagingutilities
+
+import "context"
+
+// EmailRenderer renders a named email template. TemplateRegistry and
+// TemplateStore implement it.
+type EmailRenderer interface {
+	RenderEmail(name string, data any, receivers ...string) (*Message, error)
+}
+
+type MergeRecipient struct {
+	Address string
+	Data    any
+}
+
+// SendMailMerge renders the template separately for every recipient with
+// its own data and sends each message individually, at most concurrency at
+// a time. Rendering and sending failures are reported per recipient in the
+// result, in the order of recipients.
+func SendMailMerge(
+	ctx context.Context,
+	sender Sender,
+	renderer EmailRenderer,
+	name string,
+	recipients []MergeRecipient,
+	concurrency int,
+) *BatchResult {
+	receivers := make([]string, len(recipients))
+	for index, recipient := range recipients {
+		receivers[index] = recipient.Address
+	}
+
+	return sendIndexedBatch(ctx, receivers, concurrency, func(ctx context.Context, index int) error {
+		message, err := renderer.RenderEmail(name, recipients[index].Data, recipients[index].Address)
+		if err != nil {
+			return err
+		}
+
+		_, err = sender.Send(ctx, message)
+		return err
+	})
+}
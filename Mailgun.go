@@ -0,0 +1,164 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type MailgunRegion string
+
+const (
+	MailgunRegionUS MailgunRegion = "us"
+	MailgunRegionEU MailgunRegion = "eu"
+)
+
+type MailgunCredentials struct {
+	Domain string
+	ApiKey string
+	Sender string
+	Region MailgunRegion
+}
+
+type MailgunSender struct {
+	Credentials *MailgunCredentials
+	BaseURL     string
+}
+
+func NewMailgunSender(credentials *MailgunCredentials) *MailgunSender {
+	baseURL := "https://api.mailgun.net"
+	if credentials.Region == MailgunRegionEU {
+		baseURL = "https://api.eu.mailgun.net"
+	}
+
+	return &MailgunSender{
+		Credentials: credentials,
+		BaseURL:     baseURL,
+	}
+}
+
+type mailgunResponse struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
+func (sender *MailgunSender) Send(ctx context.Context, message *Message) (result *SendResult, err error) {
+	defer func() { DefaultStats.RecordResult("mailgun", "email", err) }()
+
+	message, err = suppressRecipients(ctx, ChannelEmail, message)
+	if err != nil {
+		return nil, err
+	}
+
+	email, err := prepareEmail(sender.Credentials.Sender, message)
+	if err != nil {
+		return nil, err
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	fields := [][2]string{{"from", email.From}, {"subject", email.Subject}}
+	for _, receiver := range email.To {
+		fields = append(fields, [2]string{"to", receiver})
+	}
+	for _, receiver := range email.Cc {
+		fields = append(fields, [2]string{"cc", receiver})
+	}
+	for _, receiver := range email.Bcc {
+		fields = append(fields, [2]string{"bcc", receiver})
+	}
+	if email.ReplyTo != "" {
+		fields = append(fields, [2]string{"h:Reply-To", email.ReplyTo})
+	}
+	for name, values := range email.Headers {
+		for _, value := range values {
+			fields = append(fields, [2]string{"h:" + name, value})
+		}
+	}
+	if email.Text != "" {
+		fields = append(fields, [2]string{"text", email.Text})
+	}
+	if email.HTML != "" {
+		fields = append(fields, [2]string{"html", email.HTML})
+	}
+	if email.AMP != "" {
+		fields = append(fields, [2]string{"amp-html", email.AMP})
+	}
+	if email.Category != "" {
+		fields = append(fields, [2]string{"o:tag", string(email.Category)})
+	}
+	for _, tag := range email.Tags {
+		fields = append(fields, [2]string{"o:tag", tag})
+	}
+	for key, value := range email.Metadata {
+		fields = append(fields, [2]string{"v:" + key, value})
+	}
+	if email.SendAt != nil {
+		fields = append(fields, [2]string{"o:deliverytime", email.SendAt.UTC().Format(time.RFC1123Z)})
+	}
+
+	for _, field := range fields {
+		if err := writer.WriteField(field[0], field[1]); err != nil {
+			return nil, fmt.Errorf("Failed to encode Mailgun request: %w", err)
+		}
+	}
+
+	attachments, err := email.readAttachments()
+	if err != nil {
+		return nil, err
+	}
+	// Mailgun derives the Content-ID of inline files from their file name.
+	for _, attachment := range attachments {
+		field, name := "attachment", attachment.Name
+		if attachment.Inline {
+			field, name = "inline", attachment.ContentID
+		}
+		part, err := writer.CreateFormFile(field, name)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to encode Mailgun request: %w", err)
+		}
+		if _, err := part.Write(attachment.Data); err != nil {
+			return nil, fmt.Errorf("Failed to encode Mailgun request: %w", err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("Failed to encode Mailgun request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		fmt.Sprintf("%s/v3/%s/messages", sender.BaseURL, url.PathEscape(sender.Credentials.Domain)),
+		body,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	request.SetBasicAuth("api", sender.Credentials.ApiKey)
+
+	_, responseBody, err := doProviderRequest("mailgun", "Mailgun", request)
+	if err != nil {
+		return nil, err
+	}
+
+	var mailgunResp mailgunResponse
+	if err := json.Unmarshal(responseBody, &mailgunResp); err != nil {
+		return nil, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	return &SendResult{
+		Provider:   "mailgun",
+		MessageID:  mailgunResp.ID,
+		Recipients: email.recipients(),
+		Raw:        string(responseBody),
+	}, nil
+}
@@ -0,0 +1,223 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type MailjetCredentials struct {
+	ApiKey    string
+	ApiSecret string
+	Sender    string
+}
+
+// MailjetSender renders TemplateID with TemplateVariables when a template is
+// set; the message body is then optional.
+type MailjetSender struct {
+	Credentials       *MailjetCredentials
+	BaseURL           string
+	TemplateID        int64
+	TemplateVariables map[string]any
+}
+
+func NewMailjetSender(credentials *MailjetCredentials) *MailjetSender {
+	return &MailjetSender{
+		Credentials: credentials,
+		BaseURL:     "https://api.mailjet.com",
+	}
+}
+
+type mailjetAddress struct {
+	Email string `json:"Email"`
+	Name  string `json:"Name,omitempty"`
+}
+
+type mailjetAttachment struct {
+	ContentType   string `json:"ContentType"`
+	Filename      string `json:"Filename"`
+	ContentID     string `json:"ContentID,omitempty"`
+	Base64Content string `json:"Base64Content"`
+}
+
+type mailjetMessage struct {
+	From               mailjetAddress      `json:"From"`
+	To                 []mailjetAddress    `json:"To"`
+	Cc                 []mailjetAddress    `json:"Cc,omitempty"`
+	Bcc                []mailjetAddress    `json:"Bcc,omitempty"`
+	ReplyTo            *mailjetAddress     `json:"ReplyTo,omitempty"`
+	Subject            string              `json:"Subject,omitempty"`
+	TextPart           string              `json:"TextPart,omitempty"`
+	HTMLPart           string              `json:"HTMLPart,omitempty"`
+	TemplateID         int64               `json:"TemplateID,omitempty"`
+	TemplateLanguage   bool                `json:"TemplateLanguage,omitempty"`
+	Variables          map[string]any      `json:"Variables,omitempty"`
+	CustomCampaign     string              `json:"CustomCampaign,omitempty"`
+	EventPayload       string              `json:"EventPayload,omitempty"`
+	Attachments        []mailjetAttachment `json:"Attachments,omitempty"`
+	InlinedAttachments []mailjetAttachment `json:"InlinedAttachments,omitempty"`
+	Headers            map[string]string   `json:"Headers,omitempty"`
+}
+
+type mailjetRequest struct {
+	Messages []mailjetMessage `json:"Messages"`
+}
+
+type mailjetResponse struct {
+	Messages []struct {
+		Status string `json:"Status"`
+		To     []struct {
+			Email       string `json:"Email"`
+			MessageUUID string `json:"MessageUUID"`
+			MessageID   int64  `json:"MessageID"`
+		} `json:"To"`
+		Errors []struct {
+			ErrorMessage string `json:"ErrorMessage"`
+		} `json:"Errors"`
+	} `json:"Messages"`
+}
+
+func mailjetAddresses(values []string) []mailjetAddress {
+	addresses := []mailjetAddress{}
+	for _, value := range values {
+		name, address := splitEmailAddress(value)
+		addresses = append(addresses, mailjetAddress{Email: address, Name: name})
+	}
+
+	return addresses
+}
+
+func (sender *MailjetSender) Send(ctx context.Context, message *Message) (result *SendResult, err error) {
+	defer func() { DefaultStats.RecordResult("mailjet", "email", err) }()
+
+	message, err = suppressRecipients(ctx, ChannelEmail, message)
+	if err != nil {
+		return nil, err
+	}
+
+	email, err := prepareEmail(sender.Credentials.Sender, message)
+	if err != nil {
+		return nil, err
+	}
+
+	mailjetMsg := mailjetMessage{
+		From:    mailjetAddress{Email: email.From},
+		To:      mailjetAddresses(email.To),
+		Cc:      mailjetAddresses(email.Cc),
+		Bcc:     mailjetAddresses(email.Bcc),
+		Subject: email.Subject,
+		Headers: email.flatHeaders(),
+	}
+	if email.ReplyTo != "" {
+		name, address := splitEmailAddress(email.ReplyTo)
+		mailjetMsg.ReplyTo = &mailjetAddress{Email: address, Name: name}
+	}
+
+	if sender.TemplateID != 0 {
+		mailjetMsg.TemplateID = sender.TemplateID
+		mailjetMsg.TemplateLanguage = true
+		mailjetMsg.Variables = sender.TemplateVariables
+	}
+
+	if email.AMP != "" {
+		return nil, &ValidationError{Field: "amp", Message: "Mailjet does not support AMP parts"}
+	}
+	mailjetMsg.TextPart = email.Text
+	mailjetMsg.HTMLPart = email.HTML
+
+	if email.Category != "" {
+		mailjetMsg.CustomCampaign = string(email.Category)
+	} else if len(email.Tags) > 0 {
+		mailjetMsg.CustomCampaign = email.Tags[0]
+	}
+
+	if len(email.Metadata) > 0 {
+		eventPayload, err := json.Marshal(email.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to encode Mailjet request: %w", err)
+		}
+		mailjetMsg.EventPayload = string(eventPayload)
+	}
+
+	attachments, err := email.readAttachments()
+	if err != nil {
+		return nil, err
+	}
+	for _, attachment := range attachments {
+		mailjetAttach := mailjetAttachment{
+			ContentType:   attachment.ContentType,
+			Filename:      attachment.Name,
+			Base64Content: base64.StdEncoding.EncodeToString(attachment.Data),
+		}
+		if attachment.Inline {
+			mailjetAttach.ContentID = attachment.ContentID
+			mailjetMsg.InlinedAttachments = append(mailjetMsg.InlinedAttachments, mailjetAttach)
+			continue
+		}
+		mailjetMsg.Attachments = append(mailjetMsg.Attachments, mailjetAttach)
+	}
+
+	body, err := json.Marshal(mailjetRequest{Messages: []mailjetMessage{mailjetMsg}})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode Mailjet request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		strings.TrimSuffix(sender.BaseURL, "/")+"/v3.1/send",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.SetBasicAuth(sender.Credentials.ApiKey, sender.Credentials.ApiSecret)
+
+	_, responseBody, err := doProviderRequest("mailjet", "Mailjet", request)
+	if err != nil {
+		return nil, err
+	}
+
+	var mailjetResp mailjetResponse
+	if err := json.Unmarshal(responseBody, &mailjetResp); err != nil {
+		return nil, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	if len(mailjetResp.Messages) != 1 {
+		return nil, fmt.Errorf("Mailjet returned %d message results", len(mailjetResp.Messages))
+	}
+
+	sent := mailjetResp.Messages[0]
+	if sent.Status != "success" {
+		messages := []string{}
+		for _, sendError := range sent.Errors {
+			messages = append(messages, sendError.ErrorMessage)
+		}
+		return nil, fmt.Errorf("Mailjet rejected the message: %s", strings.Join(messages, "; "))
+	}
+
+	result = &SendResult{
+		Provider:   "mailjet",
+		Recipients: email.recipients(),
+		Raw:        string(responseBody),
+	}
+	for _, recipient := range sent.To {
+		result.PerRecipient = append(result.PerRecipient, RecipientResult{
+			Recipient: recipient.Email,
+			MessageID: strconv.FormatInt(recipient.MessageID, 10),
+			Status:    sent.Status,
+		})
+	}
+	if len(result.PerRecipient) == 1 {
+		result.MessageID = result.PerRecipient[0].MessageID
+	}
+
+	return result, nil
+}
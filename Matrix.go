@@ -0,0 +1,108 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// MatrixCredentials authenticates a user or bot on HomeserverURL, such as
+// "https://matrix.example.org". RoomID is the room messages are sent to when
+// they have no receivers, such as "!abcdef:example.org".
+type MatrixCredentials struct {
+	HomeserverURL string
+	AccessToken   string
+	RoomID        string
+}
+
+// MatrixSender posts messages as m.text events. HTML bodies are sent as the
+// formatted body, with their text as the plain body, and notices are sent
+// with Payload("msgtype", "m.notice").
+type MatrixSender struct {
+	Credentials *MatrixCredentials
+}
+
+func NewMatrixSender(credentials *MatrixCredentials) *MatrixSender {
+	return &MatrixSender{Credentials: credentials}
+}
+
+type matrixRequest struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format,omitempty"`
+	FormattedBody string `json:"formatted_body,omitempty"`
+}
+
+type matrixResponse struct {
+	EventID string `json:"event_id"`
+}
+
+func (sender *MatrixSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	if sender.Credentials.HomeserverURL == "" {
+		return nil, &ValidationError{Field: "homeserverUrl", Message: "Matrix homeserver URL cannot be empty"}
+	}
+	if sender.Credentials.AccessToken == "" {
+		return nil, &ValidationError{Field: "accessToken", Message: "Access token cannot be empty"}
+	}
+
+	message = withDefaultReceiver(message, sender.Credentials.RoomID)
+
+	return sendEachMessage(ctx, ChannelChat, "matrix", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sender.send(ctx, message, text, receiver)
+	})
+}
+
+func (sender *MatrixSender) send(ctx context.Context, message *Message, text, receiver string) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("matrix", "chat", err) }()
+
+	if text, err = chatText(message, text); err != nil {
+		return result, err
+	}
+
+	payload := matrixRequest{MsgType: "m.text", Body: text}
+	if message.HTML != "" {
+		payload.Format = "org.matrix.custom.html"
+		payload.FormattedBody = message.HTML
+	}
+
+	body, err := encodeChatPayload(payload, message.Payload)
+	if err != nil {
+		return result, fmt.Errorf("Failed to encode Matrix request: %w", err)
+	}
+
+	// The transaction id makes retries of the same request idempotent.
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"PUT",
+		strings.TrimSuffix(sender.Credentials.HomeserverURL, "/")+
+			"/_matrix/client/v3/rooms/"+url.PathEscape(receiver)+
+			"/send/m.room.message/"+rand.Text(),
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return result, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer "+sender.Credentials.AccessToken)
+
+	_, responseBody, err := doProviderRequest("matrix", "Matrix", request)
+	result.Raw = string(responseBody)
+	if err != nil {
+		return result, err
+	}
+
+	var matrixResp matrixResponse
+	if err := json.Unmarshal(responseBody, &matrixResp); err != nil {
+		return result, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	result.MessageID = matrixResp.EventID
+
+	return result, nil
+}
@@ -0,0 +1,116 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// MattermostCredentials either holds the URL of an incoming webhook, which
+// posts to the channel it was created for, or the URL of the server and a
+// bot access token. Bot messages go to the channel ids in To, or to
+// ChannelID when a message has none.
+type MattermostCredentials struct {
+	WebhookURL string
+	ServerURL  string
+	BotToken   string
+	ChannelID  string
+}
+
+// MattermostSender posts Text as Mattermost markdown. Message attachments
+// are passed through with Payload("attachments", ...) for webhooks and
+// Payload("props", ...) for bots.
+type MattermostSender struct {
+	Credentials *MattermostCredentials
+}
+
+func NewMattermostSender(credentials *MattermostCredentials) *MattermostSender {
+	return &MattermostSender{Credentials: credentials}
+}
+
+type mattermostWebhookRequest struct {
+	Text string `json:"text,omitempty"`
+}
+
+type mattermostPostRequest struct {
+	ChannelID string `json:"channel_id"`
+	Message   string `json:"message,omitempty"`
+}
+
+type mattermostResponse struct {
+	ID string `json:"id"`
+}
+
+func (sender *MattermostSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	switch {
+	case sender.Credentials.BotToken != "":
+		if sender.Credentials.ServerURL == "" {
+			return nil, &ValidationError{Field: "serverUrl", Message: "Mattermost server URL cannot be empty"}
+		}
+		message = withDefaultReceiver(message, sender.Credentials.ChannelID)
+	case sender.Credentials.WebhookURL != "":
+		if message != nil && len(message.To) > 0 {
+			return nil, &ValidationError{Field: "to", Message: "Mattermost webhooks post to a fixed channel"}
+		}
+		message = withDefaultReceiver(message, "webhook")
+	default:
+		return nil, &ValidationError{Field: "credentials", Message: "A Mattermost webhook URL or bot token is required"}
+	}
+
+	return sendEachMessage(ctx, ChannelChat, "mattermost", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sender.send(ctx, message, text, receiver)
+	})
+}
+
+func (sender *MattermostSender) send(ctx context.Context, message *Message, text, receiver string) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("mattermost", "chat", err) }()
+
+	if text, err = chatText(message, text); err != nil {
+		return result, err
+	}
+
+	var payload any = mattermostWebhookRequest{Text: text}
+	requestURL := sender.Credentials.WebhookURL
+	if sender.Credentials.BotToken != "" {
+		payload = mattermostPostRequest{ChannelID: receiver, Message: text}
+		requestURL = strings.TrimSuffix(sender.Credentials.ServerURL, "/") + "/api/v4/posts"
+	}
+
+	body, err := encodeChatPayload(payload, message.Payload)
+	if err != nil {
+		return result, fmt.Errorf("Failed to encode Mattermost request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "POST", requestURL, bytes.NewReader(body))
+	if err != nil {
+		return result, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	if sender.Credentials.BotToken != "" {
+		request.Header.Set("Authorization", "Bearer "+sender.Credentials.BotToken)
+	}
+
+	_, responseBody, err := doProviderRequest("mattermost", "Mattermost", request)
+	result.Raw = string(responseBody)
+	if err != nil {
+		return result, err
+	}
+
+	// Webhooks answer with a plain "ok".
+	if sender.Credentials.BotToken == "" {
+		return result, nil
+	}
+
+	var mattermostResp mattermostResponse
+	if err := json.Unmarshal(responseBody, &mattermostResp); err != nil {
+		return result, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	result.MessageID = mattermostResp.ID
+
+	return result, nil
+}
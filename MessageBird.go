@@ -0,0 +1,130 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// MessageBirdCredentials sends from Originator, which is a phone number or
+// an alphanumeric sender ID of up to 11 characters.
+type MessageBirdCredentials struct {
+	AccessKey  string
+	Originator string
+}
+
+type MessageBirdSender struct {
+	Credentials *MessageBirdCredentials
+	BaseURL     string
+}
+
+func NewMessageBirdSender(credentials *MessageBirdCredentials) *MessageBirdSender {
+	return &MessageBirdSender{
+		Credentials: credentials,
+		BaseURL:     "https://rest.messagebird.com",
+	}
+}
+
+type messageBirdRequest struct {
+	Originator string   `json:"originator"`
+	Recipients []string `json:"recipients"`
+	Body       string   `json:"body"`
+}
+
+type messageBirdRecipient struct {
+	Recipient        json.Number `json:"recipient"`
+	Status           string      `json:"status"`
+	MessagePartCount int         `json:"messagePartCount"`
+}
+
+type messageBirdResponse struct {
+	ID         string `json:"id"`
+	Recipients struct {
+		TotalCount     int                    `json:"totalCount"`
+		TotalSentCount int                    `json:"totalSentCount"`
+		Items          []messageBirdRecipient `json:"items"`
+	} `json:"recipients"`
+	Errors []struct {
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+		Parameter   string `json:"parameter"`
+	} `json:"errors"`
+}
+
+func (sender *MessageBirdSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	return sendEachSms(ctx, "messagebird", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sender.send(ctx, text, receiver)
+	})
+}
+
+func (sender *MessageBirdSender) send(ctx context.Context, text, receiver string) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("messagebird", "sms", err) }()
+
+	// MessageBird expects numbers in international format without the
+	// leading plus sign.
+	number := strings.TrimPrefix(strings.TrimSpace(receiver), "+")
+	if _, err := strconv.ParseUint(number, 10, 64); err != nil {
+		return result, &ValidationError{Field: "receiver", Message: "Invalid phone number " + receiver}
+	}
+
+	body, err := json.Marshal(messageBirdRequest{
+		Originator: sender.Credentials.Originator,
+		Recipients: []string{number},
+		Body:       text,
+	})
+	if err != nil {
+		return result, fmt.Errorf("Failed to encode MessageBird request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		strings.TrimSuffix(sender.BaseURL, "/")+"/messages",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return result, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("Authorization", "AccessKey "+sender.Credentials.AccessKey)
+
+	_, responseBody, err := doProviderRequest("messagebird", "MessageBird", request)
+	result.Raw = string(responseBody)
+	if err != nil {
+		return result, err
+	}
+
+	var messageBirdResp messageBirdResponse
+	if err := json.Unmarshal(responseBody, &messageBirdResp); err != nil {
+		return result, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	if len(messageBirdResp.Errors) > 0 {
+		return result, fmt.Errorf(
+			"MessageBird rejected the message (%d): %s",
+			messageBirdResp.Errors[0].Code,
+			messageBirdResp.Errors[0].Description,
+		)
+	}
+
+	result.MessageID = messageBirdResp.ID
+	for _, recipient := range messageBirdResp.Recipients.Items {
+		if recipient.Recipient.String() != number {
+			continue
+		}
+
+		result.Status = recipient.Status
+		result.Segments = recipient.MessagePartCount
+		if recipient.Status == "delivery_failed" {
+			return result, fmt.Errorf("MessageBird could not send the message")
+		}
+	}
+
+	return result, nil
+}
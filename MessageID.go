@@ -0,0 +1,104 @@
+package messagingutilities
+
+import (
+	"crypto/rand"
+	"maps"
+	"net/textproto"
+	"strings"
+)
+
+// GenerateMessageID returns a new unique Message-ID at domain, without the
+// surrounding angle brackets.
+func GenerateMessageID(domain string) string {
+	return strings.ToLower(rand.Text()) + "@" + domain
+}
+
+// normalizeMessageID strips the angle brackets from id and reports whether
+// what is left can be used as a Message-ID.
+func normalizeMessageID(id string) (string, bool) {
+	id = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(id), "<"), ">")
+	if id == "" || strings.ContainsAny(id, "<> \t\r\n\x00") || !strings.Contains(id, "@") {
+		return "", false
+	}
+
+	return id, true
+}
+
+// assignMessageID generates the Message-ID of email at domain unless one was
+// set on the message. The domain of the sender is used when domain is empty.
+func (email *preparedEmail) assignMessageID(domain string) {
+	if email.MessageID != "" {
+		return
+	}
+
+	if domain == "" {
+		_, address := splitEmailAddress(email.From)
+		if _, host, ok := strings.Cut(address, "@"); ok && host != "" {
+			domain = host
+		} else {
+			domain = "localhost"
+		}
+	}
+
+	email.MessageID = GenerateMessageID(domain)
+}
+
+// threadingHeaders validates the Message-ID and threading fields of message
+// and adds them to headers. References defaults to the In-Reply-To message,
+// as replies are expected to carry it.
+func threadingHeaders(message *Message, headers map[string][]string) (string, map[string][]string, error) {
+	if message.MessageID == "" && message.InReplyTo == "" && len(message.References) == 0 {
+		return "", headers, nil
+	}
+
+	headers = maps.Clone(headers)
+	if headers == nil {
+		headers = map[string][]string{}
+	}
+
+	messageID := ""
+	if message.MessageID != "" {
+		id, ok := normalizeMessageID(message.MessageID)
+		if !ok {
+			return "", nil, &ValidationError{Field: "messageId", Message: "Invalid Message-ID " + message.MessageID}
+		}
+		messageID = id
+		headers["Message-ID"] = []string{"<" + id + ">"}
+	}
+
+	references := []string{}
+	for _, reference := range message.References {
+		id, ok := normalizeMessageID(reference)
+		if !ok {
+			return "", nil, &ValidationError{Field: "references", Message: "Invalid Message-ID " + reference}
+		}
+		references = append(references, "<"+id+">")
+	}
+
+	if message.InReplyTo != "" {
+		id, ok := normalizeMessageID(message.InReplyTo)
+		if !ok {
+			return "", nil, &ValidationError{Field: "inReplyTo", Message: "Invalid Message-ID " + message.InReplyTo}
+		}
+		deleteHeader(headers, "In-Reply-To")
+		headers["In-Reply-To"] = []string{"<" + id + ">"}
+		if len(references) == 0 {
+			references = append(references, "<"+id+">")
+		}
+	}
+
+	if len(references) > 0 {
+		deleteHeader(headers, "References")
+		headers["References"] = []string{strings.Join(references, " ")}
+	}
+
+	return messageID, headers, nil
+}
+
+func deleteHeader(headers map[string][]string, name string) {
+	for key := range headers {
+		if textproto.CanonicalMIMEHeaderKey(key) == textproto.CanonicalMIMEHeaderKey(name) {
+			delete(headers, key)
+		}
+	}
+}
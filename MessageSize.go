@@ -0,0 +1,100 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// MessageSizeError reports a message over a configured size limit.
+// Attachment names the offending attachment and is empty when the limit on
+// the whole encoded message was exceeded. Attachments are only read until
+// they pass the limit, so Size is then a lower bound.
+type MessageSizeError struct {
+	Attachment string
+	Size       int64
+	Limit      int64
+}
+
+func (err *MessageSizeError) Error() string {
+	if err.Attachment != "" {
+		return fmt.Sprintf("Attachment %s exceeds the size limit of %d bytes", err.Attachment, err.Limit)
+	}
+
+	return fmt.Sprintf("Message size of %d bytes exceeds the limit of %d bytes", err.Size, err.Limit)
+}
+
+type byteCounter int64
+
+func (counter *byteCounter) Write(data []byte) (int, error) {
+	*counter += byteCounter(len(data))
+	return len(data), nil
+}
+
+// checkSize enforces the limits before any connection is made. Attachments
+// are buffered to measure them, so their readers are replaced with the
+// buffered data.
+func (email *preparedEmail) checkSize(maxAttachmentSize, maxMessageSize int64) error {
+	if maxAttachmentSize <= 0 && maxMessageSize <= 0 {
+		return nil
+	}
+
+	buffered := [][]byte{}
+	for index, attachment := range email.Attachments {
+		reader := attachment.Data
+		if maxAttachmentSize > 0 {
+			reader = io.LimitReader(reader, maxAttachmentSize+1)
+		}
+
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return fmt.Errorf("Failed to read attachment %s: %w", *attachment.Name, err)
+		}
+		if maxAttachmentSize > 0 && int64(len(data)) > maxAttachmentSize {
+			return &MessageSizeError{Attachment: *attachment.Name, Size: int64(len(data)), Limit: maxAttachmentSize}
+		}
+
+		buffered = append(buffered, data)
+		email.Attachments[index].Data = bytes.NewReader(data)
+	}
+
+	for index, attachment := range email.Inline {
+		reader := attachment.Data
+		if maxAttachmentSize > 0 {
+			reader = io.LimitReader(reader, maxAttachmentSize+1)
+		}
+
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return fmt.Errorf("Failed to read inline attachment %s: %w", attachment.Name, err)
+		}
+		if maxAttachmentSize > 0 && int64(len(data)) > maxAttachmentSize {
+			return &MessageSizeError{Attachment: attachment.Name, Size: int64(len(data)), Limit: maxAttachmentSize}
+		}
+
+		buffered = append(buffered, data)
+		email.Inline[index].Data = bytes.NewReader(data)
+	}
+
+	if maxMessageSize <= 0 {
+		return nil
+	}
+
+	var size byteCounter
+	if _, err := email.gomailMessage().WriteTo(&size); err != nil {
+		return fmt.Errorf("Failed to measure message: %w", err)
+	}
+
+	for index := range email.Attachments {
+		email.Attachments[index].Data = bytes.NewReader(buffered[index])
+	}
+	for index := range email.Inline {
+		email.Inline[index].Data = bytes.NewReader(buffered[len(email.Attachments)+index])
+	}
+
+	if int64(size) > maxMessageSize {
+		return &MessageSizeError{Size: int64(size), Limit: maxMessageSize}
+	}
+
+	return nil
+}
@@ -1,98 +1,668 @@
 package messagingutilities
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"mime"
 	"net/http"
+	"net/mail"
+	"net/textproto"
 	"net/url"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/twilio/twilio-go"
+	"github.com/twilio/twilio-go/client"
 	TWILIO_API "github.com/twilio/twilio-go/rest/api/v2010"
+	"golang.org/x/oauth2"
 	"gopkg.in/gomail.v2"
 )
 
 //lint:file-ignore ST1005 TF
 
+type TLSMode string
+
+const (
+	TLSModeNone     TLSMode = "none"
+	TLSModeSTARTTLS TLSMode = "starttls"
+	TLSModeImplicit TLSMode = "implicit"
+)
+
+// SMTPCredentials picks a TLS mode from the port when TLSMode is empty:
+// implicit TLS on 465, otherwise STARTTLS, which is required when UseTLS is
+// set and used opportunistically when it is not. TLSConfig is cloned per
+// connection, with ServerName defaulting to Host. When TokenSource is set
+// the connection authenticates with XOAUTH2 as User instead of a password.
+// Messages are S/MIME or PGP/MIME signed and optionally encrypted when SMIME
+// or PGP is set, and DKIM signed after that when DKIM is set.
+// MaxAttachmentSize and MaxMessageSize, in bytes, are checked before
+// connecting when non-zero; the message size is measured after encoding.
 type SMTPCredentials struct {
-	Host     string
-	Port     string
-	User     string
-	Sender   string
-	Password string
-	UseTLS   bool
+	Host        string
+	Port        string
+	User        string
+	Sender      string
+	Password    string
+	UseTLS      bool
+	TLSMode     TLSMode
+	TLSConfig   *tls.Config
+	TokenSource oauth2.TokenSource
+	DKIM        *DKIMOptions
+	SMIME       *SMIMEOptions
+	PGP         *PGPOptions
+
+	MaxAttachmentSize int64
+	MaxMessageSize    int64
+
+	// MessageIDDomain is the domain of generated Message-IDs. It defaults to
+	// the domain of Sender.
+	MessageIDDomain string
+
+	// ReturnPath is the envelope sender that receives bounces, which
+	// defaults to the address of Sender. With VERP set, every recipient is
+	// sent a separate copy with the recipient encoded in the envelope
+	// sender; see VERPRecipient.
+	ReturnPath string
+	VERP       bool
 }
 
+// EmailAttachment takes its content type from ContentType when set,
+// otherwise from the extension of Name or, failing that, by sniffing the
+// first bytes of Data.
 type EmailAttachment struct {
-	Data io.Reader
-	Name *string
+	Data        io.Reader
+	Name        *string
+	ContentType string
 }
 
-func SendSMTPEmailMessage(
-	credentials *SMTPCredentials,
+func (attachment *EmailAttachment) contentType() string {
+	if attachment.ContentType != "" {
+		return attachment.ContentType
+	}
+
+	return "application/octet-stream"
+}
+
+// detectContentType fills in ContentType, replacing Data with a reader that
+// still yields the sniffed bytes. It must be called on the message's own
+// attachment rather than a copy, or the caller is left holding a reader
+// that has lost them.
+func (attachment *EmailAttachment) detectContentType() error {
+	if attachment.ContentType != "" {
+		return nil
+	}
+
+	if contentType := mime.TypeByExtension(filepath.Ext(*attachment.Name)); contentType != "" {
+		attachment.ContentType = contentType
+		return nil
+	}
+
+	head := make([]byte, 512)
+	read, err := io.ReadFull(attachment.Data, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("Failed to read attachment %s: %w", *attachment.Name, err)
+	}
+	head = head[:read]
+
+	attachment.ContentType = http.DetectContentType(head)
+	attachment.Data = io.MultiReader(bytes.NewReader(head), attachment.Data)
+
+	return nil
+}
+
+// InlineAttachment is embedded in the message so HTML bodies can reference
+// it as "cid:<ContentID>". ContentID defaults to Name.
+type InlineAttachment struct {
+	ContentID string
+	Name      string
+	Data      io.Reader
+}
+
+type preparedEmail struct {
+	From        string
+	To          []string
+	Cc          []string
+	Bcc         []string
+	ReplyTo     string
+	Headers     map[string][]string
+	MessageID   string
+	Subject     string
+	Text        string
+	HTML        string
+	AMP         string
+	Category    MessageCategory
+	Tags        []string
+	Metadata    map[string]string
+	SendAt      *time.Time
+	Attachments []EmailAttachment
+	Inline      []InlineAttachment
+
+	CalendarMethod CalendarMethod
+	Calendar       string
+}
+
+func prepareEmail(from string, message *Message) (*preparedEmail, error) {
+	if err := checkChannel(message, ChannelEmail); err != nil {
+		return nil, err
+	}
+
+	if len(message.To) == 0 {
+		return nil, &ValidationError{Field: "receivers", Message: "Receivers cannot be empty"}
+	}
+
+	for _, group := range []struct {
+		field     string
+		addresses []string
+	}{
+		{"to", message.To},
+		{"cc", message.Cc},
+		{"bcc", message.Bcc},
+		{"replyTo", []string{message.ReplyTo}},
+	} {
+		for _, address := range group.addresses {
+			if address == "" && group.field == "replyTo" {
+				continue
+			}
+			if _, err := mail.ParseAddress(address); err != nil {
+				return nil, &ValidationError{Field: group.field, Message: "Invalid email address: " + address}
+			}
+		}
+	}
+
+	if err := validateEmailHeaders(message.Headers); err != nil {
+		return nil, err
+	}
+
+	headers := message.Headers
+	if message.Priority != "" {
+		priorityHeaders, ok := emailPriorityHeaders[message.Priority]
+		if !ok {
+			return nil, &ValidationError{Field: "priority", Message: "Invalid priority " + string(message.Priority)}
+		}
+
+		headers = maps.Clone(message.Headers)
+		if headers == nil {
+			headers = map[string][]string{}
+		}
+		for name, value := range priorityHeaders {
+			deleteHeader(headers, name)
+			headers[name] = []string{value}
+		}
+	}
+
+	messageID, headers, err := threadingHeaders(message, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	// Clients without AMP support show the HTML part instead.
+	if message.AMP != "" && message.HTML == "" {
+		return nil, &ValidationError{Field: "amp", Message: "An AMP part requires an HTML body"}
+	}
+
+	email := &preparedEmail{
+		From:      from,
+		To:        message.To,
+		Cc:        message.Cc,
+		Bcc:       message.Bcc,
+		ReplyTo:   message.ReplyTo,
+		Headers:   headers,
+		MessageID: messageID,
+		Subject:   message.Subject,
+		Text:      message.Text,
+		HTML:      message.HTML,
+		AMP:       message.AMP,
+		Category:  message.Category,
+		Tags:      message.Tags,
+		Metadata:  message.Metadata,
+		SendAt:    message.SendAt,
+	}
+
+	for index := range message.Attachments {
+		attachment := &message.Attachments[index]
+		if attachment.Name == nil || attachment.Data == nil {
+			return nil, &ValidationError{Field: "attachments", Message: "Attachments must have a name and data"}
+		}
+		if err := attachment.detectContentType(); err != nil {
+			return nil, err
+		}
+		email.Attachments = append(email.Attachments, *attachment)
+	}
+
+	for _, attachment := range message.Inline {
+		if attachment.Name == "" || attachment.Data == nil {
+			return nil, &ValidationError{Field: "inline", Message: "Inline attachments must have a name and data"}
+		}
+		if attachment.ContentID == "" {
+			attachment.ContentID = attachment.Name
+		}
+		if strings.ContainsAny(attachment.ContentID, "<> \t\r\n") {
+			return nil, &ValidationError{Field: "inline", Message: "Invalid content ID " + attachment.ContentID}
+		}
+		email.Inline = append(email.Inline, attachment)
+	}
+
+	if message.Calendar != nil {
+		if err := message.Calendar.validate(); err != nil {
+			return nil, err
+		}
+
+		organizer := message.Calendar.Organizer
+		if organizer == "" {
+			organizer = from
+		}
+		if _, err := mail.ParseAddress(organizer); err != nil {
+			return nil, &ValidationError{Field: "calendar", Message: "Invalid organizer address: " + organizer}
+		}
+
+		attendees := message.Calendar.Attendees
+		if len(attendees) == 0 {
+			attendees = append(append([]string{}, message.To...), message.Cc...)
+		}
+		for _, attendee := range attendees {
+			if _, err := mail.ParseAddress(attendee); err != nil {
+				return nil, &ValidationError{Field: "calendar", Message: "Invalid attendee address: " + attendee}
+			}
+		}
+
+		email.CalendarMethod = message.Calendar.Method
+		email.Calendar = message.Calendar.ICS(organizer, attendees)
+	}
+
+	return email, nil
+}
+
+func legacyEmailMessage(
 	subject,
 	message *string,
 	isHtml bool,
 	attachments *[]EmailAttachment,
 	receivers *[]string,
-) error {
-	message_ := gomail.NewMessage()
+) *Message {
+	builder := NewEmail()
 
-	message_.SetHeader("From", credentials.Sender)
-	message_.SetHeader("To", *receivers...)
-	if subject != nil {
-		message_.SetHeader("Subject", *subject)
+	if receivers != nil {
+		builder.To(*receivers...)
 	}
 
-	mimeType := "text/plain"
-	if isHtml {
-		mimeType = "text/html"
+	if subject != nil {
+		builder.Subject(*subject)
 	}
+
 	if message != nil {
-		message_.SetBody(mimeType, *message)
+		if isHtml {
+			builder.HTML(*message)
+		} else {
+			builder.Text(*message)
+		}
 	}
 
 	if attachments != nil {
-		for _, reader := range *attachments {
-			message_.Attach(
-				*reader.Name,
-				gomail.SetHeader(map[string][]string{
-					"Content-Type": {"application/octet-stream"},
-				}),
-				gomail.SetCopyFunc(func(w io.Writer) error {
-					_, err := io.Copy(w, reader.Data)
-					return err
-				}),
-			)
+		builder.Attachments(*attachments...)
+	}
+
+	return builder.Build()
+}
+
+// reservedEmailHeaders are set from the message fields and cannot be
+// overridden through Message.Headers.
+var reservedEmailHeaders = map[string]bool{
+	"From":                      true,
+	"Sender":                    true,
+	"To":                        true,
+	"Cc":                        true,
+	"Bcc":                       true,
+	"Reply-To":                  true,
+	"Subject":                   true,
+	"Date":                      true,
+	"Message-Id":                true,
+	"Mime-Version":              true,
+	"Content-Type":              true,
+	"Content-Transfer-Encoding": true,
+	"Dkim-Signature":            true,
+}
+
+// emailPriorityHeaders replace any priority headers set through
+// Message.Headers, keyed by their canonical names.
+var emailPriorityHeaders = map[EmailPriority]map[string]string{
+	EmailPriorityHigh:   {"X-Priority": "1 (Highest)", "Importance": "high", "X-Msmail-Priority": "High"},
+	EmailPriorityNormal: {"X-Priority": "3 (Normal)", "Importance": "normal", "X-Msmail-Priority": "Normal"},
+	EmailPriorityLow:    {"X-Priority": "5 (Lowest)", "Importance": "low", "X-Msmail-Priority": "Low"},
+}
+
+func validateEmailHeaders(headers map[string][]string) error {
+	for name, values := range headers {
+		if name == "" {
+			return &ValidationError{Field: "headers", Message: "Header names cannot be empty"}
+		}
+		for _, character := range name {
+			if character < 33 || character > 126 || character == ':' {
+				return &ValidationError{Field: "headers", Message: fmt.Sprintf("Invalid header name %q", name)}
+			}
+		}
+
+		if reservedEmailHeaders[textproto.CanonicalMIMEHeaderKey(name)] {
+			return &ValidationError{Field: "headers", Message: fmt.Sprintf("Header %s cannot be set directly", name)}
+		}
+
+		for _, value := range values {
+			if strings.ContainsAny(value, "\r\n\x00") {
+				return &ValidationError{Field: "headers", Message: fmt.Sprintf("Header %s contains a line break", name)}
+			}
 		}
 	}
 
-	port, err := strconv.Atoi(credentials.Port)
+	return nil
+}
+
+// flatHeaders joins repeated header values for providers that accept a
+// single value per header name.
+func (email *preparedEmail) flatHeaders() map[string]string {
+	if len(email.Headers) == 0 {
+		return nil
+	}
+
+	headers := map[string]string{}
+	for name, values := range email.Headers {
+		headers[name] = strings.Join(values, ", ")
+	}
+
+	return headers
+}
+
+// recipients returns the SMTP envelope recipients, including Bcc.
+func (email *preparedEmail) recipients() []string {
+	recipients := append([]string{}, email.To...)
+	recipients = append(recipients, email.Cc...)
+	recipients = append(recipients, email.Bcc...)
+
+	return recipients
+}
+
+// splitEmailAddress returns the display name and bare address of a value
+// such as "Jane Doe <jane@example.com>".
+func splitEmailAddress(value string) (string, string) {
+	address, err := mail.ParseAddress(value)
 	if err != nil {
-		return fmt.Errorf("Invalid port number: %w", err)
+		return "", value
+	}
+
+	return address.Name, address.Address
+}
+
+func formatAddressHeader(message_ *gomail.Message, values []string) []string {
+	formatted := []string{}
+	for _, value := range values {
+		name, address := splitEmailAddress(value)
+		formatted = append(formatted, message_.FormatAddress(address, name))
+	}
+
+	return formatted
+}
+
+func (email *preparedEmail) gomailMessage() *gomail.Message {
+	message_ := gomail.NewMessage()
+
+	message_.SetHeader("From", formatAddressHeader(message_, []string{email.From})...)
+	message_.SetHeader("To", formatAddressHeader(message_, email.To)...)
+	if len(email.Cc) > 0 {
+		message_.SetHeader("Cc", formatAddressHeader(message_, email.Cc)...)
+	}
+	if email.ReplyTo != "" {
+		message_.SetHeader("Reply-To", formatAddressHeader(message_, []string{email.ReplyTo})...)
+	}
+	if email.Subject != "" {
+		message_.SetHeader("Subject", email.Subject)
+	}
+	if email.MessageID != "" {
+		message_.SetHeader("Message-ID", "<"+email.MessageID+">")
+	}
+	for name, values := range email.Headers {
+		message_.SetHeader(name, values...)
+	}
+
+	bodies := [][2]string{}
+	if email.Text != "" {
+		bodies = append(bodies, [2]string{"text/plain", email.Text})
+	}
+	// Some clients show the last alternative they support, so the AMP part
+	// goes before the HTML it falls back to.
+	if email.AMP != "" {
+		bodies = append(bodies, [2]string{"text/x-amp-html", email.AMP})
+	}
+	if email.HTML != "" {
+		bodies = append(bodies, [2]string{"text/html", email.HTML})
+	}
+	// Calendar clients only treat the message as an invite when the event is
+	// an alternative of the body rather than an attachment.
+	if email.Calendar != "" {
+		bodies = append(bodies, [2]string{email.CalendarMethod.contentType(), email.Calendar})
+	}
+	for index, body := range bodies {
+		if index == 0 {
+			message_.SetBody(body[0], body[1])
+		} else {
+			message_.AddAlternative(body[0], body[1])
+		}
 	}
 
-	dialer := gomail.NewDialer(
-		credentials.Host,
-		port,
-		credentials.User,
-		credentials.Password,
+	for _, reader := range email.Attachments {
+		message_.Attach(
+			*reader.Name,
+			gomail.SetHeader(map[string][]string{
+				"Content-Type": {reader.contentType()},
+			}),
+			gomail.SetCopyFunc(func(w io.Writer) error {
+				_, err := io.Copy(w, reader.Data)
+				return err
+			}),
+		)
+	}
+
+	for _, attachment := range email.Inline {
+		message_.Embed(
+			attachment.Name,
+			gomail.SetHeader(map[string][]string{
+				"Content-Type": {inlineContentType(attachment.Name)},
+				"Content-ID":   {"<" + attachment.ContentID + ">"},
+			}),
+			gomail.SetCopyFunc(func(w io.Writer) error {
+				_, err := io.Copy(w, attachment.Data)
+				return err
+			}),
+		)
+	}
+
+	return message_
+}
+
+func inlineContentType(name string) string {
+	if contentType := mime.TypeByExtension(filepath.Ext(name)); contentType != "" {
+		return contentType
+	}
+
+	return "application/octet-stream"
+}
+
+type emailAttachmentData struct {
+	Name        string
+	ContentType string
+	ContentID   string
+	Inline      bool
+	Data        []byte
+}
+
+func (email *preparedEmail) readAttachments() ([]emailAttachmentData, error) {
+	attachments := []emailAttachmentData{}
+	for _, attachment := range email.Attachments {
+		data, err := io.ReadAll(attachment.Data)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read attachment %s: %w", *attachment.Name, err)
+		}
+
+		attachments = append(attachments, emailAttachmentData{
+			Name:        *attachment.Name,
+			ContentType: attachment.contentType(),
+			Data:        data,
+		})
+	}
+
+	for _, attachment := range email.Inline {
+		data, err := io.ReadAll(attachment.Data)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read inline attachment %s: %w", attachment.Name, err)
+		}
+
+		attachments = append(attachments, emailAttachmentData{
+			Name:        attachment.Name,
+			ContentType: inlineContentType(attachment.Name),
+			ContentID:   attachment.ContentID,
+			Inline:      true,
+			Data:        data,
+		})
+	}
+
+	if email.Calendar != "" {
+		attachments = append(attachments, emailAttachmentData{
+			Name:        "invite.ics",
+			ContentType: email.CalendarMethod.contentType(),
+			Data:        []byte(email.Calendar),
+		})
+	}
+
+	return attachments, nil
+}
+
+func SendSMTPEmailMessage(
+	credentials *SMTPCredentials,
+	subject,
+	message *string,
+	isHtml bool,
+	attachments *[]EmailAttachment,
+	receivers *[]string,
+) error {
+	return SendSMTPEmailMessageContext(context.Background(), credentials, subject, message, isHtml, attachments, receivers)
+}
+
+func SendSMTPEmailMessageContext(
+	ctx context.Context,
+	credentials *SMTPCredentials,
+	subject,
+	message *string,
+	isHtml bool,
+	attachments *[]EmailAttachment,
+	receivers *[]string,
+) error {
+	_, err := NewSMTPSender(credentials).Send(
+		ctx,
+		legacyEmailMessage(subject, message, isHtml, attachments, receivers),
 	)
 
-	if credentials.UseTLS {
-		//
+	return err
+}
+
+func sendSMTPEmail(ctx context.Context, credentials *SMTPCredentials, email *preparedEmail) (response string, err error) {
+	port, err := strconv.Atoi(credentials.Port)
+	if err != nil {
+		return "", fmt.Errorf("Invalid port number: %w", err)
+	}
+
+	if err := email.checkSize(credentials.MaxAttachmentSize, credentials.MaxMessageSize); err != nil {
+		return "", err
+	}
+
+	defer func() {
+		if err != nil && ctx.Err() != nil {
+			err = fmt.Errorf("%w: %w", ctx.Err(), err)
+		}
+	}()
+
+	message, err := renderSMTPMessage(credentials, email)
+	if err != nil {
+		return "", err
+	}
+
+	sender, err := dialSMTP(ctx, credentials, port)
+	if err != nil {
+		return "", err
+	}
+	defer sender.Close()
+
+	if err := sender.sendMessage(message); err != nil {
+		return "", err
 	}
 
-	return dialer.DialAndSend(message_)
+	return sender.lastResponse, nil
 }
 
+var smtpQueueIDPattern = regexp.MustCompile(`(?i)(?:queued as|id=|ok:?)\s*<?([A-Za-z0-9._@-]+)>?`)
+
+func smtpQueueID(response string) string {
+	match := smtpQueueIDPattern.FindStringSubmatch(response)
+	if match == nil {
+		return ""
+	}
+
+	return match[1]
+}
+
+// TwilioCredentials needs at least one of SenderPhoneNumber and
+// MessagingServiceSID. A Messaging Service picks the sender from its pool,
+// which enables sticky sender and geo-match; SenderPhoneNumber then pins
+// the sender to one of the pool's numbers. StatusCallback is used for
+// messages without their own Message.StatusCallback.
 type TwilioCredentials struct {
-	AccountSID        string
-	AuthToken         string
-	SenderPhoneNumber string
-	SenderName        string
+	AccountSID          string
+	AuthToken           string
+	SenderPhoneNumber   string
+	SenderName          string
+	MessagingServiceSID string
+	StatusCallback      string
+}
+
+// contextTransport binds every request to ctx, since twilio-go builds its own
+// requests without one.
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+func (transport *contextTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	base := transport.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return base.RoundTrip(request.WithContext(transport.ctx))
+}
+
+func newTwilioRestClient(ctx context.Context, credentials *TwilioCredentials) *twilio.RestClient {
+	httpClient := &http.Client{
+		Transport: &contextTransport{ctx: ctx},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Timeout: 10 * time.Second,
+	}
+
+	baseClient := &client.Client{
+		Credentials: client.NewCredentials(credentials.AccountSID, credentials.AuthToken),
+		HTTPClient:  debugHTTPClient("twilio", httpClient),
+	}
+	baseClient.SetAccountSid(credentials.AccountSID)
+
+	return twilio.NewRestClientWithParams(twilio.ClientParams{
+		Client: baseClient,
+	})
 }
 
 func SendTwilioSmsMessage(
@@ -100,21 +670,200 @@ func SendTwilioSmsMessage(
 	message *string,
 	receiver *string,
 ) error {
-	client := twilio.NewRestClientWithParams(twilio.ClientParams{
-		Username: credentials.AccountSID,
-		Password: credentials.AuthToken,
-	})
+	return SendTwilioSmsMessageContext(context.Background(), credentials, message, receiver)
+}
 
-	if message == nil {
-		return fmt.Errorf("Message body and receivers cannot be empty")
+func SendTwilioSmsMessageContext(
+	ctx context.Context,
+	credentials *TwilioCredentials,
+	message *string,
+	receiver *string,
+) error {
+	if receiver == nil {
+		return &ValidationError{Field: "receiver", Message: "Message body and receivers cannot be empty"}
+	}
+
+	builder := NewSMS().To(*receiver)
+	if message != nil {
+		builder.Text(*message)
 	}
 
+	_, err := NewTwilioSender(credentials).Send(ctx, builder.Build())
+
+	return err
+}
+
+func sendTwilioMessage(
+	ctx context.Context,
+	credentials *TwilioCredentials,
+	channel Channel,
+	message *Message,
+	text,
+	receiver string,
+) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("twilio", string(channel), err) }()
+
+	client := newTwilioRestClient(ctx, credentials)
+
 	params := &TWILIO_API.CreateMessageParams{}
-	params.SetBody(*message)
-	params.SetFrom(credentials.SenderPhoneNumber)
-	params.SetTo(*receiver)
+	if text != "" {
+		params.SetBody(text)
+	}
+	if len(message.MediaURLs) > 0 {
+		params.SetMediaUrl(message.MediaURLs)
+	}
+	if template := message.ContentTemplate; template != nil {
+		if template.SID == "" {
+			return result, &ValidationError{Field: "contentTemplate", Message: "Content template SID cannot be empty"}
+		}
+		params.SetContentSid(template.SID)
+		if len(template.Variables) > 0 {
+			variables, err := json.Marshal(template.Variables)
+			if err != nil {
+				return result, fmt.Errorf("Failed to encode content variables: %w", err)
+			}
+			params.SetContentVariables(string(variables))
+		}
+	}
+	if message.SendAt != nil {
+		if err := validateTwilioSchedule(credentials, *message.SendAt); err != nil {
+			return result, err
+		}
+		params.SetScheduleType("fixed")
+		params.SetSendAt(message.SendAt.UTC())
+	}
+	if message.StatusCallback != "" {
+		params.SetStatusCallback(message.StatusCallback)
+	} else if credentials.StatusCallback != "" {
+		params.SetStatusCallback(credentials.StatusCallback)
+	}
+	if credentials.MessagingServiceSID != "" {
+		params.SetMessagingServiceSid(credentials.MessagingServiceSID)
+	}
+	from := credentials.SenderPhoneNumber
+	if channel == ChannelWhatsApp {
+		receiver = whatsAppAddress(receiver)
+		if from != "" {
+			from = whatsAppAddress(from)
+		}
+	}
+	if from != "" {
+		params.SetFrom(from)
+	}
+	params.SetTo(receiver)
+
+	response, err := client.Api.CreateMessage(params)
+	if err != nil {
+		return result, wrapTwilioError(err)
+	}
+
+	return twilioRecipientResult(response), nil
+}
+
+func twilioRecipientResult(response *TWILIO_API.ApiV2010Message) RecipientResult {
+	result := RecipientResult{}
+	if response.Sid != nil {
+		result.MessageID = *response.Sid
+	}
+	if response.Status != nil {
+		result.Status = *response.Status
+	}
+	if response.Price != nil {
+		result.Cost = *response.Price
+		if response.PriceUnit != nil {
+			result.Cost += " " + *response.PriceUnit
+		}
+	}
+	if response.NumSegments != nil {
+		result.Segments, _ = strconv.Atoi(*response.NumSegments)
+	}
+	if raw, err := json.Marshal(response); err == nil {
+		result.Raw = string(raw)
+	}
+
+	return result
+}
+
+// Twilio schedules messages between 15 minutes and 35 days ahead.
+const (
+	twilioMinScheduleDelay = 15 * time.Minute
+	twilioMaxScheduleDelay = 35 * 24 * time.Hour
+)
+
+func validateTwilioSchedule(credentials *TwilioCredentials, sendAt time.Time) error {
+	if credentials.MessagingServiceSID == "" {
+		return &ValidationError{Field: "sendAt", Message: "Scheduled Twilio messages require a messaging service"}
+	}
+
+	delay := time.Until(sendAt)
+	if delay < twilioMinScheduleDelay || delay > twilioMaxScheduleDelay {
+		return &ValidationError{Field: "sendAt", Message: "Twilio messages can only be scheduled 15 minutes to 35 days ahead"}
+	}
+
+	return nil
+}
+
+// CancelTwilioScheduledMessage cancels a message scheduled with SendAt
+// before it is sent.
+func CancelTwilioScheduledMessage(ctx context.Context, credentials *TwilioCredentials, sid string) error {
+	if sid == "" {
+		return &ValidationError{Field: "sid", Message: "Message SID cannot be empty"}
+	}
+
+	params := &TWILIO_API.UpdateMessageParams{}
+	params.SetStatus("canceled")
+
+	if _, err := newTwilioRestClient(ctx, credentials).Api.UpdateMessage(sid, params); err != nil {
+		return wrapTwilioError(err)
+	}
+
+	return nil
+}
+
+// GetTwilioMessageStatus fetches the current status, error and price of a
+// message sent through Twilio.
+func GetTwilioMessageStatus(ctx context.Context, credentials *TwilioCredentials, sid string) (*MessageStatus, error) {
+	if sid == "" {
+		return nil, &ValidationError{Field: "sid", Message: "Message SID cannot be empty"}
+	}
 
-	_, err := client.Api.CreateMessage(params)
+	response, err := newTwilioRestClient(ctx, credentials).Api.FetchMessage(sid, nil)
+	if err != nil {
+		return nil, wrapTwilioError(err)
+	}
+
+	status := &MessageStatus{MessageID: sid}
+	if response.To != nil {
+		status.Recipient = *response.To
+	}
+	if response.Status != nil {
+		status.ProviderStatus = *response.Status
+		status.Status = twilioDeliveryStatus(*response.Status)
+	}
+	if response.ErrorCode != nil && *response.ErrorCode != 0 {
+		status.ErrorCode = strconv.Itoa(*response.ErrorCode)
+	}
+	if response.ErrorMessage != nil {
+		status.ErrorMessage = *response.ErrorMessage
+	}
+	if response.Price != nil {
+		status.Cost = *response.Price
+		if response.PriceUnit != nil {
+			status.Cost += " " + *response.PriceUnit
+		}
+	}
+	if response.DateUpdated != nil {
+		status.UpdatedAt, _ = time.Parse(time.RFC1123Z, *response.DateUpdated)
+	}
+
+	return status, nil
+}
+
+func wrapTwilioError(err error) error {
+	var restError *client.TwilioRestError
+	if errors.As(err, &restError) && restError.Status == http.StatusTooManyRequests {
+		return fmt.Errorf("%w: %w", ErrRateLimited, err)
+	}
 
 	return err
 }
@@ -123,10 +872,35 @@ type AfricasTalkingCredentials struct {
 	ApiKey   string
 	Username string
 	SenderID string
+	// Sandbox sends requests to the Africa's Talking sandbox with the
+	// "sandbox" username, using the API key of the sandbox app.
+	Sandbox bool
+}
+
+// url returns the endpoint at path on host, a subdomain of
+// africastalking.com such as "api", in the environment of the credentials.
+func (credentials *AfricasTalkingCredentials) url(host, path string) string {
+	if credentials.Sandbox {
+		host += ".sandbox"
+	}
+
+	return "https://" + host + ".africastalking.com" + path
+}
+
+func (credentials *AfricasTalkingCredentials) username() string {
+	if credentials.Sandbox {
+		return "sandbox"
+	}
+
+	return credentials.Username
 }
 
 type atSmsResponseRecipient struct {
-	status string `json:""`
+	Status     string `json:"status"`
+	StatusCode int    `json:"statusCode"`
+	MessageID  string `json:"messageId"`
+	Number     string `json:"number"`
+	Cost       string `json:"cost"`
 }
 
 type atSmsResponse struct {
@@ -141,63 +915,98 @@ func SendAfricasTalkingSmsMessage(
 	message *string,
 	receiver *string,
 ) error {
-	if message == nil {
-		return fmt.Errorf("Message body cannot be empty")
+	return SendAfricasTalkingSmsMessageContext(context.Background(), credentials, message, receiver)
+}
+
+func SendAfricasTalkingSmsMessageContext(
+	ctx context.Context,
+	credentials *AfricasTalkingCredentials,
+	message *string,
+	receiver *string,
+) error {
+	if receiver == nil {
+		return &ValidationError{Field: "receiver", Message: "Receiver cannot be empty"}
 	}
 
-	if strings.Contains(*receiver, ",") {
-		return fmt.Errorf("Multiple receivers may hav been passed")
+	builder := NewSMS().To(*receiver)
+	if message != nil {
+		builder.Text(*message)
 	}
 
-	baseURL := "https://api.africastalking.com/version1/messaging"
+	_, err := NewAfricasTalkingSender(credentials).Send(ctx, builder.Build())
 
-	payload := url.Values{}
-	payload.Set("username", credentials.Username)
-	payload.Set("to", *receiver)
-	payload.Set("from", credentials.SenderID)
-	payload.Set("message", *message)
+	return err
+}
 
-	request, err := http.NewRequest("POST", baseURL, strings.NewReader(payload.Encode()))
-	if err != nil {
-		return fmt.Errorf("Failed to create http request: %w", err)
+func sendAfricasTalkingSms(
+	ctx context.Context,
+	credentials *AfricasTalkingCredentials,
+	premium *AfricasTalkingPremiumOptions,
+	text,
+	receiver string,
+) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("africastalking", "sms", err) }()
+
+	if strings.Contains(receiver, ",") {
+		return result, &ValidationError{Field: "receiver", Message: "Multiple receivers may hav been passed"}
 	}
 
-	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	request.Header.Set("Accept", "application/json")
-	request.Header.Set("apiKey", credentials.ApiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(request)
+	atResp, responseBody, err := postAfricasTalkingSms(ctx, credentials, premium, text, []string{receiver})
+	result.Raw = string(responseBody)
 	if err != nil {
-		return fmt.Errorf("Failed to execute http request: %w", err)
+		return result, err
 	}
 
-	defer resp.Body.Close()
+	if len(atResp.SMSMessageData.Recipients) != 1 {
+		return result, fmt.Errorf("Africa's talking rejected the message: %s", atResp.SMSMessageData.Message)
+	}
 
-	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
+	recipient := atResp.SMSMessageData.Recipients[0]
+	recipient.fill(&result)
 
-		return fmt.Errorf(
-			"Africa's talking API failed with status %d. Response body: %s",
-			resp.StatusCode,
-			string(bodyBytes),
-		)
+	return result, recipient.err()
+}
+
+// postAfricasTalkingSms sends text to all receivers in a single request.
+func postAfricasTalkingSms(
+	ctx context.Context,
+	credentials *AfricasTalkingCredentials,
+	premium *AfricasTalkingPremiumOptions,
+	text string,
+	receivers []string,
+) (*atSmsResponse, []byte, error) {
+	payload := url.Values{}
+	payload.Set("username", credentials.username())
+	payload.Set("to", strings.Join(receivers, ","))
+	payload.Set("from", credentials.SenderID)
+	payload.Set("message", text)
+	if premium != nil {
+		premium.setParameters(payload)
 	}
 
-	var atResp atSmsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&atResp); err != nil {
-		return fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		credentials.url("api", "/version1/messaging"),
+		strings.NewReader(payload.Encode()),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to create http request: %w", err)
 	}
 
-	if len(atResp.SMSMessageData.Recipients) != 1 {
-		return fmt.Errorf("Sent recipient list is empty.")
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("apiKey", credentials.ApiKey)
+
+	_, responseBody, err := doProviderRequest("africastalking", "Africa's talking", request)
+	if err != nil {
+		return nil, responseBody, err
 	}
 
-	for _, atResp_ := range atResp.SMSMessageData.Recipients {
-		if strings.Compare("", atResp_.status) != 0 {
-			return fmt.Errorf("Message could not be sent")
-		}
+	var atResp atSmsResponse
+	if err := json.Unmarshal(responseBody, &atResp); err != nil {
+		return nil, responseBody, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
 	}
 
-	return nil
+	return &atResp, responseBody, nil
 }
@@ -0,0 +1,163 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// OrangeCredentials authenticates with the client credentials of an Orange
+// Developer application. SenderAddress is the number the SMS contract is
+// attached to, such as "tel:+2250000" for the development sender of Côte
+// d'Ivoire. SenderName is an approved production sender name and is left
+// empty in development.
+type OrangeCredentials struct {
+	ClientID      string
+	ClientSecret  string
+	SenderAddress string
+	SenderName    string
+}
+
+// OrangeSender caches its access token across sends until it expires.
+type OrangeSender struct {
+	Credentials *OrangeCredentials
+	BaseURL     string
+
+	tokenMutex  sync.Mutex
+	tokenSource oauth2.TokenSource
+}
+
+func NewOrangeSender(credentials *OrangeCredentials) *OrangeSender {
+	return &OrangeSender{
+		Credentials: credentials,
+		BaseURL:     "https://api.orange.com",
+	}
+}
+
+type orangeMessageRequest struct {
+	Address                string `json:"address"`
+	SenderAddress          string `json:"senderAddress"`
+	SenderName             string `json:"senderName,omitempty"`
+	OutboundSMSTextMessage struct {
+		Message string `json:"message"`
+	} `json:"outboundSMSTextMessage"`
+}
+
+type orangeRequest struct {
+	OutboundSMSMessageRequest orangeMessageRequest `json:"outboundSMSMessageRequest"`
+}
+
+type orangeResponse struct {
+	OutboundSMSMessageRequest struct {
+		ResourceURL string `json:"resourceURL"`
+	} `json:"outboundSMSMessageRequest"`
+}
+
+func (sender *OrangeSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	if sender.Credentials.SenderAddress == "" {
+		return nil, &ValidationError{Field: "sender", Message: "Sender address cannot be empty"}
+	}
+
+	return sendEachSms(ctx, "orange", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sender.send(ctx, text, receiver)
+	})
+}
+
+func (sender *OrangeSender) token() (*oauth2.Token, error) {
+	sender.tokenMutex.Lock()
+	defer sender.tokenMutex.Unlock()
+
+	if sender.tokenSource == nil {
+		config := &clientcredentials.Config{
+			ClientID:     sender.Credentials.ClientID,
+			ClientSecret: sender.Credentials.ClientSecret,
+			TokenURL:     strings.TrimSuffix(sender.BaseURL, "/") + "/oauth/v3/token",
+			AuthStyle:    oauth2.AuthStyleInHeader,
+		}
+		// The token source outlives any single send, so it is not bound to
+		// the context of the send that created it.
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, newProviderHTTPClient("orange"))
+		sender.tokenSource = config.TokenSource(ctx)
+	}
+
+	token, err := sender.tokenSource.Token()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get Orange access token: %w", err)
+	}
+
+	return token, nil
+}
+
+func (sender *OrangeSender) send(ctx context.Context, text, receiver string) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("orange", "sms", err) }()
+
+	token, err := sender.token()
+	if err != nil {
+		return result, err
+	}
+
+	senderAddress := orangeAddress(sender.Credentials.SenderAddress)
+	payload := orangeRequest{OutboundSMSMessageRequest: orangeMessageRequest{
+		Address:       orangeAddress(receiver),
+		SenderAddress: senderAddress,
+		SenderName:    sender.Credentials.SenderName,
+	}}
+	payload.OutboundSMSMessageRequest.OutboundSMSTextMessage.Message = text
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return result, fmt.Errorf("Failed to encode Orange request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		strings.TrimSuffix(sender.BaseURL, "/")+"/smsmessaging/v1/outbound/"+url.QueryEscape(senderAddress)+"/requests",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return result, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	token.SetAuthHeader(request)
+
+	_, responseBody, err := doProviderRequest("orange", "Orange", request)
+	result.Raw = string(responseBody)
+	if err != nil {
+		return result, err
+	}
+
+	var orangeResp orangeResponse
+	if err := json.Unmarshal(responseBody, &orangeResp); err != nil {
+		return result, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	// The id of the request is the last segment of its resource URL.
+	if resourceURL := orangeResp.OutboundSMSMessageRequest.ResourceURL; resourceURL != "" {
+		result.MessageID = path.Base(resourceURL)
+	}
+	result.Status = string(DeliveryStatusQueued)
+
+	return result, nil
+}
+
+// orangeAddress formats number as the "tel:+<number>" URI Orange expects.
+func orangeAddress(number string) string {
+	number = strings.TrimPrefix(strings.TrimSpace(number), "tel:")
+	if !strings.HasPrefix(number, "+") {
+		number = "+" + number
+	}
+
+	return "tel:" + number
+}
@@ -0,0 +1,171 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"crypto"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// PGPOptions produces PGP/MIME messages as described in RFC 3156. Messages
+// are signed with SigningKey when it is set, whose private key must already
+// be decrypted. When Encrypt is set the message is encrypted to every
+// recipient's entry in RecipientKeys, keyed by address, and to SigningKey so
+// the sender can read its own copy; sending fails if a recipient has no key.
+type PGPOptions struct {
+	SigningKey    *openpgp.Entity
+	Encrypt       bool
+	RecipientKeys map[string]*openpgp.Entity
+}
+
+// ParsePGPKeyRing reads ASCII armored public or private keys.
+func ParsePGPKeyRing(data []byte) (openpgp.EntityList, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse PGP key ring: %w", err)
+	}
+
+	return entities, nil
+}
+
+var pgpConfig = &packet.Config{
+	DefaultHash:   crypto.SHA256,
+	DefaultCipher: packet.CipherAES256,
+}
+
+func applyPGP(message io.WriterTo, recipients []string, options *PGPOptions) (*bytes.Buffer, error) {
+	if options.SigningKey == nil && !options.Encrypt {
+		return nil, &ValidationError{Field: "pgp", Message: "PGP signing key is required unless encrypting"}
+	}
+	if options.SigningKey != nil && (options.SigningKey.PrivateKey == nil || options.SigningKey.PrivateKey.Encrypted) {
+		return nil, &ValidationError{Field: "pgp", Message: "PGP signing key must have a decrypted private key"}
+	}
+
+	outer, entity, err := splitMIMEEntity(message)
+	if err != nil {
+		return nil, err
+	}
+
+	var protected string
+	if options.Encrypt {
+		keys := openpgp.EntityList{}
+		if options.SigningKey != nil {
+			keys = append(keys, options.SigningKey)
+		}
+		for _, recipient := range recipients {
+			key := pgpRecipientKey(options.RecipientKeys, recipient)
+			if key == nil {
+				return nil, &ValidationError{Field: "pgp", Message: "No PGP key for recipient " + recipient}
+			}
+			keys = append(keys, key)
+		}
+
+		protected, err = encryptPGPEntity(entity, keys, options.SigningKey)
+	} else {
+		protected, err = signPGPEntity(entity, options.SigningKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &bytes.Buffer{}
+	result.WriteString(outer)
+	result.WriteString(protected)
+
+	return result, nil
+}
+
+func pgpRecipientKey(keys map[string]*openpgp.Entity, recipient string) *openpgp.Entity {
+	for address, key := range keys {
+		if strings.EqualFold(address, recipient) {
+			return key
+		}
+	}
+
+	return nil
+}
+
+func signPGPEntity(entity string, signer *openpgp.Entity) (string, error) {
+	signature := &bytes.Buffer{}
+	if err := openpgp.ArmoredDetachSign(signature, signer, strings.NewReader(entity), pgpConfig); err != nil {
+		return "", fmt.Errorf("Failed to sign message: %w", err)
+	}
+
+	boundary, err := mimeBoundary()
+	if err != nil {
+		return "", err
+	}
+
+	builder := &strings.Builder{}
+	builder.WriteString("Content-Type: multipart/signed; micalg=pgp-sha256;\r\n")
+	builder.WriteString(" protocol=\"application/pgp-signature\"; boundary=\"" + boundary + "\"\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString("This is an OpenPGP/MIME signed message (RFC 3156)\r\n")
+	builder.WriteString("--" + boundary + "\r\n")
+	builder.WriteString(entity)
+	builder.WriteString("\r\n--" + boundary + "\r\n")
+	builder.WriteString("Content-Type: application/pgp-signature; name=\"signature.asc\"\r\n")
+	builder.WriteString("Content-Description: OpenPGP digital signature\r\n")
+	builder.WriteString("Content-Disposition: attachment; filename=\"signature.asc\"\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(pgpCRLF(signature.String()))
+	builder.WriteString("--" + boundary + "--\r\n")
+
+	return builder.String(), nil
+}
+
+func encryptPGPEntity(entity string, keys openpgp.EntityList, signer *openpgp.Entity) (string, error) {
+	encrypted := &bytes.Buffer{}
+	armored, err := armor.Encode(encrypted, "PGP MESSAGE", nil)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := openpgp.Encrypt(armored, keys, signer, nil, pgpConfig)
+	if err != nil {
+		return "", fmt.Errorf("Failed to encrypt message: %w", err)
+	}
+	if _, err := io.WriteString(plaintext, entity); err != nil {
+		return "", fmt.Errorf("Failed to encrypt message: %w", err)
+	}
+	if err := plaintext.Close(); err != nil {
+		return "", fmt.Errorf("Failed to encrypt message: %w", err)
+	}
+	if err := armored.Close(); err != nil {
+		return "", fmt.Errorf("Failed to encrypt message: %w", err)
+	}
+
+	boundary, err := mimeBoundary()
+	if err != nil {
+		return "", err
+	}
+
+	builder := &strings.Builder{}
+	builder.WriteString("Content-Type: multipart/encrypted; protocol=\"application/pgp-encrypted\";\r\n")
+	builder.WriteString(" boundary=\"" + boundary + "\"\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString("This is an OpenPGP/MIME encrypted message (RFC 3156)\r\n")
+	builder.WriteString("--" + boundary + "\r\n")
+	builder.WriteString("Content-Type: application/pgp-encrypted\r\n")
+	builder.WriteString("Content-Description: PGP/MIME version identification\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString("Version: 1\r\n")
+	builder.WriteString("\r\n--" + boundary + "\r\n")
+	builder.WriteString("Content-Type: application/octet-stream; name=\"encrypted.asc\"\r\n")
+	builder.WriteString("Content-Description: OpenPGP encrypted message\r\n")
+	builder.WriteString("Content-Disposition: inline; filename=\"encrypted.asc\"\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(pgpCRLF(encrypted.String()))
+	builder.WriteString("--" + boundary + "--\r\n")
+
+	return builder.String(), nil
+}
+
+func pgpCRLF(armored string) string {
+	return strings.ReplaceAll(strings.TrimRight(armored, "\n")+"\n", "\n", "\r\n")
+}
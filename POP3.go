@@ -0,0 +1,240 @@
+package messagingutilities
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// POP3Credentials picks a TLS mode from the port when TLSMode is empty:
+// implicit TLS on 995, otherwise STLS, which is always required. Set
+// TLSModeNone explicitly for local test servers.
+type POP3Credentials struct {
+	Host      string
+	Port      string
+	User      string
+	Password  string
+	TLSMode   TLSMode
+	TLSConfig *tls.Config
+}
+
+type POP3ReceiverOptions struct {
+	// KeepMessages leaves fetched messages on the server instead of
+	// deleting them.
+	KeepMessages bool
+	// PollInterval is the time between checks in Listen. It defaults to one
+	// minute.
+	PollInterval time.Duration
+}
+
+// POP3Receiver downloads the messages of a POP3 mailbox and deletes them.
+// Deletions only take effect once every message has been read, so a failed
+// fetch leaves the mailbox unchanged.
+type POP3Receiver struct {
+	Credentials *POP3Credentials
+	options     POP3ReceiverOptions
+}
+
+func NewPOP3Receiver(credentials *POP3Credentials, options POP3ReceiverOptions) *POP3Receiver {
+	if options.PollInterval <= 0 {
+		options.PollInterval = time.Minute
+	}
+
+	return &POP3Receiver{Credentials: credentials, options: options}
+}
+
+// Fetch connects, returns every message in the mailbox and logs out. The ID
+// of each message is its UIDL when the server supports it.
+func (receiver *POP3Receiver) Fetch(ctx context.Context) ([]*InboundEmail, error) {
+	conn, err := dialPOP3(ctx, receiver.Credentials)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+
+	stop := context.AfterFunc(ctx, func() { conn.close() })
+	defer stop()
+
+	lines, err := conn.command("LIST", true)
+	if err != nil {
+		return nil, mailboxContextError(ctx, err)
+	}
+
+	numbers := []int{}
+	for _, line := range lines {
+		if number, err := strconv.Atoi(strings.Fields(line + " ")[0]); err == nil {
+			numbers = append(numbers, number)
+		}
+	}
+
+	ids := map[int]string{}
+	if lines, err := conn.command("UIDL", true); err == nil {
+		for _, line := range lines {
+			fields := strings.Fields(line)
+			if len(fields) == 2 {
+				if number, err := strconv.Atoi(fields[0]); err == nil {
+					ids[number] = fields[1]
+				}
+			}
+		}
+	}
+
+	emails := []*InboundEmail{}
+	for _, number := range numbers {
+		if _, err := conn.command("RETR "+strconv.Itoa(number), false); err != nil {
+			return nil, mailboxContextError(ctx, err)
+		}
+		raw, err := conn.text.ReadDotBytes()
+		if err != nil {
+			return nil, mailboxContextError(ctx, err)
+		}
+
+		email := parseMailboxEmail(raw)
+		email.ID = ids[number]
+		if email.ID == "" {
+			email.ID = strconv.Itoa(number)
+		}
+		emails = append(emails, email)
+	}
+
+	if !receiver.options.KeepMessages {
+		for _, number := range numbers {
+			if _, err := conn.command("DELE "+strconv.Itoa(number), false); err != nil {
+				return nil, mailboxContextError(ctx, err)
+			}
+		}
+	}
+
+	// The server only deletes messages once the session ends with QUIT.
+	if _, err := conn.command("QUIT", false); err != nil {
+		return nil, mailboxContextError(ctx, err)
+	}
+
+	return emails, nil
+}
+
+// Listen polls the mailbox and delivers messages to messages until ctx is
+// done or a fetch fails.
+func (receiver *POP3Receiver) Listen(ctx context.Context, messages chan<- *InboundEmail) error {
+	for {
+		emails, err := receiver.Fetch(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, email := range emails {
+			select {
+			case messages <- email:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		select {
+		case <-time.After(receiver.options.PollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+type pop3Conn struct {
+	conn net.Conn
+	text *textproto.Conn
+}
+
+func dialPOP3(ctx context.Context, credentials *POP3Credentials) (*pop3Conn, error) {
+	port, err := strconv.Atoi(credentials.Port)
+	if err != nil {
+		return nil, &ValidationError{Field: "port", Message: "Invalid POP3 port " + credentials.Port}
+	}
+
+	mode, tlsConfig, err := mailboxTLS(credentials.TLSMode, port, 995, credentials.Host, credentials.TLSConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	netConn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(credentials.Host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	if mode == TLSModeImplicit {
+		netConn = tls.Client(netConn, tlsConfig)
+	}
+
+	stop := context.AfterFunc(ctx, func() { netConn.Close() })
+	defer stop()
+
+	conn := &pop3Conn{conn: netConn, text: textproto.NewConn(netConn)}
+	netConn.SetDeadline(time.Now().Add(mailboxCommandTimeout))
+	if _, err := conn.response(false); err != nil {
+		conn.close()
+		return nil, mailboxContextError(ctx, err)
+	}
+
+	if mode == TLSModeSTARTTLS {
+		if _, err := conn.command("STLS", false); err != nil {
+			conn.close()
+			return nil, fmt.Errorf("POP3 server %s does not support STLS: %w", credentials.Host, err)
+		}
+		conn.conn = tls.Client(netConn, tlsConfig)
+		conn.text = textproto.NewConn(conn.conn)
+	}
+
+	if _, err := conn.command("USER "+credentials.User, false); err != nil {
+		conn.close()
+		return nil, mailboxContextError(ctx, err)
+	}
+	if _, err := conn.command("PASS "+credentials.Password, false); err != nil {
+		conn.close()
+		return nil, mailboxContextError(ctx, err)
+	}
+
+	return conn, nil
+}
+
+// command sends command and reads its status line, followed by the lines of
+// a multi-line response when multiline is set.
+func (conn *pop3Conn) command(command string, multiline bool) ([]string, error) {
+	if strings.ContainsAny(command, "\r\n") {
+		return nil, fmt.Errorf("POP3 command contains a line break")
+	}
+
+	conn.conn.SetDeadline(time.Now().Add(mailboxCommandTimeout))
+	if err := conn.text.PrintfLine("%s", command); err != nil {
+		return nil, err
+	}
+
+	name, _, _ := strings.Cut(command, " ")
+	lines, err := conn.response(multiline)
+	if err != nil {
+		return nil, fmt.Errorf("POP3 %s failed: %w", name, err)
+	}
+
+	return lines, nil
+}
+
+func (conn *pop3Conn) response(multiline bool) ([]string, error) {
+	status, err := conn.text.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(status, "+OK") {
+		return nil, fmt.Errorf("%s", status)
+	}
+	if !multiline {
+		return nil, nil
+	}
+
+	return conn.text.ReadDotLines()
+}
+
+func (conn *pop3Conn) close() error {
+	return conn.conn.Close()
+}
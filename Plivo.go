@@ -0,0 +1,114 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// PlivoCredentials sends from either SourceNumber or a Powerpack, which
+// picks a number from its pool for each destination. Exactly one of the two
+// must be set.
+type PlivoCredentials struct {
+	AuthID        string
+	AuthToken     string
+	SourceNumber  string
+	PowerpackUUID string
+}
+
+type PlivoSender struct {
+	Credentials *PlivoCredentials
+	BaseURL     string
+}
+
+func NewPlivoSender(credentials *PlivoCredentials) *PlivoSender {
+	return &PlivoSender{
+		Credentials: credentials,
+		BaseURL:     "https://api.plivo.com",
+	}
+}
+
+type plivoRequest struct {
+	Source        string `json:"src,omitempty"`
+	PowerpackUUID string `json:"powerpack_uuid,omitempty"`
+	Destination   string `json:"dst"`
+	Text          string `json:"text"`
+}
+
+type plivoResponse struct {
+	APIID       string   `json:"api_id"`
+	Message     string   `json:"message"`
+	MessageUUID []string `json:"message_uuid"`
+	Error       string   `json:"error"`
+}
+
+func (sender *PlivoSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	credentials := sender.Credentials
+	if (credentials.SourceNumber == "") == (credentials.PowerpackUUID == "") {
+		return nil, &ValidationError{Field: "sender", Message: "Exactly one of a source number and a Powerpack is required"}
+	}
+
+	return sendEachSms(ctx, "plivo", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sender.send(ctx, text, receiver)
+	})
+}
+
+func (sender *PlivoSender) send(ctx context.Context, text, receiver string) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("plivo", "sms", err) }()
+
+	// Plivo treats "<" as a separator between several destinations.
+	if strings.Contains(receiver, "<") {
+		return result, &ValidationError{Field: "receiver", Message: "Invalid phone number " + receiver}
+	}
+
+	body, err := json.Marshal(plivoRequest{
+		Source:        sender.Credentials.SourceNumber,
+		PowerpackUUID: sender.Credentials.PowerpackUUID,
+		Destination:   receiver,
+		Text:          text,
+	})
+	if err != nil {
+		return result, fmt.Errorf("Failed to encode Plivo request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		strings.TrimSuffix(sender.BaseURL, "/")+"/v1/Account/"+url.PathEscape(sender.Credentials.AuthID)+"/Message/",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return result, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	request.SetBasicAuth(sender.Credentials.AuthID, sender.Credentials.AuthToken)
+
+	_, responseBody, err := doProviderRequest("plivo", "Plivo", request)
+	result.Raw = string(responseBody)
+	if err != nil {
+		return result, err
+	}
+
+	var plivoResp plivoResponse
+	if err := json.Unmarshal(responseBody, &plivoResp); err != nil {
+		return result, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	if plivoResp.Error != "" {
+		return result, fmt.Errorf("Plivo rejected the message: %s", plivoResp.Error)
+	}
+	if len(plivoResp.MessageUUID) == 0 {
+		return result, fmt.Errorf("Plivo did not return a message id")
+	}
+
+	result.MessageID = plivoResp.MessageUUID[0]
+	result.Status = "queued"
+
+	return result, nil
+}
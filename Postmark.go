@@ -0,0 +1,164 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type PostmarkCredentials struct {
+	ServerToken   string
+	Sender        string
+	MessageStream string
+}
+
+type PostmarkSender struct {
+	Credentials *PostmarkCredentials
+	BaseURL     string
+}
+
+func NewPostmarkSender(credentials *PostmarkCredentials) *PostmarkSender {
+	return &PostmarkSender{
+		Credentials: credentials,
+		BaseURL:     "https://api.postmarkapp.com",
+	}
+}
+
+type postmarkAttachment struct {
+	Name        string `json:"Name"`
+	Content     string `json:"Content"`
+	ContentType string `json:"ContentType"`
+	ContentID   string `json:"ContentID,omitempty"`
+}
+
+type postmarkHeader struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
+type postmarkRequest struct {
+	From          string               `json:"From"`
+	To            string               `json:"To"`
+	Cc            string               `json:"Cc,omitempty"`
+	Bcc           string               `json:"Bcc,omitempty"`
+	ReplyTo       string               `json:"ReplyTo,omitempty"`
+	Subject       string               `json:"Subject,omitempty"`
+	HtmlBody      string               `json:"HtmlBody,omitempty"`
+	TextBody      string               `json:"TextBody,omitempty"`
+	Tag           string               `json:"Tag,omitempty"`
+	Metadata      map[string]string    `json:"Metadata,omitempty"`
+	MessageStream string               `json:"MessageStream,omitempty"`
+	Headers       []postmarkHeader     `json:"Headers,omitempty"`
+	Attachments   []postmarkAttachment `json:"Attachments,omitempty"`
+}
+
+type postmarkResponse struct {
+	To        string `json:"To"`
+	MessageID string `json:"MessageID"`
+	ErrorCode int    `json:"ErrorCode"`
+	Message   string `json:"Message"`
+}
+
+func (sender *PostmarkSender) Send(ctx context.Context, message *Message) (result *SendResult, err error) {
+	defer func() { DefaultStats.RecordResult("postmark", "email", err) }()
+
+	message, err = suppressRecipients(ctx, ChannelEmail, message)
+	if err != nil {
+		return nil, err
+	}
+
+	email, err := prepareEmail(sender.Credentials.Sender, message)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := postmarkRequest{
+		From:          email.From,
+		To:            strings.Join(email.To, ","),
+		Cc:            strings.Join(email.Cc, ","),
+		Bcc:           strings.Join(email.Bcc, ","),
+		ReplyTo:       email.ReplyTo,
+		Subject:       email.Subject,
+		Metadata:      email.Metadata,
+		MessageStream: sender.Credentials.MessageStream,
+	}
+
+	for name, values := range email.Headers {
+		for _, value := range values {
+			payload.Headers = append(payload.Headers, postmarkHeader{Name: name, Value: value})
+		}
+	}
+
+	if email.AMP != "" {
+		return nil, &ValidationError{Field: "amp", Message: "Postmark does not support AMP parts"}
+	}
+	payload.TextBody = email.Text
+	payload.HtmlBody = email.HTML
+
+	// Postmark accepts a single tag per message.
+	if email.Category != "" {
+		payload.Tag = string(email.Category)
+	} else if len(email.Tags) > 0 {
+		payload.Tag = email.Tags[0]
+	}
+
+	attachments, err := email.readAttachments()
+	if err != nil {
+		return nil, err
+	}
+	for _, attachment := range attachments {
+		postmarkAttach := postmarkAttachment{
+			Name:        attachment.Name,
+			Content:     base64.StdEncoding.EncodeToString(attachment.Data),
+			ContentType: attachment.ContentType,
+		}
+		if attachment.Inline {
+			postmarkAttach.ContentID = "cid:" + attachment.ContentID
+		}
+		payload.Attachments = append(payload.Attachments, postmarkAttach)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode Postmark request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		strings.TrimSuffix(sender.BaseURL, "/")+"/email",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("X-Postmark-Server-Token", sender.Credentials.ServerToken)
+
+	_, responseBody, err := doProviderRequest("postmark", "Postmark", request)
+	if err != nil {
+		return nil, err
+	}
+
+	var postmarkResp postmarkResponse
+	if err := json.Unmarshal(responseBody, &postmarkResp); err != nil {
+		return nil, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	if postmarkResp.ErrorCode != 0 {
+		return nil, fmt.Errorf("Postmark rejected the message (%d): %s", postmarkResp.ErrorCode, postmarkResp.Message)
+	}
+
+	return &SendResult{
+		Provider:   "postmark",
+		MessageID:  postmarkResp.MessageID,
+		Recipients: email.recipients(),
+		Raw:        string(responseBody),
+	}, nil
+}
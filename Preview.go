@@ -0,0 +1,264 @@
+package messagingutilities
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+type SmsEncoding string
+
+const (
+	SmsEncodingGSM7 SmsEncoding = "GSM-7"
+	SmsEncodingUCS2 SmsEncoding = "UCS-2"
+)
+
+const gsm7BasicCharacters = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+
+const gsm7ExtensionCharacters = "\f^{}\\[~]|€"
+
+type SmsSegmentInfo struct {
+	Encoding        SmsEncoding `json:"encoding"`
+	Units           int         `json:"units"`
+	Segments        int         `json:"segments"`
+	UnitsPerSegment int         `json:"unitsPerSegment"`
+}
+
+func AnalyzeSmsSegments(text string) SmsSegmentInfo {
+	septets := 0
+	isGSM7 := true
+	for _, character := range text {
+		switch {
+		case strings.ContainsRune(gsm7BasicCharacters, character):
+			septets++
+		case strings.ContainsRune(gsm7ExtensionCharacters, character):
+			septets += 2
+		default:
+			isGSM7 = false
+		}
+		if !isGSM7 {
+			break
+		}
+	}
+
+	info := SmsSegmentInfo{
+		Encoding: SmsEncodingGSM7,
+		Units:    septets,
+	}
+	single, multiple := 160, 153
+	if !isGSM7 {
+		info.Encoding = SmsEncodingUCS2
+		info.Units = len(utf16.Encode([]rune(text)))
+		single, multiple = 70, 67
+	}
+
+	switch {
+	case info.Units == 0:
+		info.Segments = 0
+		info.UnitsPerSegment = single
+	case info.Units <= single:
+		info.Segments = 1
+		info.UnitsPerSegment = single
+	default:
+		info.Segments = (info.Units + multiple - 1) / multiple
+		info.UnitsPerSegment = multiple
+	}
+
+	return info
+}
+
+func prepareSmsText(message *string) (string, error) {
+	if message == nil || *message == "" {
+		return "", &ValidationError{Field: "message", Message: "Message body cannot be empty"}
+	}
+
+	return *message, nil
+}
+
+type SmsPreview struct {
+	Text          string      `json:"text"`
+	Encoding      SmsEncoding `json:"encoding"`
+	Segments      int         `json:"segments"`
+	Units         int         `json:"units"`
+	EstimatedCost float64     `json:"estimatedCost"`
+}
+
+func PreviewSmsMessage(message *string, costPerSegment float64) (*SmsPreview, error) {
+	text, err := prepareSmsText(message)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := AnalyzeSmsSegments(text)
+
+	return &SmsPreview{
+		Text:          text,
+		Encoding:      segments.Encoding,
+		Segments:      segments.Segments,
+		Units:         segments.Units,
+		EstimatedCost: float64(segments.Segments) * costPerSegment,
+	}, nil
+}
+
+type AttachmentManifestEntry struct {
+	Name        string `json:"name"`
+	ContentType string `json:"contentType"`
+	ContentID   string `json:"contentId,omitempty"`
+}
+
+type EmailPreview struct {
+	From        string                    `json:"from"`
+	To          []string                  `json:"to"`
+	Cc          []string                  `json:"cc,omitempty"`
+	Bcc         []string                  `json:"bcc,omitempty"`
+	ReplyTo     string                    `json:"replyTo,omitempty"`
+	Subject     string                    `json:"subject"`
+	HTML        string                    `json:"html,omitempty"`
+	AMP         string                    `json:"amp,omitempty"`
+	Plain       string                    `json:"plain,omitempty"`
+	Attachments []AttachmentManifestEntry `json:"attachments"`
+}
+
+func PreviewSMTPEmailMessage(
+	credentials *SMTPCredentials,
+	subject,
+	message *string,
+	isHtml bool,
+	attachments *[]EmailAttachment,
+	receivers *[]string,
+) (*EmailPreview, error) {
+	return PreviewEmail(credentials, legacyEmailMessage(subject, message, isHtml, attachments, receivers))
+}
+
+// PreviewEmail shows the email as SMTPSender would send it. Options that
+// change the content, such as WithInlineCSS or WithClickTracking, are applied
+// as Send applies them.
+func PreviewEmail(credentials *SMTPCredentials, message *Message, options ...SendOption) (*EmailPreview, error) {
+	settings := newSendOptions(options)
+	message, err := settings.prepareMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	email, err := prepareEmail(credentials.Sender, message)
+	if err != nil {
+		return nil, err
+	}
+
+	return email.preview(), nil
+}
+
+func (email *preparedEmail) preview() *EmailPreview {
+	preview := &EmailPreview{
+		From:        email.From,
+		To:          email.To,
+		Cc:          email.Cc,
+		Bcc:         email.Bcc,
+		ReplyTo:     email.ReplyTo,
+		Subject:     email.Subject,
+		Attachments: []AttachmentManifestEntry{},
+	}
+
+	preview.Plain = email.Text
+	preview.HTML = email.HTML
+	preview.AMP = email.AMP
+
+	for _, attachment := range email.Attachments {
+		preview.Attachments = append(preview.Attachments, AttachmentManifestEntry{
+			Name:        *attachment.Name,
+			ContentType: attachment.contentType(),
+		})
+	}
+
+	for _, attachment := range email.Inline {
+		preview.Attachments = append(preview.Attachments, AttachmentManifestEntry{
+			Name:        attachment.Name,
+			ContentType: inlineContentType(attachment.Name),
+			ContentID:   attachment.ContentID,
+		})
+	}
+
+	if email.Calendar != "" {
+		preview.Attachments = append(preview.Attachments, AttachmentManifestEntry{
+			Name:        "invite.ics",
+			ContentType: email.CalendarMethod.contentType(),
+		})
+	}
+
+	return preview
+}
+
+// RenderEML builds the complete message as SMTPSender would send it,
+// including any DKIM, S/MIME or PGP processing, without connecting. Options
+// are applied as in PreviewEmail.
+func RenderEML(credentials *SMTPCredentials, message *Message, options ...SendOption) ([]byte, error) {
+	settings := newSendOptions(options)
+	message, err := settings.prepareMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	_, eml, err := renderEML(credentials, message)
+	return eml, err
+}
+
+func renderEML(credentials *SMTPCredentials, message *Message) (*preparedEmail, []byte, error) {
+	email, err := prepareEmail(credentials.Sender, message)
+	if err != nil {
+		return nil, nil, err
+	}
+	email.assignMessageID(credentials.MessageIDDomain)
+
+	rendered, err := renderSMTPMessage(credentials, email)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return email, rendered.data, nil
+}
+
+// DryRunSender writes each message to a new .eml file in Dir instead of
+// sending it, so it can be opened in a mail client. The file path is
+// returned as the message ID.
+type DryRunSender struct {
+	Credentials *SMTPCredentials
+	Dir         string
+}
+
+func NewDryRunSender(credentials *SMTPCredentials, dir string) *DryRunSender {
+	return &DryRunSender{
+		Credentials: credentials,
+		Dir:         dir,
+	}
+}
+
+func (sender *DryRunSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	email, eml, err := renderEML(sender.Credentials, message)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp(sender.Dir, "message-*.eml")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create eml file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(eml); err != nil {
+		return nil, fmt.Errorf("Failed to write eml file: %w", err)
+	}
+
+	return &SendResult{
+		Provider:          "dryrun",
+		MessageID:         file.Name(),
+		InternetMessageID: email.MessageID,
+		Recipients:        email.recipients(),
+	}, nil
+}
@@ -0,0 +1,43 @@
+package messagingutilities
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+func newProviderHTTPClient(provider string) *http.Client {
+	return debugHTTPClient(provider, &http.Client{Timeout: 30 * time.Second})
+}
+
+// doProviderRequest executes request and returns the response body. Rate
+// limiting is reported as ErrRateLimited and any other non-2xx status as an
+// error carrying the body.
+func doProviderRequest(provider, name string, request *http.Request) (*http.Response, []byte, error) {
+	response, err := newProviderHTTPClient(provider).Do(request)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to execute http request: %w", err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return response, nil, fmt.Errorf("Failed to read http response: %w", err)
+	}
+
+	if response.StatusCode == http.StatusTooManyRequests {
+		return response, body, fmt.Errorf("%w: %s API returned status %d", ErrRateLimited, name, response.StatusCode)
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response, body, fmt.Errorf(
+			"%s API failed with status %d. Response body: %s",
+			name,
+			response.StatusCode,
+			string(body),
+		)
+	}
+
+	return response, body, nil
+}
@@ -0,0 +1,143 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type ResendCredentials struct {
+	ApiKey string
+	Sender string
+}
+
+type ResendSender struct {
+	Credentials *ResendCredentials
+	BaseURL     string
+}
+
+func NewResendSender(credentials *ResendCredentials) *ResendSender {
+	return &ResendSender{
+		Credentials: credentials,
+		BaseURL:     "https://api.resend.com",
+	}
+}
+
+type resendAttachment struct {
+	Filename    string `json:"filename"`
+	Content     string `json:"content"`
+	ContentType string `json:"content_type,omitempty"`
+	ContentID   string `json:"content_id,omitempty"`
+}
+
+type resendTag struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type resendRequest struct {
+	From        string             `json:"from"`
+	To          []string           `json:"to"`
+	Cc          []string           `json:"cc,omitempty"`
+	Bcc         []string           `json:"bcc,omitempty"`
+	ReplyTo     string             `json:"reply_to,omitempty"`
+	Subject     string             `json:"subject"`
+	HTML        string             `json:"html,omitempty"`
+	Text        string             `json:"text,omitempty"`
+	Attachments []resendAttachment `json:"attachments,omitempty"`
+	Tags        []resendTag        `json:"tags,omitempty"`
+	Headers     map[string]string  `json:"headers,omitempty"`
+}
+
+type resendResponse struct {
+	ID string `json:"id"`
+}
+
+func (sender *ResendSender) Send(ctx context.Context, message *Message) (result *SendResult, err error) {
+	defer func() { DefaultStats.RecordResult("resend", "email", err) }()
+
+	message, err = suppressRecipients(ctx, ChannelEmail, message)
+	if err != nil {
+		return nil, err
+	}
+
+	email, err := prepareEmail(sender.Credentials.Sender, message)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := resendRequest{
+		From:    email.From,
+		To:      email.To,
+		Cc:      email.Cc,
+		Bcc:     email.Bcc,
+		ReplyTo: email.ReplyTo,
+		Subject: email.Subject,
+		Headers: email.flatHeaders(),
+	}
+
+	if email.AMP != "" {
+		return nil, &ValidationError{Field: "amp", Message: "Resend does not support AMP parts"}
+	}
+	payload.Text = email.Text
+	payload.HTML = email.HTML
+
+	if email.Category != "" {
+		payload.Tags = append(payload.Tags, resendTag{Name: "category", Value: string(email.Category)})
+	}
+	for key, value := range email.Metadata {
+		payload.Tags = append(payload.Tags, resendTag{Name: key, Value: value})
+	}
+
+	attachments, err := email.readAttachments()
+	if err != nil {
+		return nil, err
+	}
+	for _, attachment := range attachments {
+		payload.Attachments = append(payload.Attachments, resendAttachment{
+			Filename:    attachment.Name,
+			Content:     base64.StdEncoding.EncodeToString(attachment.Data),
+			ContentType: attachment.ContentType,
+			ContentID:   attachment.ContentID,
+		})
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode Resend request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		strings.TrimSuffix(sender.BaseURL, "/")+"/emails",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer "+sender.Credentials.ApiKey)
+
+	_, responseBody, err := doProviderRequest("resend", "Resend", request)
+	if err != nil {
+		return nil, err
+	}
+
+	var resendResp resendResponse
+	if err := json.Unmarshal(responseBody, &resendResp); err != nil {
+		return nil, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	return &SendResult{
+		Provider:   "resend",
+		MessageID:  resendResp.ID,
+		Recipients: email.recipients(),
+		Raw:        string(responseBody),
+	}, nil
+}
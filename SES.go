@@ -0,0 +1,167 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+	"github.com/aws/smithy-go"
+)
+
+// SESCredentials leaves AccessKeyID empty to use the default AWS credential
+// chain (environment, shared config, or an attached IAM role).
+type SESCredentials struct {
+	Region           string
+	AccessKeyID      string
+	SecretAccessKey  string
+	SessionToken     string
+	Sender           string
+	ConfigurationSet string
+}
+
+type SESSender struct {
+	Credentials *SESCredentials
+	client      *sesv2.Client
+}
+
+func NewSESSender(credentials_ *SESCredentials) (*SESSender, error) {
+	options := []func(*config.LoadOptions) error{
+		config.WithRegion(credentials_.Region),
+	}
+	if credentials_.AccessKeyID != "" {
+		options = append(options, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			credentials_.AccessKeyID,
+			credentials_.SecretAccessKey,
+			credentials_.SessionToken,
+		)))
+	}
+
+	awsConfig, err := config.LoadDefaultConfig(context.Background(), options...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load AWS configuration: %w", err)
+	}
+
+	return &SESSender{
+		Credentials: credentials_,
+		client:      sesv2.NewFromConfig(awsConfig),
+	}, nil
+}
+
+func (sender *SESSender) Send(ctx context.Context, message *Message) (result *SendResult, err error) {
+	defer func() { DefaultStats.RecordResult("ses", "email", err) }()
+
+	message, err = suppressRecipients(ctx, ChannelEmail, message)
+	if err != nil {
+		return nil, err
+	}
+
+	email, err := prepareEmail(sender.Credentials.Sender, message)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &sesv2.SendEmailInput{
+		FromEmailAddress: aws.String(email.From),
+		Destination: &types.Destination{
+			ToAddresses:  email.To,
+			CcAddresses:  email.Cc,
+			BccAddresses: email.Bcc,
+		},
+	}
+	if email.ReplyTo != "" {
+		input.ReplyToAddresses = []string{email.ReplyTo}
+	}
+	if sender.Credentials.ConfigurationSet != "" {
+		input.ConfigurationSetName = aws.String(sender.Credentials.ConfigurationSet)
+	}
+
+	if email.Category != "" {
+		input.EmailTags = append(input.EmailTags, types.MessageTag{
+			Name:  aws.String("category"),
+			Value: aws.String(string(email.Category)),
+		})
+	}
+	for key, value := range email.Metadata {
+		input.EmailTags = append(input.EmailTags, types.MessageTag{
+			Name:  aws.String(key),
+			Value: aws.String(value),
+		})
+	}
+
+	if len(email.Attachments) > 0 || len(email.Inline) > 0 || email.Calendar != "" || email.AMP != "" {
+		raw := &bytes.Buffer{}
+		if _, err := email.gomailMessage().WriteTo(raw); err != nil {
+			return nil, fmt.Errorf("Failed to build raw message: %w", err)
+		}
+		input.Content = &types.EmailContent{Raw: &types.RawMessage{Data: raw.Bytes()}}
+	} else {
+		body := &types.Body{}
+		if email.Text != "" {
+			body.Text = &types.Content{Data: aws.String(email.Text), Charset: aws.String("UTF-8")}
+		}
+		if email.HTML != "" {
+			body.Html = &types.Content{Data: aws.String(email.HTML), Charset: aws.String("UTF-8")}
+		}
+		simple := &types.Message{
+			Subject: &types.Content{Data: aws.String(email.Subject), Charset: aws.String("UTF-8")},
+			Body:    body,
+		}
+		for name, values := range email.Headers {
+			for _, value := range values {
+				simple.Headers = append(simple.Headers, types.MessageHeader{
+					Name:  aws.String(name),
+					Value: aws.String(value),
+				})
+			}
+		}
+		input.Content = &types.EmailContent{Simple: simple}
+	}
+
+	output, err := sender.client.SendEmail(ctx, input, func(options *sesv2.Options) {
+		if activeDebugDumper.Load() != nil {
+			options.HTTPClient = debugHTTPClient("ses", &http.Client{
+				Transport: &httpDoerTransport{doer: options.HTTPClient},
+			})
+		}
+	})
+	if err != nil {
+		return nil, wrapSESError(err)
+	}
+
+	return &SendResult{
+		Provider:   "ses",
+		MessageID:  aws.ToString(output.MessageId),
+		Recipients: email.recipients(),
+	}, nil
+}
+
+// httpDoerTransport adapts the SDK's HTTP client so the debug transport can
+// wrap it without replacing its TLS and transport configuration.
+type httpDoerTransport struct {
+	doer interface {
+		Do(request *http.Request) (*http.Response, error)
+	}
+}
+
+func (transport *httpDoerTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	return transport.doer.Do(request)
+}
+
+func wrapSESError(err error) error {
+	var apiError smithy.APIError
+	if errors.As(err, &apiError) {
+		switch apiError.ErrorCode() {
+		case "TooManyRequestsException", "LimitExceededException", "Throttling":
+			return fmt.Errorf("%w: %w", ErrRateLimited, err)
+		}
+	}
+
+	return err
+}
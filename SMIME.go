@@ -0,0 +1,461 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	oidCMSData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidCMSSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidCMSEnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+	oidCMSContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidCMSMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidCMSSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidSHA256           = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSAEncryption    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidECDSAWithSHA256  = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidAES256CBC        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	cmsNullParameters   = asn1.RawValue{Tag: asn1.TagNull}
+)
+
+const smimeBase64LineWidth = 76
+
+// SMIMEOptions signs messages with Certificate and PrivateKey, including
+// Intermediates so recipients can build the chain. When Encrypt is set the
+// signed message is also encrypted to every recipient's entry in
+// RecipientCertificates, keyed by address, and to Certificate so the sender
+// can read its own copy; sending fails if a recipient has no certificate.
+// Signing supports RSA and ECDSA keys, encryption RSA certificates only.
+type SMIMEOptions struct {
+	Certificate           *x509.Certificate
+	PrivateKey            crypto.Signer
+	Intermediates         []*x509.Certificate
+	Encrypt               bool
+	RecipientCertificates map[string]*x509.Certificate
+}
+
+func ParseSMIMECertificates(data []byte) ([]*x509.Certificate, error) {
+	certificates := []*x509.Certificate{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse S/MIME certificate: %w", err)
+		}
+		certificates = append(certificates, certificate)
+	}
+
+	if len(certificates) == 0 {
+		return nil, errors.New("No PEM encoded S/MIME certificates found")
+	}
+
+	return certificates, nil
+}
+
+func ParseSMIMEPrivateKey(data []byte) (crypto.Signer, error) {
+	return parsePEMPrivateKey(data, "S/MIME")
+}
+
+type cmsIssuerAndSerial struct {
+	Issuer asn1.RawValue
+	Serial *big.Int
+}
+
+type cmsAlgorithm struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+type cmsAttribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
+
+type cmsSignerInfo struct {
+	Version            int
+	Signer             cmsIssuerAndSerial
+	DigestAlgorithm    cmsAlgorithm
+	SignedAttributes   asn1.RawValue
+	SignatureAlgorithm cmsAlgorithm
+	Signature          []byte
+}
+
+type cmsEncapsulatedContent struct {
+	ContentType asn1.ObjectIdentifier
+}
+
+type cmsSignedData struct {
+	Version          int
+	DigestAlgorithms []cmsAlgorithm `asn1:"set"`
+	Content          cmsEncapsulatedContent
+	Certificates     asn1.RawValue
+	SignerInfos      []cmsSignerInfo `asn1:"set"`
+}
+
+type cmsRecipientInfo struct {
+	Version                int
+	Recipient              cmsIssuerAndSerial
+	KeyEncryptionAlgorithm cmsAlgorithm
+	EncryptedKey           []byte
+}
+
+type cmsEncryptedContent struct {
+	ContentType      asn1.ObjectIdentifier
+	Algorithm        cmsAlgorithm
+	EncryptedContent asn1.RawValue
+}
+
+type cmsEnvelopedData struct {
+	Version          int
+	RecipientInfos   []cmsRecipientInfo `asn1:"set"`
+	EncryptedContent cmsEncryptedContent
+}
+
+type cmsContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+// applySMIME signs and optionally encrypts a message. Addressing headers stay
+// outside the protected entity so the message can still be delivered.
+func applySMIME(message io.WriterTo, recipients []string, options *SMIMEOptions) (*bytes.Buffer, error) {
+	if options.Certificate == nil || options.PrivateKey == nil {
+		return nil, &ValidationError{Field: "smime", Message: "S/MIME certificate and private key are required"}
+	}
+
+	outer, entity, err := splitMIMEEntity(message)
+	if err != nil {
+		return nil, err
+	}
+
+	signed, err := signSMIMEEntity(entity, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Encrypt {
+		certificates := []*x509.Certificate{options.Certificate}
+		for _, recipient := range recipients {
+			certificate := smimeRecipientCertificate(options.RecipientCertificates, recipient)
+			if certificate == nil {
+				return nil, &ValidationError{Field: "smime", Message: "No S/MIME certificate for recipient " + recipient}
+			}
+			certificates = append(certificates, certificate)
+		}
+
+		if signed, err = encryptSMIMEEntity(signed, certificates); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &bytes.Buffer{}
+	result.WriteString(outer)
+	result.WriteString(signed)
+
+	return result, nil
+}
+
+// splitMIMEEntity separates the message headers that have to stay visible
+// from the Content-* headers and body that form the entity being protected.
+func splitMIMEEntity(message io.WriterTo) (string, string, error) {
+	raw := &bytes.Buffer{}
+	if _, err := message.WriteTo(raw); err != nil {
+		return "", "", err
+	}
+
+	content := strings.ReplaceAll(strings.ReplaceAll(raw.String(), "\r\n", "\n"), "\n", "\r\n")
+	headerBlock, body, _ := strings.Cut(content, "\r\n\r\n")
+
+	outer := &strings.Builder{}
+	entity := &strings.Builder{}
+	target := outer
+	for _, line := range strings.Split(headerBlock, "\r\n") {
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			target = outer
+			if strings.HasPrefix(strings.ToLower(line), "content-") {
+				target = entity
+			}
+		}
+		target.WriteString(line + "\r\n")
+	}
+	entity.WriteString("\r\n")
+	entity.WriteString(body)
+
+	return outer.String(), entity.String(), nil
+}
+
+func smimeRecipientCertificate(certificates map[string]*x509.Certificate, recipient string) *x509.Certificate {
+	for address, certificate := range certificates {
+		if strings.EqualFold(address, recipient) {
+			return certificate
+		}
+	}
+
+	return nil
+}
+
+func signSMIMEEntity(entity string, options *SMIMEOptions) (string, error) {
+	var signatureAlgorithm cmsAlgorithm
+	switch options.PrivateKey.Public().(type) {
+	case *rsa.PublicKey:
+		signatureAlgorithm = cmsAlgorithm{Algorithm: oidRSAEncryption, Parameters: cmsNullParameters}
+	case *ecdsa.PublicKey:
+		signatureAlgorithm = cmsAlgorithm{Algorithm: oidECDSAWithSHA256}
+	default:
+		return "", errors.New("S/MIME private key type is not supported")
+	}
+
+	digest := sha256.Sum256([]byte(entity))
+	attributes, err := cmsSignedAttributes(digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	hashed := sha256.Sum256(attributes.FullBytes)
+	signature, err := options.PrivateKey.Sign(rand.Reader, hashed[:], crypto.SHA256)
+	if err != nil {
+		return "", fmt.Errorf("Failed to sign message: %w", err)
+	}
+
+	certificates := append([]byte{}, options.Certificate.Raw...)
+	for _, intermediate := range options.Intermediates {
+		certificates = append(certificates, intermediate.Raw...)
+	}
+
+	// The attributes are signed as a SET but embedded with an implicit [0].
+	attributes.FullBytes = nil
+	attributes.Class = asn1.ClassContextSpecific
+	attributes.Tag = 0
+
+	signedData, err := asn1.Marshal(cmsSignedData{
+		Version:          1,
+		DigestAlgorithms: []cmsAlgorithm{{Algorithm: oidSHA256}},
+		Content:          cmsEncapsulatedContent{ContentType: oidCMSData},
+		Certificates: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      certificates,
+		},
+		SignerInfos: []cmsSignerInfo{{
+			Version: 1,
+			Signer: cmsIssuerAndSerial{
+				Issuer: asn1.RawValue{FullBytes: options.Certificate.RawIssuer},
+				Serial: options.Certificate.SerialNumber,
+			},
+			DigestAlgorithm:    cmsAlgorithm{Algorithm: oidSHA256},
+			SignedAttributes:   attributes,
+			SignatureAlgorithm: signatureAlgorithm,
+			Signature:          signature,
+		}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode S/MIME signature: %w", err)
+	}
+
+	signatureInfo, err := asn1.Marshal(cmsContentInfo{
+		ContentType: oidCMSSignedData,
+		Content:     cmsExplicitContent(signedData),
+	})
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode S/MIME signature: %w", err)
+	}
+
+	boundary, err := mimeBoundary()
+	if err != nil {
+		return "", err
+	}
+
+	builder := &strings.Builder{}
+	builder.WriteString("Content-Type: multipart/signed; protocol=\"application/pkcs7-signature\";\r\n")
+	builder.WriteString(" micalg=sha-256; boundary=\"" + boundary + "\"\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString("This is an S/MIME signed message\r\n")
+	builder.WriteString("--" + boundary + "\r\n")
+	builder.WriteString(entity)
+	builder.WriteString("\r\n--" + boundary + "\r\n")
+	builder.WriteString("Content-Type: application/pkcs7-signature; name=\"smime.p7s\"\r\n")
+	builder.WriteString("Content-Transfer-Encoding: base64\r\n")
+	builder.WriteString("Content-Disposition: attachment; filename=\"smime.p7s\"\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(wrapBase64(signatureInfo))
+	builder.WriteString("--" + boundary + "--\r\n")
+
+	return builder.String(), nil
+}
+
+// cmsSignedAttributes returns the DER SET of signed attributes, which is
+// what the signature covers.
+func cmsSignedAttributes(digest []byte) (asn1.RawValue, error) {
+	values := []struct {
+		oid   asn1.ObjectIdentifier
+		value any
+	}{
+		{oidCMSContentType, oidCMSData},
+		{oidCMSSigningTime, time.Now().UTC()},
+		{oidCMSMessageDigest, digest},
+	}
+
+	encoded := [][]byte{}
+	for _, attribute := range values {
+		value, err := asn1.Marshal(attribute.value)
+		if err != nil {
+			return asn1.RawValue{}, fmt.Errorf("Failed to encode S/MIME attributes: %w", err)
+		}
+
+		der, err := asn1.Marshal(cmsAttribute{
+			Type:   attribute.oid,
+			Values: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: value},
+		})
+		if err != nil {
+			return asn1.RawValue{}, fmt.Errorf("Failed to encode S/MIME attributes: %w", err)
+		}
+		encoded = append(encoded, der)
+	}
+
+	// DER requires the members of a SET OF to be sorted by their encoding.
+	sort.Slice(encoded, func(i, j int) bool { return bytes.Compare(encoded[i], encoded[j]) < 0 })
+
+	attributes := asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: bytes.Join(encoded, nil)}
+	der, err := asn1.Marshal(attributes)
+	if err != nil {
+		return asn1.RawValue{}, fmt.Errorf("Failed to encode S/MIME attributes: %w", err)
+	}
+	attributes.FullBytes = der
+
+	return attributes, nil
+}
+
+func encryptSMIMEEntity(entity string, certificates []*x509.Certificate) (string, error) {
+	key := make([]byte, 32)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+
+	padding := aes.BlockSize - len(entity)%aes.BlockSize
+	plaintext := append([]byte(entity), bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+
+	recipientInfos := []cmsRecipientInfo{}
+	for _, certificate := range certificates {
+		publicKey, ok := certificate.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return "", fmt.Errorf("S/MIME encryption requires an RSA certificate for %s", certificate.Subject)
+		}
+
+		encryptedKey, err := rsa.EncryptPKCS1v15(rand.Reader, publicKey, key)
+		if err != nil {
+			return "", fmt.Errorf("Failed to encrypt S/MIME content key: %w", err)
+		}
+
+		recipientInfos = append(recipientInfos, cmsRecipientInfo{
+			Recipient: cmsIssuerAndSerial{
+				Issuer: asn1.RawValue{FullBytes: certificate.RawIssuer},
+				Serial: certificate.SerialNumber,
+			},
+			KeyEncryptionAlgorithm: cmsAlgorithm{Algorithm: oidRSAEncryption, Parameters: cmsNullParameters},
+			EncryptedKey:           encryptedKey,
+		})
+	}
+
+	parameters, err := asn1.Marshal(iv)
+	if err != nil {
+		return "", err
+	}
+
+	envelopedData, err := asn1.Marshal(cmsEnvelopedData{
+		RecipientInfos: recipientInfos,
+		EncryptedContent: cmsEncryptedContent{
+			ContentType: oidCMSData,
+			Algorithm:   cmsAlgorithm{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: parameters}},
+			EncryptedContent: asn1.RawValue{
+				Class: asn1.ClassContextSpecific,
+				Tag:   0,
+				Bytes: ciphertext,
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode S/MIME envelope: %w", err)
+	}
+
+	contentInfo, err := asn1.Marshal(cmsContentInfo{
+		ContentType: oidCMSEnvelopedData,
+		Content:     cmsExplicitContent(envelopedData),
+	})
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode S/MIME envelope: %w", err)
+	}
+
+	builder := &strings.Builder{}
+	builder.WriteString("Content-Type: application/pkcs7-mime; smime-type=enveloped-data;\r\n")
+	builder.WriteString(" name=\"smime.p7m\"\r\n")
+	builder.WriteString("Content-Transfer-Encoding: base64\r\n")
+	builder.WriteString("Content-Disposition: attachment; filename=\"smime.p7m\"\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(wrapBase64(contentInfo))
+
+	return builder.String(), nil
+}
+
+// cmsExplicitContent wraps content in the explicit [0] tag of a ContentInfo.
+func cmsExplicitContent(content []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: content}
+}
+
+func mimeBoundary() (string, error) {
+	random := make([]byte, 24)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", random), nil
+}
+
+func wrapBase64(data []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	builder := &strings.Builder{}
+	for len(encoded) > smimeBase64LineWidth {
+		builder.WriteString(encoded[:smimeBase64LineWidth] + "\r\n")
+		encoded = encoded[smimeBase64LineWidth:]
+	}
+	builder.WriteString(encoded + "\r\n")
+
+	return builder.String()
+}
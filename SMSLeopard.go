@@ -0,0 +1,109 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// SMSLeopardCredentials sends from Source, a registered sender ID.
+type SMSLeopardCredentials struct {
+	APIKey    string
+	APISecret string
+	Source    string
+}
+
+type SMSLeopardSender struct {
+	Credentials *SMSLeopardCredentials
+	BaseURL     string
+}
+
+func NewSMSLeopardSender(credentials *SMSLeopardCredentials) *SMSLeopardSender {
+	return &SMSLeopardSender{
+		Credentials: credentials,
+		BaseURL:     "https://api.smsleopard.com",
+	}
+}
+
+type smsLeopardDestination struct {
+	Number string `json:"number"`
+}
+
+type smsLeopardRequest struct {
+	Source      string                  `json:"source"`
+	Message     string                  `json:"message"`
+	Destination []smsLeopardDestination `json:"destination"`
+}
+
+type smsLeopardRecipient struct {
+	ID     string      `json:"id"`
+	Number string      `json:"number"`
+	Cost   json.Number `json:"cost"`
+	Status string      `json:"status"`
+}
+
+type smsLeopardResponse struct {
+	Success    bool                  `json:"success"`
+	Message    string                `json:"message"`
+	Recipients []smsLeopardRecipient `json:"recipients"`
+}
+
+func (sender *SMSLeopardSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	return sendEachSms(ctx, "smsleopard", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sender.send(ctx, text, receiver)
+	})
+}
+
+func (sender *SMSLeopardSender) send(ctx context.Context, text, receiver string) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("smsleopard", "sms", err) }()
+
+	// SMSLeopard expects numbers in international format without the
+	// leading plus sign.
+	body, err := json.Marshal(smsLeopardRequest{
+		Source:      sender.Credentials.Source,
+		Message:     text,
+		Destination: []smsLeopardDestination{{Number: strings.TrimPrefix(strings.TrimSpace(receiver), "+")}},
+	})
+	if err != nil {
+		return result, fmt.Errorf("Failed to encode SMSLeopard request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		strings.TrimSuffix(sender.BaseURL, "/")+"/v1/sms/send",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return result, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	request.SetBasicAuth(sender.Credentials.APIKey, sender.Credentials.APISecret)
+
+	_, responseBody, err := doProviderRequest("smsleopard", "SMSLeopard", request)
+	result.Raw = string(responseBody)
+	if err != nil {
+		return result, err
+	}
+
+	var smsLeopardResp smsLeopardResponse
+	if err := json.Unmarshal(responseBody, &smsLeopardResp); err != nil {
+		return result, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	if !smsLeopardResp.Success || len(smsLeopardResp.Recipients) == 0 {
+		return result, fmt.Errorf("SMSLeopard rejected the message: %s", smsLeopardResp.Message)
+	}
+
+	recipient := smsLeopardResp.Recipients[0]
+	result.MessageID = recipient.ID
+	result.Status = recipient.Status
+	result.Cost = recipient.Cost.String()
+
+	return result, nil
+}
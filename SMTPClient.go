@@ -0,0 +1,491 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type smtpClient struct {
+	*smtp.Client
+	host         string
+	rawConn      net.Conn
+	transcript   *smtpTranscript
+	lastResponse string
+	startedData  bool
+	stopWatch    func() bool
+}
+
+// smtpMessage is an email rendered for the wire, so that it can be resent on
+// another connection without reading its attachments again.
+type smtpMessage struct {
+	from       string
+	recipients []string
+	data       []byte
+	verp       bool
+}
+
+type smtpLoginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (auth *smtpLoginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS {
+		advertised := false
+		for _, mechanism := range server.Auth {
+			if mechanism == "LOGIN" {
+				advertised = true
+				break
+			}
+		}
+		if !advertised {
+			return "", nil, errors.New("Unencrypted connection")
+		}
+	}
+
+	if server.Name != auth.host {
+		return "", nil, errors.New("Wrong host name")
+	}
+
+	return "LOGIN", nil, nil
+}
+
+func (auth *smtpLoginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch {
+	case bytes.Equal(fromServer, []byte("Username:")):
+		return []byte(auth.username), nil
+	case bytes.Equal(fromServer, []byte("Password:")):
+		return []byte(auth.password), nil
+	default:
+		return nil, fmt.Errorf("Unexpected server challenge: %s", fromServer)
+	}
+}
+
+type smtpXOAuth2Auth struct {
+	username    string
+	accessToken string
+	host        string
+}
+
+func (auth *smtpXOAuth2Auth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && server.Name != "localhost" && server.Name != "127.0.0.1" && server.Name != "::1" {
+		return "", nil, errors.New("Unencrypted connection")
+	}
+
+	if server.Name != auth.host {
+		return "", nil, errors.New("Wrong host name")
+	}
+
+	response := fmt.Sprintf("user=%s\x01auth=Bearer %s\x01\x01", auth.username, auth.accessToken)
+
+	return "XOAUTH2", []byte(response), nil
+}
+
+// Next answers the server's error challenge with an empty response, after
+// which the server reports the failure with its final status code.
+func (auth *smtpXOAuth2Auth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		return []byte{}, nil
+	}
+
+	return nil, nil
+}
+
+func dialSMTP(ctx context.Context, credentials *SMTPCredentials, port int) (*smtpClient, error) {
+	mode, required := smtpTLSMode(credentials, port)
+	switch mode {
+	case TLSModeNone, TLSModeSTARTTLS, TLSModeImplicit:
+	default:
+		return nil, &ValidationError{Field: "tlsMode", Message: fmt.Sprintf("Unknown TLS mode %q", mode)}
+	}
+
+	address := net.JoinHostPort(credentials.Host, strconv.Itoa(port))
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	rawConn := conn
+
+	if mode == TLSModeImplicit {
+		conn = tls.Client(conn, smtpTLSConfig(credentials))
+	}
+
+	client := &smtpClient{
+		host:    credentials.Host,
+		rawConn: rawConn,
+	}
+	if dumper := activeDebugDumper.Load(); dumper != nil {
+		client.transcript = &smtpTranscript{dumper: dumper}
+	}
+	client.bind(ctx)
+
+	if client.Client, err = smtp.NewClient(conn, credentials.Host); err != nil {
+		client.stopWatch()
+		conn.Close()
+		return nil, err
+	}
+	client.tap()
+
+	if err := client.Hello("localhost"); err != nil {
+		client.abort()
+		return nil, err
+	}
+
+	if mode == TLSModeSTARTTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(smtpTLSConfig(credentials)); err != nil {
+				client.abort()
+				return nil, err
+			}
+			client.tap()
+		} else if required {
+			client.abort()
+			return nil, fmt.Errorf("SMTP server %s does not support STARTTLS", credentials.Host)
+		}
+	}
+
+	if credentials.TokenSource != nil {
+		token, err := credentials.TokenSource.Token()
+		if err != nil {
+			client.abort()
+			return nil, fmt.Errorf("Failed to obtain OAuth2 token: %w", err)
+		}
+
+		auth := &smtpXOAuth2Auth{
+			username:    credentials.User,
+			accessToken: token.AccessToken,
+			host:        credentials.Host,
+		}
+		if err := client.Auth(auth); err != nil {
+			client.abort()
+			return nil, err
+		}
+	} else if credentials.User != "" {
+		if ok, mechanisms := client.Extension("AUTH"); ok {
+			var auth smtp.Auth
+			if strings.Contains(mechanisms, "CRAM-MD5") {
+				auth = smtp.CRAMMD5Auth(credentials.User, credentials.Password)
+			} else if strings.Contains(mechanisms, "LOGIN") && !strings.Contains(mechanisms, "PLAIN") {
+				auth = &smtpLoginAuth{
+					username: credentials.User,
+					password: credentials.Password,
+					host:     credentials.Host,
+				}
+			} else {
+				auth = smtp.PlainAuth("", credentials.User, credentials.Password, credentials.Host)
+			}
+
+			if err := client.Auth(auth); err != nil {
+				client.abort()
+				return nil, err
+			}
+		}
+	}
+
+	return client, nil
+}
+
+func smtpTLSMode(credentials *SMTPCredentials, port int) (TLSMode, bool) {
+	switch {
+	case credentials.TLSMode != "":
+		return credentials.TLSMode, true
+	case port == 465:
+		return TLSModeImplicit, true
+	default:
+		return TLSModeSTARTTLS, credentials.UseTLS
+	}
+}
+
+func smtpTLSConfig(credentials *SMTPCredentials) *tls.Config {
+	config := &tls.Config{}
+	if credentials.TLSConfig != nil {
+		config = credentials.TLSConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = credentials.Host
+	}
+
+	return config
+}
+
+// bind makes ctx's deadline and cancellation interrupt any blocked I/O on the
+// connection until release is called.
+func (client *smtpClient) bind(ctx context.Context) {
+	deadline, _ := ctx.Deadline()
+	client.rawConn.SetDeadline(deadline)
+	client.stopWatch = context.AfterFunc(ctx, func() {
+		client.rawConn.SetDeadline(time.Unix(1, 0))
+	})
+}
+
+func (client *smtpClient) release() bool {
+	stopped := client.stopWatch()
+	client.rawConn.SetDeadline(time.Time{})
+	if client.transcript != nil {
+		client.transcript.flush(client.host)
+	}
+
+	return stopped
+}
+
+func (client *smtpClient) reset() error {
+	return client.Reset()
+}
+
+// tap routes the client's text connection through the transcript. It is
+// applied again after STARTTLS, which replaces the text connection, so the
+// transcript records the session in clear text rather than the TLS records.
+func (client *smtpClient) tap() {
+	if client.transcript == nil {
+		return
+	}
+
+	client.Text = textproto.NewConn(&debugConn{
+		ReadWriteCloser: &smtpTextStream{text: client.Text},
+		transcript:      client.transcript,
+	})
+}
+
+// smtpTextStream exposes the buffered stream of a textproto.Conn, so that
+// bytes it has already read ahead are not lost when it is wrapped.
+type smtpTextStream struct {
+	text *textproto.Conn
+}
+
+func (stream *smtpTextStream) Read(buffer []byte) (int, error) {
+	return stream.text.R.Read(buffer)
+}
+
+func (stream *smtpTextStream) Write(buffer []byte) (int, error) {
+	count, err := stream.text.W.Write(buffer)
+	if err != nil {
+		return count, err
+	}
+
+	return count, stream.text.W.Flush()
+}
+
+func (stream *smtpTextStream) Close() error {
+	return stream.text.Close()
+}
+
+// Send runs one mail transaction. DATA is issued on the text connection
+// rather than through smtp.Client.Data, which discards the server's final
+// reply, because that reply carries the queue id.
+func (client *smtpClient) Send(from string, to []string, message io.WriterTo) error {
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+
+	for _, address := range to {
+		if err := client.Rcpt(address); err != nil {
+			return err
+		}
+	}
+
+	id, err := client.Text.Cmd("DATA")
+	if err != nil {
+		return err
+	}
+	client.Text.StartResponse(id)
+	_, _, err = client.Text.ReadResponse(354)
+	client.Text.EndResponse(id)
+	if err != nil {
+		return err
+	}
+	client.startedData = true
+
+	writer := client.Text.DotWriter()
+	if _, err := message.WriteTo(writer); err != nil {
+		writer.Close()
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	_, response, err := client.Text.ReadResponse(250)
+	client.lastResponse = response
+
+	return err
+}
+
+func renderSMTPMessage(credentials *SMTPCredentials, email *preparedEmail) (*smtpMessage, error) {
+	from, recipients, err := email.envelope()
+	if err != nil {
+		return nil, err
+	}
+
+	if credentials.ReturnPath != "" {
+		returnPath, err := mail.ParseAddress(credentials.ReturnPath)
+		if err != nil {
+			return nil, &ValidationError{Field: "returnPath", Message: err.Error()}
+		}
+		from = returnPath.Address
+	}
+
+	rendered, err := email.render(recipients, credentials.SMIME, credentials.PGP, credentials.DKIM)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &bytes.Buffer{}
+	if _, err := rendered.WriteTo(data); err != nil {
+		return nil, fmt.Errorf("Failed to render message: %w", err)
+	}
+
+	return &smtpMessage{
+		from:       from,
+		recipients: recipients,
+		data:       data.Bytes(),
+		verp:       credentials.VERP,
+	}, nil
+}
+
+// sendMessage sends message, recording in startedData whether any of its
+// content reached the server.
+func (client *smtpClient) sendMessage(message *smtpMessage) error {
+	client.startedData = false
+
+	if !message.verp {
+		return client.Send(message.from, message.recipients, bytes.NewReader(message.data))
+	}
+
+	// Each recipient gets its own transaction so that a bounce identifies
+	// the recipient through the envelope sender.
+	for _, recipient := range message.recipients {
+		if err := client.Send(verpAddress(message.from, recipient), []string{recipient}, bytes.NewReader(message.data)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verpAddress encodes recipient into the local part of returnPath, so that
+// bounces@example.com becomes bounces+jane=example.org@example.com for
+// jane@example.org.
+func verpAddress(returnPath, recipient string) string {
+	at := strings.LastIndex(returnPath, "@")
+	if at < 0 {
+		return returnPath
+	}
+
+	return returnPath[:at] + "+" + strings.Replace(recipient, "@", "=", 1) + returnPath[at:]
+}
+
+// VERPRecipient returns the recipient encoded in address, a bounce address
+// built from returnPath when SMTPCredentials.VERP is set.
+func VERPRecipient(returnPath, address string) (string, bool) {
+	at := strings.LastIndex(returnPath, "@")
+	if at < 0 {
+		return "", false
+	}
+
+	prefix := strings.ToLower(returnPath[:at] + "+")
+	suffix := strings.ToLower(returnPath[at:])
+	lowered := strings.ToLower(address)
+	if !strings.HasPrefix(lowered, prefix) || !strings.HasSuffix(lowered, suffix) || len(address) <= len(prefix)+len(suffix) {
+		return "", false
+	}
+
+	encoded := address[len(prefix) : len(address)-len(suffix)]
+	separator := strings.LastIndex(encoded, "=")
+	if separator <= 0 || separator == len(encoded)-1 {
+		return "", false
+	}
+
+	return encoded[:separator] + "@" + encoded[separator+1:], true
+}
+
+func (email *preparedEmail) envelope() (string, []string, error) {
+	from, err := mail.ParseAddress(email.From)
+	if err != nil {
+		return "", nil, &ValidationError{Field: "sender", Message: err.Error()}
+	}
+
+	recipients := []string{}
+	for _, receiver := range email.recipients() {
+		address, err := mail.ParseAddress(receiver)
+		if err != nil {
+			return "", nil, &ValidationError{Field: "receivers", Message: err.Error()}
+		}
+		recipients = append(recipients, address.Address)
+	}
+
+	return from.Address, recipients, nil
+}
+
+// render builds the message as it goes on the wire, applying S/MIME or PGP
+// and then DKIM when configured.
+func (email *preparedEmail) render(
+	recipients []string,
+	smime *SMIMEOptions,
+	pgp *PGPOptions,
+	dkim *DKIMOptions,
+) (io.WriterTo, error) {
+	var message io.WriterTo = email.gomailMessage()
+	var err error
+	switch {
+	case smime != nil && pgp != nil:
+		return nil, &ValidationError{Field: "pgp", Message: "S/MIME and PGP cannot both be applied"}
+	case smime != nil:
+		if message, err = applySMIME(message, recipients, smime); err != nil {
+			return nil, err
+		}
+	case pgp != nil:
+		if message, err = applyPGP(message, recipients, pgp); err != nil {
+			return nil, err
+		}
+	}
+	if dkim != nil {
+		if message, err = signDKIM(message, dkim); err != nil {
+			return nil, err
+		}
+	}
+
+	return message, nil
+}
+
+func (client *smtpClient) Close() error {
+	err := client.Quit()
+	client.abort()
+
+	return err
+}
+
+// quit closes a connection that is not bound to a context, bounding the QUIT
+// exchange so an unresponsive server cannot block the caller.
+func (client *smtpClient) quit() error {
+	client.bind(context.Background())
+	client.rawConn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	return client.Close()
+}
+
+func (client *smtpClient) abort() {
+	client.stopWatch()
+	client.Client.Close()
+	if client.transcript != nil {
+		client.transcript.flush(client.host)
+	}
+}
@@ -0,0 +1,181 @@
+package messagingutilities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type SMTPPoolOptions struct {
+	MaxIdle     int
+	IdleTimeout time.Duration
+}
+
+type pooledSMTPClient struct {
+	client   *smtpClient
+	lastUsed time.Time
+}
+
+type smtpPool struct {
+	credentials *SMTPCredentials
+	options     SMTPPoolOptions
+	mutex       sync.Mutex
+	idle        []pooledSMTPClient
+	closed      bool
+}
+
+// NewPooledSMTPSender keeps up to MaxIdle authenticated connections open
+// between sends. Idle connections are probed with RSET before reuse and
+// replaced when the probe fails or they have been idle longer than
+// IdleTimeout. Close the sender to release them.
+func NewPooledSMTPSender(credentials *SMTPCredentials, options SMTPPoolOptions) *SMTPSender {
+	if options.MaxIdle <= 0 {
+		options.MaxIdle = 2
+	}
+
+	if options.IdleTimeout <= 0 {
+		options.IdleTimeout = 30 * time.Second
+	}
+
+	return &SMTPSender{
+		Credentials: credentials,
+		pool: &smtpPool{
+			credentials: credentials,
+			options:     options,
+		},
+	}
+}
+
+func (sender *SMTPSender) Close() error {
+	if sender.pool == nil {
+		return nil
+	}
+
+	return sender.pool.close()
+}
+
+func (pool *smtpPool) send(ctx context.Context, email *preparedEmail) (response string, err error) {
+	defer func() {
+		if err != nil && ctx.Err() != nil {
+			err = fmt.Errorf("%w: %w", ctx.Err(), err)
+		}
+	}()
+
+	if err := email.checkSize(pool.credentials.MaxAttachmentSize, pool.credentials.MaxMessageSize); err != nil {
+		return "", err
+	}
+
+	message, err := renderSMTPMessage(pool.credentials, email)
+	if err != nil {
+		return "", err
+	}
+
+	client, reused, err := pool.get(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	err = client.sendMessage(message)
+
+	// Servers commonly close long-lived sessions with a 421 on the next
+	// command; retry those once on a fresh connection. A 421 after DATA
+	// was accepted may follow a delivered message, so it is not retried.
+	var protocolError *textproto.Error
+	if reused && !client.startedData && errors.As(err, &protocolError) && protocolError.Code == 421 {
+		client.abort()
+		DefaultStats.RecordRetry("smtp", "email")
+		if client, err = pool.dial(ctx); err != nil {
+			return "", err
+		}
+		err = client.sendMessage(message)
+	}
+
+	if err != nil {
+		client.abort()
+		return "", err
+	}
+
+	response = client.lastResponse
+	pool.put(client)
+
+	return response, nil
+}
+
+func (pool *smtpPool) get(ctx context.Context) (*smtpClient, bool, error) {
+	for {
+		pool.mutex.Lock()
+		if pool.closed {
+			pool.mutex.Unlock()
+			return nil, false, errors.New("SMTP sender is closed")
+		}
+		if len(pool.idle) == 0 {
+			pool.mutex.Unlock()
+			break
+		}
+		pooled := pool.idle[len(pool.idle)-1]
+		pool.idle = pool.idle[:len(pool.idle)-1]
+		pool.mutex.Unlock()
+
+		if time.Since(pooled.lastUsed) > pool.options.IdleTimeout {
+			pooled.client.quit()
+			continue
+		}
+
+		pooled.client.bind(ctx)
+		if err := pooled.client.reset(); err != nil {
+			pooled.client.abort()
+			continue
+		}
+
+		return pooled.client, true, nil
+	}
+
+	client, err := pool.dial(ctx)
+	return client, false, err
+}
+
+func (pool *smtpPool) dial(ctx context.Context) (*smtpClient, error) {
+	port, err := strconv.Atoi(pool.credentials.Port)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid port number: %w", err)
+	}
+
+	return dialSMTP(ctx, pool.credentials, port)
+}
+
+func (pool *smtpPool) put(client *smtpClient) {
+	if !client.release() {
+		client.abort()
+		return
+	}
+
+	pool.mutex.Lock()
+	if pool.closed || len(pool.idle) >= pool.options.MaxIdle {
+		pool.mutex.Unlock()
+		client.quit()
+		return
+	}
+	pool.idle = append(pool.idle, pooledSMTPClient{client: client, lastUsed: time.Now()})
+	pool.mutex.Unlock()
+}
+
+func (pool *smtpPool) close() error {
+	pool.mutex.Lock()
+	idle := pool.idle
+	pool.idle = nil
+	pool.closed = true
+	pool.mutex.Unlock()
+
+	errs := []error{}
+	for _, pooled := range idle {
+		if err := pooled.client.quit(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
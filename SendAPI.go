@@ -0,0 +1,289 @@
+package messagingutilities
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type SendAPIOptions struct {
+	Authenticate   func(request *http.Request, token string) bool
+	MaxBodyBytes   int64
+	IdempotencyTTL time.Duration
+	Timeout        time.Duration
+}
+
+type sendAPIRequest struct {
+	Sender string         `json:"sender,omitempty"`
+	SMS    *OutboundSMS   `json:"sms,omitempty"`
+	Email  *OutboundEmail `json:"email,omitempty"`
+}
+
+type sendAPIFieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type sendAPIError struct {
+	Code    string              `json:"code"`
+	Message string              `json:"message"`
+	Fields  []sendAPIFieldError `json:"fields,omitempty"`
+}
+
+type sendAPIResponse struct {
+	Result *SendResult   `json:"result,omitempty"`
+	Error  *sendAPIError `json:"error,omitempty"`
+}
+
+type idempotencyEntry struct {
+	payloadHash [32]byte
+	done        chan struct{}
+	channel     Channel
+	status      int
+	response    sendAPIResponse
+	expiresAt   time.Time
+}
+
+type SendAPIHandler struct {
+	senders     map[string]Sender
+	options     SendAPIOptions
+	mutex       sync.Mutex
+	idempotency map[string]*idempotencyEntry
+}
+
+// NewSendAPIHandler exposes POST /v1/messages. Requests name a sender from the
+// map, or omit it to use the sender registered under the channel name ("sms"
+// or "email") of the payload they carry.
+func NewSendAPIHandler(senders map[string]Sender, options SendAPIOptions) (*SendAPIHandler, error) {
+	if len(senders) == 0 {
+		return nil, fmt.Errorf("At least one sender is required")
+	}
+
+	if options.Authenticate == nil {
+		return nil, fmt.Errorf("An authentication check is required")
+	}
+
+	if options.MaxBodyBytes <= 0 {
+		options.MaxBodyBytes = 1 << 20
+	}
+
+	if options.IdempotencyTTL <= 0 {
+		options.IdempotencyTTL = 24 * time.Hour
+	}
+
+	return &SendAPIHandler{
+		senders:     senders,
+		options:     options,
+		idempotency: map[string]*idempotencyEntry{},
+	}, nil
+}
+
+func (handler *SendAPIHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if !strings.HasSuffix(request.URL.Path, "/v1/messages") {
+		writeSendAPIError(writer, http.StatusNotFound, "not_found", "Not found", nil)
+		return
+	}
+
+	if request.Method != http.MethodPost {
+		writer.Header().Set("Allow", http.MethodPost)
+		writeSendAPIError(writer, http.StatusMethodNotAllowed, "method_not_allowed", "Only POST is supported", nil)
+		return
+	}
+
+	token, found := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer ")
+	if !found || !handler.options.Authenticate(request, strings.TrimSpace(token)) {
+		writer.Header().Set("WWW-Authenticate", "Bearer")
+		writeSendAPIError(writer, http.StatusUnauthorized, "unauthorized", "Invalid or missing bearer token", nil)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, handler.options.MaxBodyBytes))
+	if err != nil {
+		writeSendAPIError(writer, http.StatusRequestEntityTooLarge, "payload_too_large", "Request body is too large", nil)
+		return
+	}
+
+	key := request.Header.Get("Idempotency-Key")
+	if key == "" {
+		_, status, response := handler.send(request.Context(), body)
+		writeSendAPIResponse(writer, status, response)
+		return
+	}
+
+	entry, owner := handler.claimIdempotencyKey(key, sha256.Sum256(body))
+	if entry == nil {
+		writeSendAPIError(writer, http.StatusUnprocessableEntity, "idempotency_key_reused", "Idempotency key was used with a different payload", nil)
+		return
+	}
+
+	if owner {
+		entry.channel, entry.status, entry.response = handler.send(request.Context(), body)
+		if entry.status >= http.StatusInternalServerError {
+			handler.releaseIdempotencyKey(key)
+		}
+		close(entry.done)
+	} else {
+		select {
+		case <-entry.done:
+		case <-request.Context().Done():
+			writeSendAPIError(writer, http.StatusConflict, "idempotency_key_in_progress", "A request with this idempotency key is in progress", nil)
+			return
+		}
+		writer.Header().Set("Idempotent-Replayed", "true")
+		if entry.response.Result != nil {
+			DefaultStats.RecordDeduplicated(entry.response.Result.Provider, string(entry.channel))
+		}
+	}
+
+	writeSendAPIResponse(writer, entry.status, entry.response)
+}
+
+func (handler *SendAPIHandler) claimIdempotencyKey(key string, payloadHash [32]byte) (*idempotencyEntry, bool) {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+
+	now := time.Now()
+	for existingKey, existing := range handler.idempotency {
+		if now.After(existing.expiresAt) {
+			delete(handler.idempotency, existingKey)
+		}
+	}
+
+	if existing, ok := handler.idempotency[key]; ok {
+		if existing.payloadHash != payloadHash {
+			return nil, false
+		}
+		return existing, false
+	}
+
+	entry := &idempotencyEntry{
+		payloadHash: payloadHash,
+		done:        make(chan struct{}),
+		expiresAt:   now.Add(handler.options.IdempotencyTTL),
+	}
+	handler.idempotency[key] = entry
+
+	return entry, true
+}
+
+func (handler *SendAPIHandler) releaseIdempotencyKey(key string) {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+
+	delete(handler.idempotency, key)
+}
+
+func (handler *SendAPIHandler) send(ctx context.Context, body []byte) (Channel, int, sendAPIResponse) {
+	payload := sendAPIRequest{}
+	decoder := json.NewDecoder(strings.NewReader(string(body)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		return "", http.StatusBadRequest, sendAPIResponse{Error: &sendAPIError{
+			Code:    "invalid_json",
+			Message: "Request body is not valid JSON: " + err.Error(),
+		}}
+	}
+
+	var message *Message
+	var validationErr error
+	switch {
+	case payload.SMS != nil && payload.Email != nil:
+		validationErr = &ValidationError{Field: "sms", Message: "Only one of sms or email may be provided"}
+	case payload.SMS != nil:
+		validationErr = payload.SMS.Validate()
+		message = payload.SMS.Message()
+	case payload.Email != nil:
+		validationErr = payload.Email.Validate()
+		message = payload.Email.Message()
+	default:
+		validationErr = &ValidationError{Field: "sms", Message: "One of sms or email is required"}
+	}
+	if validationErr != nil {
+		return "", http.StatusBadRequest, sendAPIResponse{Error: validationErrorBody(validationErr)}
+	}
+
+	senderName := payload.Sender
+	if senderName == "" {
+		senderName = string(message.Channel)
+	}
+	sender, ok := handler.senders[senderName]
+	if !ok {
+		return message.Channel, http.StatusBadRequest, sendAPIResponse{Error: &sendAPIError{
+			Code:    "validation_failed",
+			Message: "Unknown sender",
+			Fields:  []sendAPIFieldError{{Field: "sender", Message: "No sender named " + senderName}},
+		}}
+	}
+
+	if handler.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, handler.options.Timeout)
+		defer cancel()
+	}
+
+	result, err := sender.Send(ctx, message)
+	if err != nil {
+		switch {
+		case CategorizeError(err) == ErrorCategoryValidation:
+			return message.Channel, http.StatusBadRequest, sendAPIResponse{Error: validationErrorBody(err)}
+		case errors.Is(err, ErrRateLimited):
+			return message.Channel, http.StatusTooManyRequests, sendAPIResponse{Error: &sendAPIError{
+				Code:    "rate_limited",
+				Message: err.Error(),
+			}}
+		case CategorizeError(err) == ErrorCategoryTimeout:
+			return message.Channel, http.StatusGatewayTimeout, sendAPIResponse{Error: &sendAPIError{
+				Code:    "timeout",
+				Message: err.Error(),
+			}}
+		default:
+			return message.Channel, http.StatusBadGateway, sendAPIResponse{Error: &sendAPIError{
+				Code:    "provider_error",
+				Message: err.Error(),
+			}}
+		}
+	}
+
+	return message.Channel, http.StatusOK, sendAPIResponse{Result: result}
+}
+
+func validationErrorBody(err error) *sendAPIError {
+	body := &sendAPIError{
+		Code:    "validation_failed",
+		Message: "Message failed validation",
+	}
+
+	var validationErrors ValidationErrors
+	var validationError *ValidationError
+	switch {
+	case errors.As(err, &validationErrors):
+		for _, fieldError := range validationErrors {
+			body.Fields = append(body.Fields, sendAPIFieldError{Field: fieldError.Field, Message: fieldError.Message})
+		}
+	case errors.As(err, &validationError):
+		body.Fields = append(body.Fields, sendAPIFieldError{Field: validationError.Field, Message: validationError.Message})
+	}
+
+	return body
+}
+
+func writeSendAPIError(writer http.ResponseWriter, status int, code, message string, fields []sendAPIFieldError) {
+	writeSendAPIResponse(writer, status, sendAPIResponse{Error: &sendAPIError{
+		Code:    code,
+		Message: message,
+		Fields:  fields,
+	}})
+}
+
+func writeSendAPIResponse(writer http.ResponseWriter, status int, response sendAPIResponse) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	json.NewEncoder(writer).Encode(response)
+}
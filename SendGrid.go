@@ -0,0 +1,184 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type SendGridCredentials struct {
+	ApiKey     string
+	Sender     string
+	SenderName string
+}
+
+type SendGridSender struct {
+	Credentials *SendGridCredentials
+	BaseURL     string
+}
+
+func NewSendGridSender(credentials *SendGridCredentials) *SendGridSender {
+	return &SendGridSender{
+		Credentials: credentials,
+		BaseURL:     "https://api.sendgrid.com",
+	}
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridPersonalization struct {
+	To  []sendGridAddress `json:"to"`
+	Cc  []sendGridAddress `json:"cc,omitempty"`
+	Bcc []sendGridAddress `json:"bcc,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridAttachment struct {
+	Content     string `json:"content"`
+	Type        string `json:"type"`
+	Filename    string `json:"filename"`
+	Disposition string `json:"disposition"`
+	ContentID   string `json:"content_id,omitempty"`
+}
+
+type sendGridRequest struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	ReplyTo          *sendGridAddress          `json:"reply_to,omitempty"`
+	Subject          string                    `json:"subject,omitempty"`
+	Content          []sendGridContent         `json:"content,omitempty"`
+	Attachments      []sendGridAttachment      `json:"attachments,omitempty"`
+	Categories       []string                  `json:"categories,omitempty"`
+	CustomArgs       map[string]string         `json:"custom_args,omitempty"`
+	Headers          map[string]string         `json:"headers,omitempty"`
+}
+
+func (sender *SendGridSender) Send(ctx context.Context, message *Message) (result *SendResult, err error) {
+	defer func() { DefaultStats.RecordResult("sendgrid", "email", err) }()
+
+	message, err = suppressRecipients(ctx, ChannelEmail, message)
+	if err != nil {
+		return nil, err
+	}
+
+	email, err := prepareEmail(sender.Credentials.Sender, message)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := sender.payload(email)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode SendGrid request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		strings.TrimSuffix(sender.BaseURL, "/")+"/v3/mail/send",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer "+sender.Credentials.ApiKey)
+
+	response, responseBody, err := doProviderRequest("sendgrid", "SendGrid", request)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SendResult{
+		Provider:   "sendgrid",
+		MessageID:  response.Header.Get("X-Message-Id"),
+		Recipients: email.recipients(),
+		Raw:        string(responseBody),
+	}, nil
+}
+
+func sendGridAddresses(values []string) []sendGridAddress {
+	addresses := []sendGridAddress{}
+	for _, value := range values {
+		name, address := splitEmailAddress(value)
+		addresses = append(addresses, sendGridAddress{Email: address, Name: name})
+	}
+
+	return addresses
+}
+
+func (sender *SendGridSender) payload(email *preparedEmail) (*sendGridRequest, error) {
+	payload := &sendGridRequest{
+		From: sendGridAddress{
+			Email: email.From,
+			Name:  sender.Credentials.SenderName,
+		},
+		Subject:    email.Subject,
+		CustomArgs: email.Metadata,
+		Headers:    email.flatHeaders(),
+	}
+
+	if email.ReplyTo != "" {
+		name, address := splitEmailAddress(email.ReplyTo)
+		payload.ReplyTo = &sendGridAddress{Email: address, Name: name}
+	}
+
+	payload.Personalizations = []sendGridPersonalization{{
+		To:  sendGridAddresses(email.To),
+		Cc:  sendGridAddresses(email.Cc),
+		Bcc: sendGridAddresses(email.Bcc),
+	}}
+
+	// SendGrid requires text/plain, then text/x-amp-html, to precede
+	// text/html.
+	if email.Text != "" {
+		payload.Content = append(payload.Content, sendGridContent{Type: "text/plain", Value: email.Text})
+	}
+	if email.AMP != "" {
+		payload.Content = append(payload.Content, sendGridContent{Type: "text/x-amp-html", Value: email.AMP})
+	}
+	if email.HTML != "" {
+		payload.Content = append(payload.Content, sendGridContent{Type: "text/html", Value: email.HTML})
+	}
+
+	if email.Category != "" {
+		payload.Categories = append(payload.Categories, string(email.Category))
+	}
+	payload.Categories = append(payload.Categories, email.Tags...)
+
+	attachments, err := email.readAttachments()
+	if err != nil {
+		return nil, err
+	}
+	for _, attachment := range attachments {
+		sendGridAttach := sendGridAttachment{
+			Content:     base64.StdEncoding.EncodeToString(attachment.Data),
+			Type:        attachment.ContentType,
+			Filename:    attachment.Name,
+			Disposition: "attachment",
+		}
+		if attachment.Inline {
+			sendGridAttach.Disposition = "inline"
+			sendGridAttach.ContentID = attachment.ContentID
+		}
+		payload.Attachments = append(payload.Attachments, sendGridAttach)
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,457 @@
+package messagingutilities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrRateLimited = errors.New("Provider rate limit exceeded")
+
+type Channel string
+
+const (
+	ChannelSMS      Channel = "sms"
+	ChannelEmail    Channel = "email"
+	ChannelWhatsApp Channel = "whatsapp"
+	// ChannelChat messages are posted to chat apps such as Telegram, where To
+	// holds chat or channel ids. Webhook senders post to a fixed channel and
+	// need no receivers.
+	ChannelChat Channel = "chat"
+	// ChannelPush messages are push notifications, where To holds device
+	// tokens or topics, Subject is the title, Text the body and Metadata
+	// the data delivered to the app.
+	ChannelPush Channel = "push"
+)
+
+// EmailPriority is mapped to the X-Priority, Importance and
+// X-MSMail-Priority headers, which mail clients such as Outlook use to flag
+// urgent messages.
+type EmailPriority string
+
+const (
+	EmailPriorityHigh   EmailPriority = "high"
+	EmailPriorityNormal EmailPriority = "normal"
+	EmailPriorityLow    EmailPriority = "low"
+)
+
+type Message struct {
+	Channel  Channel             `json:"channel"`
+	To       []string            `json:"to"`
+	Cc       []string            `json:"cc,omitempty"`
+	Bcc      []string            `json:"bcc,omitempty"`
+	ReplyTo  string              `json:"replyTo,omitempty"`
+	Headers  map[string][]string `json:"headers,omitempty"`
+	Subject  string              `json:"subject,omitempty"`
+	Text     string              `json:"text,omitempty"`
+	HTML     string              `json:"html,omitempty"`
+	AMP      string              `json:"amp,omitempty"`
+	Category MessageCategory     `json:"category,omitempty"`
+	Tags     []string            `json:"tags,omitempty"`
+	Metadata map[string]string   `json:"metadata,omitempty"`
+	SendAt   *time.Time          `json:"sendAt,omitempty"`
+	Calendar *CalendarEvent      `json:"calendar,omitempty"`
+	Priority EmailPriority       `json:"priority,omitempty"`
+
+	// MediaURLs are publicly reachable files sent as MMS by senders that
+	// support it. Text is optional when they are set.
+	MediaURLs []string `json:"mediaUrls,omitempty"`
+	// StatusCallback is the URL delivery updates for this message are posted
+	// to, for senders with per-message callbacks such as Twilio.
+	StatusCallback string `json:"statusCallback,omitempty"`
+	// ContentTemplate sends a provider-side template instead of, or along
+	// with, Text.
+	ContentTemplate *ContentTemplate `json:"contentTemplate,omitempty"`
+	// Payload is merged into the JSON body posted by chat and push senders,
+	// for provider-specific content such as Slack blocks.
+	Payload map[string]any `json:"payload,omitempty"`
+
+	// MessageID, InReplyTo and References are Message-IDs without angle
+	// brackets. MessageID is generated when empty.
+	MessageID  string   `json:"messageId,omitempty"`
+	InReplyTo  string   `json:"inReplyTo,omitempty"`
+	References []string `json:"references,omitempty"`
+
+	Attachments []EmailAttachment  `json:"-"`
+	Inline      []InlineAttachment `json:"-"`
+}
+
+type RecipientResult struct {
+	Recipient string `json:"recipient"`
+	MessageID string `json:"messageId,omitempty"`
+	Status    string `json:"status,omitempty"`
+	Cost      string `json:"cost,omitempty"`
+	Segments  int    `json:"segments,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Raw       string `json:"raw,omitempty"`
+}
+
+type SendResult struct {
+	Provider  string `json:"provider"`
+	MessageID string `json:"messageId,omitempty"`
+	// InternetMessageID is the Message-ID header of a sent email, without
+	// angle brackets, when it was set by this package rather than the
+	// provider.
+	InternetMessageID string            `json:"internetMessageId,omitempty"`
+	Recipients        []string          `json:"recipients"`
+	PerRecipient      []RecipientResult `json:"perRecipient,omitempty"`
+	Cost              string            `json:"cost,omitempty"`
+	Segments          int               `json:"segments,omitempty"`
+	Raw               string            `json:"raw,omitempty"`
+}
+
+type Sender interface {
+	Send(ctx context.Context, message *Message) (*SendResult, error)
+}
+
+// MessageStatus is the current state of a sent message as reported by the
+// provider.
+type MessageStatus struct {
+	MessageID      string         `json:"messageId"`
+	Recipient      string         `json:"recipient,omitempty"`
+	Status         DeliveryStatus `json:"status"`
+	ProviderStatus string         `json:"providerStatus"`
+	ErrorCode      string         `json:"errorCode,omitempty"`
+	ErrorMessage   string         `json:"errorMessage,omitempty"`
+	Cost           string         `json:"cost,omitempty"`
+	UpdatedAt      time.Time      `json:"updatedAt,omitzero"`
+}
+
+// StatusChecker is implemented by senders that can look up the status of
+// the messages they sent, returning one status per message in result.
+type StatusChecker interface {
+	Status(ctx context.Context, result *SendResult) ([]MessageStatus, error)
+}
+
+type ValidationErrors []*ValidationError
+
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+type OutboundSMS struct {
+	To        []string        `json:"to"`
+	Body      string          `json:"body"`
+	MediaURLs []string        `json:"mediaUrls,omitempty"`
+	Category  MessageCategory `json:"category,omitempty"`
+}
+
+func (sms *OutboundSMS) Validate() error {
+	errs := ValidationErrors{}
+	if len(sms.To) == 0 {
+		errs = append(errs, &ValidationError{Field: "to", Message: "At least one receiver is required"})
+	}
+	for _, receiver := range sms.To {
+		if _, err := NormalizePhoneNumber(receiver, ""); err != nil {
+			errs = append(errs, &ValidationError{Field: "to", Message: err.Error()})
+		}
+	}
+	if sms.Body == "" && len(sms.MediaURLs) == 0 {
+		errs = append(errs, &ValidationError{Field: "body", Message: "Message body cannot be empty"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (sms *OutboundSMS) Message() *Message {
+	return &Message{
+		Channel:   ChannelSMS,
+		To:        sms.To,
+		Text:      sms.Body,
+		MediaURLs: sms.MediaURLs,
+		Category:  sms.Category,
+	}
+}
+
+type OutboundEmail struct {
+	To       []string        `json:"to"`
+	Cc       []string        `json:"cc,omitempty"`
+	Bcc      []string        `json:"bcc,omitempty"`
+	ReplyTo  string          `json:"replyTo,omitempty"`
+	Subject  string          `json:"subject"`
+	Text     string          `json:"text,omitempty"`
+	HTML     string          `json:"html,omitempty"`
+	Category MessageCategory `json:"category,omitempty"`
+	Priority EmailPriority   `json:"priority,omitempty"`
+}
+
+func (email *OutboundEmail) Validate() error {
+	errs := ValidationErrors{}
+	if len(email.To) == 0 {
+		errs = append(errs, &ValidationError{Field: "to", Message: "At least one receiver is required"})
+	}
+	for _, group := range []struct {
+		field     string
+		addresses []string
+	}{
+		{"to", email.To},
+		{"cc", email.Cc},
+		{"bcc", email.Bcc},
+	} {
+		for _, receiver := range group.addresses {
+			if _, err := NormalizeEmailAddress(receiver); err != nil {
+				errs = append(errs, &ValidationError{Field: group.field, Message: err.Error()})
+			}
+		}
+	}
+	if email.ReplyTo != "" {
+		if _, err := NormalizeEmailAddress(email.ReplyTo); err != nil {
+			errs = append(errs, &ValidationError{Field: "replyTo", Message: err.Error()})
+		}
+	}
+	if email.Text == "" && email.HTML == "" {
+		errs = append(errs, &ValidationError{Field: "text", Message: "Either a text or an html body is required"})
+	}
+	if _, ok := emailPriorityHeaders[email.Priority]; email.Priority != "" && !ok {
+		errs = append(errs, &ValidationError{Field: "priority", Message: "Invalid priority " + string(email.Priority)})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (email *OutboundEmail) Message() *Message {
+	return &Message{
+		Channel:  ChannelEmail,
+		To:       email.To,
+		Cc:       email.Cc,
+		Bcc:      email.Bcc,
+		ReplyTo:  email.ReplyTo,
+		Subject:  email.Subject,
+		Text:     email.Text,
+		HTML:     email.HTML,
+		Category: email.Category,
+		Priority: email.Priority,
+	}
+}
+
+type SenderFunc func(ctx context.Context, message *Message) (*SendResult, error)
+
+func (send SenderFunc) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	return send(ctx, message)
+}
+
+func checkChannel(message *Message, channel Channel) error {
+	if message == nil {
+		return &ValidationError{Field: "message", Message: "Message cannot be empty"}
+	}
+
+	if message.Channel != "" && message.Channel != channel {
+		return &ValidationError{
+			Field:   "channel",
+			Message: fmt.Sprintf("Sender does not support the %s channel", message.Channel),
+		}
+	}
+
+	return nil
+}
+
+type SMTPSender struct {
+	Credentials *SMTPCredentials
+	pool        *smtpPool
+}
+
+func NewSMTPSender(credentials *SMTPCredentials) *SMTPSender {
+	return &SMTPSender{Credentials: credentials}
+}
+
+func (sender *SMTPSender) Send(ctx context.Context, message *Message) (result *SendResult, err error) {
+	defer func() { DefaultStats.RecordResult("smtp", "email", err) }()
+
+	message, err = suppressRecipients(ctx, ChannelEmail, message)
+	if err != nil {
+		return nil, err
+	}
+
+	email, err := prepareEmail(sender.Credentials.Sender, message)
+	if err != nil {
+		return nil, err
+	}
+
+	email.assignMessageID(sender.Credentials.MessageIDDomain)
+
+	var response string
+	if sender.pool != nil {
+		response, err = sender.pool.send(ctx, email)
+	} else {
+		response, err = sendSMTPEmail(ctx, sender.Credentials, email)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &SendResult{
+		Provider:          "smtp",
+		MessageID:         smtpQueueID(response),
+		InternetMessageID: email.MessageID,
+		Recipients:        email.recipients(),
+		Raw:               response,
+	}, nil
+}
+
+func sendEachSms(
+	ctx context.Context,
+	provider string,
+	message *Message,
+	send func(ctx context.Context, text, receiver string) (RecipientResult, error),
+) (*SendResult, error) {
+	return sendEachMessage(ctx, ChannelSMS, provider, message, send)
+}
+
+// sendEachMessage sends message to each of its To recipients in turn on a
+// phone-number or chat channel.
+func sendEachMessage(
+	ctx context.Context,
+	channel Channel,
+	provider string,
+	message *Message,
+	send func(ctx context.Context, text, receiver string) (RecipientResult, error),
+) (*SendResult, error) {
+	if err := checkChannel(message, channel); err != nil {
+		return nil, err
+	}
+
+	message, err := suppressRecipients(ctx, channel, message)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(message.MediaURLs) > 0 && !mediaProviders[provider] {
+		return nil, &ValidationError{Field: "mediaUrls", Message: provider + " does not support media messages"}
+	}
+	if len(message.Attachments) > 0 && !attachmentProviders[provider] {
+		return nil, &ValidationError{Field: "attachments", Message: provider + " does not support attachments"}
+	}
+	if message.ContentTemplate != nil && !contentTemplateProviders[provider] {
+		return nil, &ValidationError{Field: "contentTemplate", Message: provider + " does not support content templates"}
+	}
+
+	// Text is optional when the message has other content, including HTML
+	// and payloads on chat channels and titles and data on push channels.
+	hasContent := len(message.MediaURLs) > 0 || message.ContentTemplate != nil || len(message.Attachments) > 0 ||
+		channel == ChannelChat && (message.HTML != "" || len(message.Payload) > 0) ||
+		channel == ChannelPush && (message.Subject != "" || len(message.Metadata) > 0 || len(message.Payload) > 0)
+	text := message.Text
+	if text != "" || !hasContent {
+		if text, err = prepareSmsText(&message.Text); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(message.To) == 0 {
+		return nil, &ValidationError{Field: "to", Message: "Receivers cannot be empty"}
+	}
+
+	result := &SendResult{Provider: provider, Recipients: []string{}}
+	errs := []error{}
+	for index, receiver := range message.To {
+		if err := ctx.Err(); err != nil {
+			for _, skipped := range message.To[index:] {
+				result.PerRecipient = append(result.PerRecipient, RecipientResult{
+					Recipient: skipped,
+					Error:     ErrNotAttempted.Error(),
+				})
+				errs = append(errs, fmt.Errorf("%s: %w", skipped, ErrNotAttempted))
+			}
+			errs = append(errs, err)
+			break
+		}
+
+		recipientResult, err := send(ctx, text, receiver)
+		recipientResult.Recipient = receiver
+		if err != nil {
+			recipientResult.Error = err.Error()
+		}
+		result.PerRecipient = append(result.PerRecipient, recipientResult)
+
+		if err != nil {
+			if len(message.To) == 1 {
+				return result, err
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", receiver, err))
+			continue
+		}
+
+		result.Recipients = append(result.Recipients, receiver)
+		result.Segments += recipientResult.Segments
+	}
+
+	if len(result.PerRecipient) == 1 {
+		result.MessageID = result.PerRecipient[0].MessageID
+		result.Cost = result.PerRecipient[0].Cost
+		result.Raw = result.PerRecipient[0].Raw
+	}
+
+	return result, errors.Join(errs...)
+}
+
+type TwilioSender struct {
+	Credentials *TwilioCredentials
+}
+
+func NewTwilioSender(credentials *TwilioCredentials) *TwilioSender {
+	return &TwilioSender{Credentials: credentials}
+}
+
+func (sender *TwilioSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	if sender.Credentials.SenderPhoneNumber == "" && sender.Credentials.MessagingServiceSID == "" {
+		return nil, &ValidationError{Field: "sender", Message: "A sender phone number or messaging service is required"}
+	}
+
+	if message != nil && len(message.MediaURLs) > 0 {
+		if err := validateMediaURLs(ctx, message.MediaURLs, twilioMaxMedia, twilioMaxMediaBytes); err != nil {
+			return nil, err
+		}
+	}
+
+	return sendEachSms(ctx, "twilio", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sendTwilioMessage(ctx, sender.Credentials, ChannelSMS, message, text, receiver)
+	})
+}
+
+func (sender *TwilioSender) Status(ctx context.Context, result *SendResult) ([]MessageStatus, error) {
+	statuses := []MessageStatus{}
+	for _, recipient := range result.PerRecipient {
+		if recipient.MessageID == "" {
+			continue
+		}
+
+		status, err := GetTwilioMessageStatus(ctx, sender.Credentials, recipient.MessageID)
+		if err != nil {
+			return statuses, err
+		}
+		statuses = append(statuses, *status)
+	}
+
+	return statuses, nil
+}
+
+type AfricasTalkingSender struct {
+	Credentials *AfricasTalkingCredentials
+	// Premium sends premium SMS from the short code in SenderID when set.
+	Premium *AfricasTalkingPremiumOptions
+}
+
+func NewAfricasTalkingSender(credentials *AfricasTalkingCredentials) *AfricasTalkingSender {
+	return &AfricasTalkingSender{Credentials: credentials}
+}
+
+func (sender *AfricasTalkingSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	return sendEachSms(ctx, "africastalking", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sendAfricasTalkingSms(ctx, sender.Credentials, sender.Premium, text, receiver)
+	})
+}
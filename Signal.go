@@ -0,0 +1,124 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// SignalCredentials points at a signal-cli-rest-api server, such as
+// "http://localhost:8080", with Number the registered account messages are
+// sent from.
+type SignalCredentials struct {
+	ServerURL string
+	Number    string
+}
+
+// SignalSender sends messages through signal-cli-rest-api. Receivers are
+// phone numbers, usernames or group ids of the form "group.<id>", and
+// attachments are sent along with the text.
+type SignalSender struct {
+	Credentials *SignalCredentials
+}
+
+func NewSignalSender(credentials *SignalCredentials) *SignalSender {
+	return &SignalSender{Credentials: credentials}
+}
+
+type signalRequest struct {
+	Number            string   `json:"number"`
+	Recipients        []string `json:"recipients"`
+	Message           string   `json:"message"`
+	Base64Attachments []string `json:"base64_attachments,omitempty"`
+}
+
+type signalResponse struct {
+	Timestamp string `json:"timestamp"`
+}
+
+func (sender *SignalSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	if sender.Credentials.ServerURL == "" {
+		return nil, &ValidationError{Field: "serverUrl", Message: "Signal server URL cannot be empty"}
+	}
+	if sender.Credentials.Number == "" {
+		return nil, &ValidationError{Field: "sender", Message: "Sender number cannot be empty"}
+	}
+
+	attachments, err := readMessageAttachments(message)
+	if err != nil {
+		return nil, err
+	}
+
+	// Attachments are sent as data URIs, with their file name in place of
+	// the parameters of the content type.
+	encoded := []string{}
+	for _, attachment := range attachments {
+		contentType, _, _ := strings.Cut(attachment.ContentType, ";")
+		encoded = append(encoded, fmt.Sprintf(
+			"data:%s;filename=%s;base64,%s",
+			strings.TrimSpace(contentType),
+			attachment.Name,
+			base64.StdEncoding.EncodeToString(attachment.Data),
+		))
+	}
+
+	return sendEachMessage(ctx, ChannelChat, "signal", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sender.send(ctx, message, encoded, text, receiver)
+	})
+}
+
+func (sender *SignalSender) send(
+	ctx context.Context,
+	message *Message,
+	attachments []string,
+	text,
+	receiver string,
+) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("signal", "chat", err) }()
+
+	if text, err = chatText(message, text); err != nil {
+		return result, err
+	}
+
+	body, err := encodeChatPayload(signalRequest{
+		Number:            sender.Credentials.Number,
+		Recipients:        []string{receiver},
+		Message:           text,
+		Base64Attachments: attachments,
+	}, message.Payload)
+	if err != nil {
+		return result, fmt.Errorf("Failed to encode Signal request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		strings.TrimSuffix(sender.Credentials.ServerURL, "/")+"/v2/send",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return result, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	_, responseBody, err := doProviderRequest("signal", "Signal", request)
+	result.Raw = string(responseBody)
+	if err != nil {
+		return result, err
+	}
+
+	var signalResp signalResponse
+	if err := json.Unmarshal(responseBody, &signalResp); err != nil {
+		return result, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	// Signal messages are identified by the timestamp they were sent at.
+	result.MessageID = signalResp.Timestamp
+
+	return result, nil
+}
@@ -0,0 +1,120 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// SlackCredentials either holds the URL of an incoming webhook, which posts
+// to the channel it was created for, or a bot token used with
+// chat.postMessage. Bot messages go to the channel ids in To, or to Channel
+// when a message has none.
+type SlackCredentials struct {
+	WebhookURL string
+	BotToken   string
+	Channel    string
+}
+
+// SlackSender posts Text as Slack mrkdwn. Block Kit layouts are passed
+// through with Payload("blocks", ...), with Text used as the notification
+// fallback.
+type SlackSender struct {
+	Credentials *SlackCredentials
+	BaseURL     string
+}
+
+func NewSlackSender(credentials *SlackCredentials) *SlackSender {
+	return &SlackSender{
+		Credentials: credentials,
+		BaseURL:     "https://slack.com/api",
+	}
+}
+
+type slackRequest struct {
+	Channel string `json:"channel,omitempty"`
+	Text    string `json:"text,omitempty"`
+}
+
+type slackResponse struct {
+	OK      bool   `json:"ok"`
+	Error   string `json:"error"`
+	Channel string `json:"channel"`
+	TS      string `json:"ts"`
+}
+
+func (sender *SlackSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	switch {
+	case sender.Credentials.BotToken != "":
+		message = withDefaultReceiver(message, sender.Credentials.Channel)
+	case sender.Credentials.WebhookURL != "":
+		if message != nil && len(message.To) > 0 {
+			return nil, &ValidationError{Field: "to", Message: "Slack webhooks post to a fixed channel"}
+		}
+		message = withDefaultReceiver(message, "webhook")
+	default:
+		return nil, &ValidationError{Field: "credentials", Message: "A Slack webhook URL or bot token is required"}
+	}
+
+	return sendEachMessage(ctx, ChannelChat, "slack", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sender.send(ctx, message, text, receiver)
+	})
+}
+
+func (sender *SlackSender) send(ctx context.Context, message *Message, text, receiver string) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("slack", "chat", err) }()
+
+	if text, err = chatText(message, text); err != nil {
+		return result, err
+	}
+
+	payload := slackRequest{Text: text}
+	requestURL := sender.Credentials.WebhookURL
+	if sender.Credentials.BotToken != "" {
+		payload.Channel = receiver
+		requestURL = strings.TrimSuffix(sender.BaseURL, "/") + "/chat.postMessage"
+	}
+
+	body, err := encodeChatPayload(payload, message.Payload)
+	if err != nil {
+		return result, fmt.Errorf("Failed to encode Slack request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "POST", requestURL, bytes.NewReader(body))
+	if err != nil {
+		return result, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if sender.Credentials.BotToken != "" {
+		request.Header.Set("Authorization", "Bearer "+sender.Credentials.BotToken)
+	}
+
+	_, responseBody, err := doProviderRequest("slack", "Slack", request)
+	result.Raw = string(responseBody)
+	if err != nil {
+		return result, err
+	}
+
+	// Webhooks answer with a plain "ok".
+	if sender.Credentials.BotToken == "" {
+		return result, nil
+	}
+
+	var slackResp slackResponse
+	if err := json.Unmarshal(responseBody, &slackResp); err != nil {
+		return result, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	if !slackResp.OK {
+		return result, fmt.Errorf("Slack rejected the message: %s", slackResp.Error)
+	}
+
+	// Messages are identified by their channel and timestamp.
+	result.MessageID = slackResp.TS
+
+	return result, nil
+}
@@ -0,0 +1,196 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type SparkPostCredentials struct {
+	ApiKey string
+	Sender string
+	EU     bool
+}
+
+// SparkPostSender sends through the transmissions API. CampaignID and
+// SubstitutionData apply to every transmission it makes; copy the sender to
+// vary them per send.
+type SparkPostSender struct {
+	Credentials      *SparkPostCredentials
+	BaseURL          string
+	CampaignID       string
+	SubstitutionData map[string]any
+}
+
+func NewSparkPostSender(credentials *SparkPostCredentials) *SparkPostSender {
+	baseURL := "https://api.sparkpost.com"
+	if credentials.EU {
+		baseURL = "https://api.eu.sparkpost.com"
+	}
+
+	return &SparkPostSender{
+		Credentials: credentials,
+		BaseURL:     baseURL,
+	}
+}
+
+type sparkPostAddress struct {
+	Email    string `json:"email"`
+	Name     string `json:"name,omitempty"`
+	HeaderTo string `json:"header_to,omitempty"`
+}
+
+type sparkPostRecipient struct {
+	Address sparkPostAddress `json:"address"`
+	Tags    []string         `json:"tags,omitempty"`
+}
+
+type sparkPostAttachment struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Data string `json:"data"`
+}
+
+type sparkPostContent struct {
+	From         string                `json:"from"`
+	ReplyTo      string                `json:"reply_to,omitempty"`
+	Headers      map[string]string     `json:"headers,omitempty"`
+	Subject      string                `json:"subject"`
+	HTML         string                `json:"html,omitempty"`
+	AMPHTML      string                `json:"amp_html,omitempty"`
+	Text         string                `json:"text,omitempty"`
+	Attachments  []sparkPostAttachment `json:"attachments,omitempty"`
+	InlineImages []sparkPostAttachment `json:"inline_images,omitempty"`
+}
+
+type sparkPostRequest struct {
+	CampaignID       string               `json:"campaign_id,omitempty"`
+	Recipients       []sparkPostRecipient `json:"recipients"`
+	Content          sparkPostContent     `json:"content"`
+	Metadata         map[string]string    `json:"metadata,omitempty"`
+	SubstitutionData map[string]any       `json:"substitution_data,omitempty"`
+}
+
+type sparkPostResponse struct {
+	Results struct {
+		TotalRejectedRecipients int    `json:"total_rejected_recipients"`
+		TotalAcceptedRecipients int    `json:"total_accepted_recipients"`
+		ID                      string `json:"id"`
+	} `json:"results"`
+}
+
+func (sender *SparkPostSender) Send(ctx context.Context, message *Message) (result *SendResult, err error) {
+	defer func() { DefaultStats.RecordResult("sparkpost", "email", err) }()
+
+	message, err = suppressRecipients(ctx, ChannelEmail, message)
+	if err != nil {
+		return nil, err
+	}
+
+	email, err := prepareEmail(sender.Credentials.Sender, message)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := sparkPostRequest{
+		CampaignID: sender.CampaignID,
+		Content: sparkPostContent{
+			From:    email.From,
+			ReplyTo: email.ReplyTo,
+			Subject: email.Subject,
+		},
+		Metadata:         email.Metadata,
+		SubstitutionData: sender.SubstitutionData,
+	}
+
+	payload.Content.Text = email.Text
+	payload.Content.HTML = email.HTML
+	payload.Content.AMPHTML = email.AMP
+
+	tags := append([]string{}, email.Tags...)
+	if email.Category != "" {
+		tags = append([]string{string(email.Category)}, tags...)
+	}
+
+	// Cc and Bcc recipients carry the visible To list in header_to so that
+	// every copy shows the same headers.
+	headerTo := strings.Join(email.To, ", ")
+	for _, receiver := range email.recipients() {
+		name, address := splitEmailAddress(receiver)
+		payload.Recipients = append(payload.Recipients, sparkPostRecipient{
+			Address: sparkPostAddress{Email: address, Name: name, HeaderTo: headerTo},
+			Tags:    tags,
+		})
+	}
+	payload.Content.Headers = email.flatHeaders()
+	if len(email.Cc) > 0 {
+		if payload.Content.Headers == nil {
+			payload.Content.Headers = map[string]string{}
+		}
+		payload.Content.Headers["CC"] = strings.Join(email.Cc, ", ")
+	}
+
+	attachments, err := email.readAttachments()
+	if err != nil {
+		return nil, err
+	}
+	for _, attachment := range attachments {
+		if attachment.Inline {
+			payload.Content.InlineImages = append(payload.Content.InlineImages, sparkPostAttachment{
+				Name: attachment.ContentID,
+				Type: attachment.ContentType,
+				Data: base64.StdEncoding.EncodeToString(attachment.Data),
+			})
+			continue
+		}
+		payload.Content.Attachments = append(payload.Content.Attachments, sparkPostAttachment{
+			Name: attachment.Name,
+			Type: attachment.ContentType,
+			Data: base64.StdEncoding.EncodeToString(attachment.Data),
+		})
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode SparkPost request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		strings.TrimSuffix(sender.BaseURL, "/")+"/api/v1/transmissions",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("Authorization", sender.Credentials.ApiKey)
+
+	_, responseBody, err := doProviderRequest("sparkpost", "SparkPost", request)
+	if err != nil {
+		return nil, err
+	}
+
+	var sparkPostResp sparkPostResponse
+	if err := json.Unmarshal(responseBody, &sparkPostResp); err != nil {
+		return nil, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	if sparkPostResp.Results.TotalAcceptedRecipients == 0 {
+		return nil, fmt.Errorf("SparkPost rejected all %d recipients", sparkPostResp.Results.TotalRejectedRecipients)
+	}
+
+	return &SendResult{
+		Provider:   "sparkpost",
+		MessageID:  sparkPostResp.Results.ID,
+		Recipients: email.recipients(),
+		Raw:        string(responseBody),
+	}, nil
+}
@@ -0,0 +1,243 @@
+package messagingutilities
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type ErrorCategory string
+
+const (
+	ErrorCategoryValidation ErrorCategory = "validation"
+	ErrorCategoryNetwork    ErrorCategory = "network"
+	ErrorCategoryTimeout    ErrorCategory = "timeout"
+	ErrorCategoryCancelled  ErrorCategory = "cancelled"
+	ErrorCategoryProvider   ErrorCategory = "provider"
+)
+
+var errorCategories = [...]ErrorCategory{
+	ErrorCategoryValidation,
+	ErrorCategoryNetwork,
+	ErrorCategoryTimeout,
+	ErrorCategoryCancelled,
+	ErrorCategoryProvider,
+}
+
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (err *ValidationError) Error() string {
+	return err.Message
+}
+
+func CategorizeError(err error) ErrorCategory {
+	var validationError *ValidationError
+	var validationErrors ValidationErrors
+	var sizeError *MessageSizeError
+	var netError net.Error
+
+	switch {
+	case errors.As(err, &validationError), errors.As(err, &validationErrors), errors.As(err, &sizeError),
+		errors.Is(err, ErrSuppressed):
+		return ErrorCategoryValidation
+	case errors.Is(err, context.Canceled):
+		return ErrorCategoryCancelled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorCategoryTimeout
+	case errors.As(err, &netError):
+		if netError.Timeout() {
+			return ErrorCategoryTimeout
+		}
+		return ErrorCategoryNetwork
+	default:
+		return ErrorCategoryProvider
+	}
+}
+
+const statsBucketCount = 60
+
+type statsBucket struct {
+	minute    int64
+	attempted atomic.Uint64
+	succeeded atomic.Uint64
+}
+
+type providerCounters struct {
+	attempted    atomic.Uint64
+	succeeded    atomic.Uint64
+	retried      atomic.Uint64
+	deduplicated atomic.Uint64
+	failed       [len(errorCategories)]atomic.Uint64
+	buckets      [statsBucketCount]atomic.Pointer[statsBucket]
+}
+
+// bucket returns the bucket for the minute of now. A slot holding an older
+// minute is replaced with a fresh bucket rather than zeroed, so counts added
+// concurrently to the new minute are never reset.
+func (counters *providerCounters) bucket(now time.Time) *statsBucket {
+	minute := now.Unix() / 60
+	slot := &counters.buckets[minute%statsBucketCount]
+
+	for {
+		current := slot.Load()
+		if current != nil && current.minute == minute {
+			return current
+		}
+
+		fresh := &statsBucket{minute: minute}
+		if slot.CompareAndSwap(current, fresh) {
+			return fresh
+		}
+	}
+}
+
+type ProviderStats struct {
+	Provider          string                   `json:"provider"`
+	Channel           string                   `json:"channel"`
+	Attempted         uint64                   `json:"attempted"`
+	Succeeded         uint64                   `json:"succeeded"`
+	Failed            uint64                   `json:"failed"`
+	FailedByCategory  map[ErrorCategory]uint64 `json:"failedByCategory"`
+	Retried           uint64                   `json:"retried"`
+	Deduplicated      uint64                   `json:"deduplicated"`
+	RecentAttempted   uint64                   `json:"recentAttempted"`
+	RecentSuccessRate float64                  `json:"recentSuccessRate"`
+}
+
+type StatsSnapshot struct {
+	TakenAt   time.Time       `json:"takenAt"`
+	Window    time.Duration   `json:"window"`
+	Providers []ProviderStats `json:"providers"`
+}
+
+type statsKey struct {
+	provider string
+	channel  string
+}
+
+type StatsCollector struct {
+	Window   time.Duration
+	counters sync.Map
+}
+
+var DefaultStats = &StatsCollector{Window: 15 * time.Minute}
+
+func Stats() StatsSnapshot {
+	return DefaultStats.Stats()
+}
+
+func ResetStats() {
+	DefaultStats.Reset()
+}
+
+func (collector *StatsCollector) get(provider, channel string) *providerCounters {
+	key := statsKey{provider: provider, channel: channel}
+	if counters, ok := collector.counters.Load(key); ok {
+		return counters.(*providerCounters)
+	}
+
+	counters, _ := collector.counters.LoadOrStore(key, &providerCounters{})
+
+	return counters.(*providerCounters)
+}
+
+func (collector *StatsCollector) RecordResult(provider, channel string, err error) {
+	counters := collector.get(provider, channel)
+	bucket := counters.bucket(time.Now())
+
+	counters.attempted.Add(1)
+	bucket.attempted.Add(1)
+
+	if err == nil {
+		counters.succeeded.Add(1)
+		bucket.succeeded.Add(1)
+		return
+	}
+
+	category := CategorizeError(err)
+	for index, candidate := range errorCategories {
+		if candidate == category {
+			counters.failed[index].Add(1)
+		}
+	}
+}
+
+func (collector *StatsCollector) RecordRetry(provider, channel string) {
+	collector.get(provider, channel).retried.Add(1)
+}
+
+func (collector *StatsCollector) RecordDeduplicated(provider, channel string) {
+	collector.get(provider, channel).deduplicated.Add(1)
+}
+
+func (collector *StatsCollector) Stats() StatsSnapshot {
+	now := time.Now()
+	window := collector.Window
+	if window <= 0 || window > statsBucketCount*time.Minute {
+		window = statsBucketCount * time.Minute
+	}
+	oldestMinute := now.Unix()/60 - int64(window/time.Minute) + 1
+
+	snapshot := StatsSnapshot{
+		TakenAt:   now,
+		Window:    window,
+		Providers: []ProviderStats{},
+	}
+
+	collector.counters.Range(func(key, value any) bool {
+		statsKey := key.(statsKey)
+		counters := value.(*providerCounters)
+
+		stats := ProviderStats{
+			Provider:         statsKey.provider,
+			Channel:          statsKey.channel,
+			Attempted:        counters.attempted.Load(),
+			Succeeded:        counters.succeeded.Load(),
+			FailedByCategory: map[ErrorCategory]uint64{},
+			Retried:          counters.retried.Load(),
+			Deduplicated:     counters.deduplicated.Load(),
+		}
+
+		for index, category := range errorCategories {
+			if failed := counters.failed[index].Load(); failed > 0 {
+				stats.FailedByCategory[category] = failed
+				stats.Failed += failed
+			}
+		}
+
+		var recentSucceeded uint64
+		for index := range counters.buckets {
+			bucket := counters.buckets[index].Load()
+			if bucket != nil && bucket.minute >= oldestMinute {
+				stats.RecentAttempted += bucket.attempted.Load()
+				recentSucceeded += bucket.succeeded.Load()
+			}
+		}
+		if stats.RecentAttempted > 0 {
+			stats.RecentSuccessRate = float64(recentSucceeded) / float64(stats.RecentAttempted)
+		}
+
+		snapshot.Providers = append(snapshot.Providers, stats)
+		return true
+	})
+
+	sort.Slice(snapshot.Providers, func(i, j int) bool {
+		if snapshot.Providers[i].Provider != snapshot.Providers[j].Provider {
+			return snapshot.Providers[i].Provider < snapshot.Providers[j].Provider
+		}
+		return snapshot.Providers[i].Channel < snapshot.Providers[j].Channel
+	})
+
+	return snapshot
+}
+
+func (collector *StatsCollector) Reset() {
+	collector.counters.Clear()
+}
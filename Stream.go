@@ -0,0 +1,98 @@
+package messagingutilities
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"sync"
+)
+
+type StreamOptions[T any] struct {
+	Concurrency int
+	OnResult    func(item T, err error)
+}
+
+type StreamSummary struct {
+	Pulled     int
+	Sent       int
+	Failed     int
+	StopReason error
+}
+
+func SendStream[T any](
+	ctx context.Context,
+	next func() (T, bool, error),
+	send func(ctx context.Context, item T) error,
+	options StreamOptions[T],
+) *StreamSummary {
+	if options.Concurrency <= 0 {
+		options.Concurrency = 1
+	}
+
+	summary := &StreamSummary{}
+	mutex := sync.Mutex{}
+	slots := make(chan struct{}, options.Concurrency)
+	waitGroup := sync.WaitGroup{}
+
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			summary.StopReason = err
+			break
+		}
+
+		item, ok, err := next()
+		if err != nil {
+			<-slots
+			summary.StopReason = fmt.Errorf("Message source failed: %w", err)
+			break
+		}
+		if !ok {
+			<-slots
+			break
+		}
+		summary.Pulled++
+
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			defer func() { <-slots }()
+
+			err := send(ctx, item)
+
+			mutex.Lock()
+			if err == nil {
+				summary.Sent++
+			} else {
+				summary.Failed++
+			}
+			mutex.Unlock()
+
+			if options.OnResult != nil {
+				options.OnResult(item, err)
+			}
+		}()
+	}
+
+	waitGroup.Wait()
+
+	return summary
+}
+
+func SendStreamSeq[T any](
+	ctx context.Context,
+	source iter.Seq2[T, error],
+	send func(ctx context.Context, item T) error,
+	options StreamOptions[T],
+) *StreamSummary {
+	next, stop := iter.Pull2(source)
+	defer stop()
+
+	return SendStream(ctx, func() (T, bool, error) {
+		item, err, ok := next()
+		return item, ok, err
+	}, send, options)
+}
@@ -0,0 +1,374 @@
+package messagingutilities
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrSuppressed = errors.New("All recipients are on the suppression list")
+
+type SuppressionReason string
+
+const (
+	SuppressionReasonBounce      SuppressionReason = "bounce"
+	SuppressionReasonComplaint   SuppressionReason = "complaint"
+	SuppressionReasonUnsubscribe SuppressionReason = "unsubscribe"
+	SuppressionReasonManual      SuppressionReason = "manual"
+)
+
+type Suppression struct {
+	Channel   Channel           `json:"channel"`
+	Address   string            `json:"address"`
+	Reason    SuppressionReason `json:"reason"`
+	Detail    string            `json:"detail,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+type SuppressionList interface {
+	Suppress(ctx context.Context, suppression Suppression) error
+	Unsuppress(ctx context.Context, channel Channel, address string) error
+	IsSuppressed(ctx context.Context, channel Channel, address string) (bool, error)
+}
+
+// DefaultSuppressionList is consulted by every email and SMS sender before
+// sending. Suppressed recipients are dropped from the message, and the send
+// fails with ErrSuppressed when no To recipient is left.
+var DefaultSuppressionList SuppressionList
+
+// normalizeSuppressedAddress makes email lookups case-insensitive and phone
+// numbers match in local and international formats.
+func normalizeSuppressedAddress(channel Channel, address string) string {
+	address = strings.TrimSpace(address)
+	switch channel {
+	case ChannelEmail:
+		address = strings.ToLower(address)
+	case ChannelSMS, ChannelWhatsApp:
+		address = phoneNumberKey(address)
+	}
+
+	return address
+}
+
+type MemorySuppressionList struct {
+	mutex        sync.RWMutex
+	suppressions map[string]Suppression
+}
+
+func NewMemorySuppressionList() *MemorySuppressionList {
+	return &MemorySuppressionList{suppressions: map[string]Suppression{}}
+}
+
+func (list *MemorySuppressionList) Suppress(ctx context.Context, suppression Suppression) error {
+	if suppression.CreatedAt.IsZero() {
+		suppression.CreatedAt = time.Now()
+	}
+	suppression.Address = normalizeSuppressedAddress(suppression.Channel, suppression.Address)
+
+	list.mutex.Lock()
+	defer list.mutex.Unlock()
+
+	list.suppressions[string(suppression.Channel)+"|"+suppression.Address] = suppression
+
+	return nil
+}
+
+func (list *MemorySuppressionList) Unsuppress(ctx context.Context, channel Channel, address string) error {
+	list.mutex.Lock()
+	defer list.mutex.Unlock()
+
+	delete(list.suppressions, string(channel)+"|"+normalizeSuppressedAddress(channel, address))
+
+	return nil
+}
+
+func (list *MemorySuppressionList) IsSuppressed(ctx context.Context, channel Channel, address string) (bool, error) {
+	list.mutex.RLock()
+	defer list.mutex.RUnlock()
+
+	_, ok := list.suppressions[string(channel)+"|"+normalizeSuppressedAddress(channel, address)]
+
+	return ok, nil
+}
+
+func (list *MemorySuppressionList) Suppressions() []Suppression {
+	list.mutex.RLock()
+	defer list.mutex.RUnlock()
+
+	return slices.Collect(maps.Values(list.suppressions))
+}
+
+// FileSuppressionList keeps the list in memory and writes it to a JSON file
+// after every change. The file is replaced atomically, so a crash leaves
+// either the old or the new list.
+type FileSuppressionList struct {
+	path   string
+	memory *MemorySuppressionList
+	// writeMutex orders file writes so an older snapshot never replaces a
+	// newer one.
+	writeMutex sync.Mutex
+}
+
+func OpenFileSuppressionList(path string) (*FileSuppressionList, error) {
+	list := &FileSuppressionList{path: path, memory: NewMemorySuppressionList()}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return list, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read suppression list: %w", err)
+	}
+
+	suppressions := []Suppression{}
+	if err := json.Unmarshal(data, &suppressions); err != nil {
+		return nil, fmt.Errorf("Failed to parse suppression list: %w", err)
+	}
+	for _, suppression := range suppressions {
+		list.memory.Suppress(context.Background(), suppression)
+	}
+
+	return list, nil
+}
+
+func (list *FileSuppressionList) Suppress(ctx context.Context, suppression Suppression) error {
+	list.writeMutex.Lock()
+	defer list.writeMutex.Unlock()
+
+	list.memory.Suppress(ctx, suppression)
+
+	return list.save()
+}
+
+func (list *FileSuppressionList) Unsuppress(ctx context.Context, channel Channel, address string) error {
+	list.writeMutex.Lock()
+	defer list.writeMutex.Unlock()
+
+	list.memory.Unsuppress(ctx, channel, address)
+
+	return list.save()
+}
+
+func (list *FileSuppressionList) IsSuppressed(ctx context.Context, channel Channel, address string) (bool, error) {
+	return list.memory.IsSuppressed(ctx, channel, address)
+}
+
+func (list *FileSuppressionList) Suppressions() []Suppression {
+	return list.memory.Suppressions()
+}
+
+func (list *FileSuppressionList) save() error {
+	suppressions := list.memory.Suppressions()
+	slices.SortFunc(suppressions, func(a, b Suppression) int {
+		return strings.Compare(string(a.Channel)+"|"+a.Address, string(b.Channel)+"|"+b.Address)
+	})
+
+	data, err := json.MarshalIndent(suppressions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(list.path), filepath.Base(list.path)+".*")
+	if err != nil {
+		return fmt.Errorf("Failed to write suppression list: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("Failed to write suppression list: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("Failed to write suppression list: %w", err)
+	}
+	if err := os.Rename(file.Name(), list.path); err != nil {
+		return fmt.Errorf("Failed to write suppression list: %w", err)
+	}
+
+	return nil
+}
+
+// SQLSuppressionList stores one row per suppressed address. The expected
+// table is:
+//
+//	CREATE TABLE suppressions (
+//		channel    VARCHAR(32)  NOT NULL,
+//		address    VARCHAR(320) NOT NULL,
+//		reason     VARCHAR(32)  NOT NULL,
+//		detail     TEXT         NOT NULL,
+//		created_at TIMESTAMP    NOT NULL,
+//		PRIMARY KEY (channel, address)
+//	);
+type SQLSuppressionList struct {
+	DB          *sql.DB
+	Table       string
+	Placeholder func(index int) string
+}
+
+func NewSQLSuppressionList(db *sql.DB, table string) *SQLSuppressionList {
+	return &SQLSuppressionList{
+		DB:    db,
+		Table: table,
+		Placeholder: func(index int) string {
+			return "?"
+		},
+	}
+}
+
+func (list *SQLSuppressionList) Suppress(ctx context.Context, suppression Suppression) error {
+	if suppression.CreatedAt.IsZero() {
+		suppression.CreatedAt = time.Now()
+	}
+	suppression.Address = normalizeSuppressedAddress(suppression.Channel, suppression.Address)
+
+	transaction, err := list.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("Failed to suppress address: %w", err)
+	}
+	defer transaction.Rollback()
+
+	if err := list.delete(ctx, transaction, suppression.Channel, suppression.Address); err != nil {
+		return fmt.Errorf("Failed to suppress address: %w", err)
+	}
+
+	query := fmt.Sprintf(
+		"INSERT INTO %s (channel, address, reason, detail, created_at) VALUES (%s, %s, %s, %s, %s)",
+		list.Table,
+		list.Placeholder(1),
+		list.Placeholder(2),
+		list.Placeholder(3),
+		list.Placeholder(4),
+		list.Placeholder(5),
+	)
+	_, err = transaction.ExecContext(
+		ctx,
+		query,
+		string(suppression.Channel),
+		suppression.Address,
+		string(suppression.Reason),
+		suppression.Detail,
+		suppression.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to suppress address: %w", err)
+	}
+
+	if err := transaction.Commit(); err != nil {
+		return fmt.Errorf("Failed to suppress address: %w", err)
+	}
+
+	return nil
+}
+
+func (list *SQLSuppressionList) Unsuppress(ctx context.Context, channel Channel, address string) error {
+	if err := list.delete(ctx, list.DB, channel, normalizeSuppressedAddress(channel, address)); err != nil {
+		return fmt.Errorf("Failed to unsuppress address: %w", err)
+	}
+
+	return nil
+}
+
+func (list *SQLSuppressionList) delete(
+	ctx context.Context,
+	executor interface {
+		ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	},
+	channel Channel,
+	address string,
+) error {
+	query := fmt.Sprintf(
+		"DELETE FROM %s WHERE channel = %s AND address = %s",
+		list.Table,
+		list.Placeholder(1),
+		list.Placeholder(2),
+	)
+	_, err := executor.ExecContext(ctx, query, string(channel), address)
+
+	return err
+}
+
+func (list *SQLSuppressionList) IsSuppressed(ctx context.Context, channel Channel, address string) (bool, error) {
+	query := fmt.Sprintf(
+		"SELECT 1 FROM %s WHERE channel = %s AND address = %s",
+		list.Table,
+		list.Placeholder(1),
+		list.Placeholder(2),
+	)
+
+	var found int
+	err := list.DB.QueryRowContext(ctx, query, string(channel), normalizeSuppressedAddress(channel, address)).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("Failed to read suppression list: %w", err)
+	}
+
+	return true, nil
+}
+
+// suppressRecipients drops the recipients on DefaultSuppressionList from
+// message, returning a copy when any were dropped.
+func suppressRecipients(ctx context.Context, channel Channel, message *Message) (*Message, error) {
+	list := DefaultSuppressionList
+	if list == nil || message == nil || len(message.To) == 0 {
+		return message, nil
+	}
+
+	changed := false
+	filter := func(addresses []string) ([]string, error) {
+		if len(addresses) == 0 {
+			return addresses, nil
+		}
+
+		kept := []string{}
+		for _, address := range addresses {
+			lookup := address
+			if channel == ChannelEmail {
+				_, lookup = splitEmailAddress(address)
+			}
+
+			suppressed, err := list.IsSuppressed(ctx, channel, lookup)
+			if err != nil {
+				return nil, err
+			}
+			if suppressed {
+				changed = true
+				continue
+			}
+			kept = append(kept, address)
+		}
+
+		return kept, nil
+	}
+
+	copied := *message
+	var err error
+	if copied.To, err = filter(message.To); err != nil {
+		return nil, err
+	}
+	if copied.Cc, err = filter(message.Cc); err != nil {
+		return nil, err
+	}
+	if copied.Bcc, err = filter(message.Bcc); err != nil {
+		return nil, err
+	}
+
+	if !changed {
+		return message, nil
+	}
+	if len(copied.To) == 0 {
+		return nil, ErrSuppressed
+	}
+
+	return &copied, nil
+}
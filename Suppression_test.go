@@ -0,0 +1,65 @@
+package messagingutilities
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestStopKeywordSuppressesSends(t *testing.T) {
+	DefaultPhoneCountryCode = "254"
+	defer func() { DefaultPhoneCountryCode = "" }()
+
+	suppressions := NewMemorySuppressionList()
+	DefaultSuppressionList = suppressions
+	defer func() { DefaultSuppressionList = nil }()
+
+	// Replies go through the same suppression check as any other send.
+	replies := []string{}
+	processor := NewKeywordProcessor(suppressions, SMSSenderFunc(func(ctx context.Context, receiver, text string) error {
+		message := NewSMS().To(receiver).Text(text).Build()
+		_, err := sendEachMessage(ctx, ChannelSMS, "test", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+			replies = append(replies, text)
+			return RecipientResult{}, nil
+		})
+		return err
+	}))
+
+	inbound := ParseTwilioInboundSMS(url.Values{"From": {"+254712345678"}, "Body": {" stop! "}})
+	event, err := processor.Process(context.Background(), inbound)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if event.ReplyError != "" || len(replies) != 1 || replies[0] != processor.Replies[KeywordActionStop] {
+		t.Errorf("got STOP replies %q with error %q, want the STOP confirmation", replies, event.ReplyError)
+	}
+
+	sent := []string{}
+	send := func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		sent = append(sent, receiver)
+		return RecipientResult{}, nil
+	}
+
+	for _, receiver := range []string{"0712345678", "254712345678", "+254 712 345 678"} {
+		message := NewSMS().To(receiver).Text("Hello").Build()
+		if _, err := sendEachMessage(context.Background(), ChannelSMS, "test", message, send); !errors.Is(err, ErrSuppressed) {
+			t.Errorf("sending to %s: got %v, want ErrSuppressed", receiver, err)
+		}
+	}
+	if len(sent) != 0 {
+		t.Errorf("sent to suppressed receivers %v", sent)
+	}
+
+	inbound.Text = "START"
+	if event, err = processor.Process(context.Background(), inbound); err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if event.ReplyError != "" || len(replies) != 2 {
+		t.Errorf("got START reply error %q, want the START confirmation sent", event.ReplyError)
+	}
+	message := NewSMS().To("0712345678").Text("Hello").Build()
+	if _, err := sendEachMessage(context.Background(), ChannelSMS, "test", message, send); err != nil {
+		t.Errorf("sending after START: %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// TeamsCredentials posts through the URL of a Teams incoming webhook or of
+// a Workflows "post to a channel when a webhook request is received" flow.
+type TeamsCredentials struct {
+	WebhookURL string
+}
+
+// TeamsSender posts messages as Adaptive Cards, with Subject as a bold title
+// above Text. A card of your own replaces the generated one when passed
+// with Payload("attachments", ...).
+type TeamsSender struct {
+	Credentials *TeamsCredentials
+}
+
+func NewTeamsSender(credentials *TeamsCredentials) *TeamsSender {
+	return &TeamsSender{Credentials: credentials}
+}
+
+type teamsTextBlock struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Wrap   bool   `json:"wrap"`
+	Size   string `json:"size,omitempty"`
+	Weight string `json:"weight,omitempty"`
+}
+
+type teamsCard struct {
+	Schema  string           `json:"$schema"`
+	Type    string           `json:"type"`
+	Version string           `json:"version"`
+	Body    []teamsTextBlock `json:"body"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsRequest struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+func (sender *TeamsSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	if sender.Credentials.WebhookURL == "" {
+		return nil, &ValidationError{Field: "webhookUrl", Message: "Teams webhook URL cannot be empty"}
+	}
+	if message != nil && len(message.To) > 0 {
+		return nil, &ValidationError{Field: "to", Message: "Teams webhooks post to a fixed channel"}
+	}
+
+	message = withDefaultReceiver(message, "webhook")
+
+	return sendEachMessage(ctx, ChannelChat, "teams", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sender.send(ctx, message, text)
+	})
+}
+
+func (sender *TeamsSender) send(ctx context.Context, message *Message, text string) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("teams", "chat", err) }()
+
+	if text, err = chatText(message, text); err != nil {
+		return result, err
+	}
+
+	card := teamsCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		Body:    []teamsTextBlock{},
+	}
+	if message.Subject != "" {
+		card.Body = append(card.Body, teamsTextBlock{
+			Type:   "TextBlock",
+			Text:   message.Subject,
+			Wrap:   true,
+			Size:   "Medium",
+			Weight: "Bolder",
+		})
+	}
+	if text != "" {
+		card.Body = append(card.Body, teamsTextBlock{Type: "TextBlock", Text: text, Wrap: true})
+	}
+
+	body, err := encodeChatPayload(teamsRequest{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content:     card,
+		}},
+	}, message.Payload)
+	if err != nil {
+		return result, fmt.Errorf("Failed to encode Teams request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "POST", sender.Credentials.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return result, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	// Teams webhooks do not return an id for the posted message.
+	_, responseBody, err := doProviderRequest("teams", "Teams", request)
+	result.Raw = string(responseBody)
+
+	return result, err
+}
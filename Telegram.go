@@ -0,0 +1,239 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// TelegramCredentials authenticates a bot. ChatID is the chat, group or
+// channel messages are sent to when they have no receivers, such as
+// "-1001234567890" or "@alerts".
+type TelegramCredentials struct {
+	BotToken string
+	ChatID   string
+}
+
+// TelegramSender posts Text with ParseMode, "MarkdownV2", "Markdown" or
+// "HTML", or plain when it is empty. HTML bodies are always sent with the
+// HTML parse mode. Media URLs are sent as photos, videos, audio or
+// documents and attachments are uploaded as documents, with the text as the
+// caption of the first file when it fits.
+type TelegramSender struct {
+	Credentials *TelegramCredentials
+	BaseURL     string
+	ParseMode   string
+}
+
+func NewTelegramSender(credentials *TelegramCredentials) *TelegramSender {
+	return &TelegramSender{
+		Credentials: credentials,
+		BaseURL:     "https://api.telegram.org",
+	}
+}
+
+// telegramMaxCaption is the length limit of captions, in characters.
+const telegramMaxCaption = 1024
+
+type telegramRequest struct {
+	ChatID    string `json:"chat_id"`
+	Text      string `json:"text,omitempty"`
+	Caption   string `json:"caption,omitempty"`
+	ParseMode string `json:"parse_mode,omitempty"`
+	Photo     string `json:"photo,omitempty"`
+	Video     string `json:"video,omitempty"`
+	Audio     string `json:"audio,omitempty"`
+	Document  string `json:"document,omitempty"`
+}
+
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+	Result      struct {
+		MessageID int64 `json:"message_id"`
+	} `json:"result"`
+}
+
+func (sender *TelegramSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	if sender.Credentials.BotToken == "" {
+		return nil, &ValidationError{Field: "botToken", Message: "Bot token cannot be empty"}
+	}
+
+	attachments, err := readMessageAttachments(message)
+	if err != nil {
+		return nil, err
+	}
+
+	message = withDefaultReceiver(message, sender.Credentials.ChatID)
+
+	return sendEachMessage(ctx, ChannelChat, "telegram", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sender.send(ctx, message, attachments, text, receiver)
+	})
+}
+
+func (sender *TelegramSender) send(
+	ctx context.Context,
+	message *Message,
+	attachments []emailAttachmentData,
+	text,
+	receiver string,
+) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("telegram", "chat", err) }()
+
+	parseMode := sender.ParseMode
+	if message.HTML != "" {
+		text, parseMode = message.HTML, "HTML"
+	}
+
+	// The text is sent as a caption when there are files and it fits,
+	// and as a message of its own before them otherwise.
+	caption := ""
+	hasFiles := len(message.MediaURLs) > 0 || len(attachments) > 0
+	if hasFiles && utf8.RuneCountInString(text) <= telegramMaxCaption {
+		caption, text = text, ""
+	}
+
+	raw := []string{}
+	record := func(messageID int64, body []byte) {
+		raw = append(raw, string(body))
+		result.Raw = strings.Join(raw, "\n")
+		if result.MessageID == "" {
+			result.MessageID = strconv.FormatInt(messageID, 10)
+		}
+	}
+
+	if text != "" {
+		messageID, body, err := sender.post(ctx, "sendMessage", telegramRequest{ChatID: receiver, Text: text, ParseMode: parseMode})
+		record(messageID, body)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	for _, mediaURL := range message.MediaURLs {
+		payload := telegramRequest{ChatID: receiver, Caption: caption}
+		if caption != "" {
+			payload.ParseMode = parseMode
+		}
+		method := ""
+		switch mediaTypeFromURL(mediaURL) {
+		case "image":
+			method, payload.Photo = "sendPhoto", mediaURL
+		case "video":
+			method, payload.Video = "sendVideo", mediaURL
+		case "audio":
+			method, payload.Audio = "sendAudio", mediaURL
+		default:
+			method, payload.Document = "sendDocument", mediaURL
+		}
+
+		messageID, body, err := sender.post(ctx, method, payload)
+		record(messageID, body)
+		if err != nil {
+			return result, err
+		}
+		caption = ""
+	}
+
+	for _, attachment := range attachments {
+		messageID, body, err := sender.upload(ctx, receiver, attachment, caption, parseMode)
+		record(messageID, body)
+		if err != nil {
+			return result, err
+		}
+		caption = ""
+	}
+
+	return result, nil
+}
+
+func (sender *TelegramSender) methodURL(method string) string {
+	return strings.TrimSuffix(sender.BaseURL, "/") + "/bot" + sender.Credentials.BotToken + "/" + method
+}
+
+func (sender *TelegramSender) post(ctx context.Context, method string, payload telegramRequest) (int64, []byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, nil, fmt.Errorf("Failed to encode Telegram request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "POST", sender.methodURL(method), bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	return sender.do(request)
+}
+
+// upload sends attachment as a document with a multipart request.
+func (sender *TelegramSender) upload(
+	ctx context.Context,
+	receiver string,
+	attachment emailAttachmentData,
+	caption,
+	parseMode string,
+) (int64, []byte, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("chat_id", receiver)
+	if caption != "" {
+		writer.WriteField("caption", caption)
+		if parseMode != "" {
+			writer.WriteField("parse_mode", parseMode)
+		}
+	}
+	part, err := writer.CreateFormFile("document", attachment.Name)
+	if err != nil {
+		return 0, nil, fmt.Errorf("Failed to encode Telegram request: %w", err)
+	}
+	part.Write(attachment.Data)
+	if err := writer.Close(); err != nil {
+		return 0, nil, fmt.Errorf("Failed to encode Telegram request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "POST", sender.methodURL("sendDocument"), body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return sender.do(request)
+}
+
+func (sender *TelegramSender) do(request *http.Request) (int64, []byte, error) {
+	_, responseBody, err := doProviderRequest("telegram", "Telegram", request)
+	if err != nil {
+		// The bot token is part of the URL, so it is kept out of errors.
+		var urlError *url.Error
+		if errors.As(err, &urlError) {
+			return 0, responseBody, fmt.Errorf("Failed to execute http request: %w", &url.Error{
+				Op:  urlError.Op,
+				URL: strings.ReplaceAll(urlError.URL, sender.Credentials.BotToken, "<token>"),
+				Err: urlError.Err,
+			})
+		}
+		return 0, responseBody, err
+	}
+
+	var telegramResp telegramResponse
+	if err := json.Unmarshal(responseBody, &telegramResp); err != nil {
+		return 0, responseBody, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	if !telegramResp.OK {
+		return 0, responseBody, fmt.Errorf("Telegram rejected the message: %s", telegramResp.Description)
+	}
+
+	return telegramResp.Result.MessageID, responseBody, nil
+}
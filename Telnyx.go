@@ -0,0 +1,126 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TelnyxCredentials needs at least one of SenderPhoneNumber and
+// MessagingProfileID. With only the profile set, Telnyx picks a number from
+// the profile's number pool.
+type TelnyxCredentials struct {
+	APIKey             string
+	MessagingProfileID string
+	SenderPhoneNumber  string
+}
+
+type TelnyxSender struct {
+	Credentials *TelnyxCredentials
+	BaseURL     string
+}
+
+func NewTelnyxSender(credentials *TelnyxCredentials) *TelnyxSender {
+	return &TelnyxSender{
+		Credentials: credentials,
+		BaseURL:     "https://api.telnyx.com",
+	}
+}
+
+type telnyxRequest struct {
+	From               string `json:"from,omitempty"`
+	MessagingProfileID string `json:"messaging_profile_id,omitempty"`
+	To                 string `json:"to"`
+	Text               string `json:"text"`
+}
+
+type telnyxResponse struct {
+	Data struct {
+		ID string `json:"id"`
+		To []struct {
+			PhoneNumber string `json:"phone_number"`
+			Status      string `json:"status"`
+		} `json:"to"`
+		Parts int `json:"parts"`
+		Cost  *struct {
+			Amount   string `json:"amount"`
+			Currency string `json:"currency"`
+		} `json:"cost"`
+	} `json:"data"`
+	Errors []struct {
+		Code   string `json:"code"`
+		Title  string `json:"title"`
+		Detail string `json:"detail"`
+	} `json:"errors"`
+}
+
+func (sender *TelnyxSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	if sender.Credentials.SenderPhoneNumber == "" && sender.Credentials.MessagingProfileID == "" {
+		return nil, &ValidationError{Field: "sender", Message: "A sender phone number or messaging profile is required"}
+	}
+
+	return sendEachSms(ctx, "telnyx", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sender.send(ctx, text, receiver)
+	})
+}
+
+func (sender *TelnyxSender) send(ctx context.Context, text, receiver string) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("telnyx", "sms", err) }()
+
+	body, err := json.Marshal(telnyxRequest{
+		From:               sender.Credentials.SenderPhoneNumber,
+		MessagingProfileID: sender.Credentials.MessagingProfileID,
+		To:                 receiver,
+		Text:               text,
+	})
+	if err != nil {
+		return result, fmt.Errorf("Failed to encode Telnyx request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		strings.TrimSuffix(sender.BaseURL, "/")+"/v2/messages",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return result, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("Authorization", "Bearer "+sender.Credentials.APIKey)
+
+	_, responseBody, err := doProviderRequest("telnyx", "Telnyx", request)
+	result.Raw = string(responseBody)
+	if err != nil {
+		return result, err
+	}
+
+	var telnyxResp telnyxResponse
+	if err := json.Unmarshal(responseBody, &telnyxResp); err != nil {
+		return result, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	if len(telnyxResp.Errors) > 0 {
+		return result, fmt.Errorf(
+			"Telnyx rejected the message (%s): %s",
+			telnyxResp.Errors[0].Code,
+			telnyxResp.Errors[0].Detail,
+		)
+	}
+
+	result.MessageID = telnyxResp.Data.ID
+	result.Segments = telnyxResp.Data.Parts
+	if len(telnyxResp.Data.To) > 0 {
+		result.Status = telnyxResp.Data.To[0].Status
+	}
+	if cost := telnyxResp.Data.Cost; cost != nil && cost.Amount != "" {
+		result.Cost = cost.Amount + " " + cost.Currency
+	}
+
+	return result, nil
+}
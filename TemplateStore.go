@@ -0,0 +1,284 @@
+package messagingutilities
+
+import (
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+var templateKinds = []string{"subject", "text", "html", "sms"}
+
+type TemplateStoreOptions struct {
+	// Reload re-reads every template before each render so edits show up
+	// without a restart. It is meant for development.
+	Reload bool
+}
+
+// TemplateStore loads named templates from a file system such as an
+// embed.FS or a directory. A template is the set of files
+// "<name>.subject.tmpl", "<name>.text.tmpl", "<name>.html.tmpl" and
+// "<name>.sms.tmpl", where name may include directories; any of them may be
+// missing. Files under "partials/" are shared with every template of the same
+// kind, html partials with HTML bodies and text partials with subjects, text
+// bodies and SMS. A page that defines a "content" block is rendered through
+// "layouts/<name>.<kind>.tmpl", or "layouts/default.<kind>.tmpl" when there is
+// none, for the html and text kinds.
+type TemplateStore struct {
+	files   fs.FS
+	options TemplateStoreOptions
+
+	mutex sync.RWMutex
+	email map[string]*compiledEmailTemplate
+	sms   map[string]templateExecutor
+}
+
+func NewTemplateStore(files fs.FS, options TemplateStoreOptions) (*TemplateStore, error) {
+	store := &TemplateStore{files: files, options: options}
+	if err := store.Reload(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func LoadTemplateDir(dir string, options TemplateStoreOptions) (*TemplateStore, error) {
+	return NewTemplateStore(os.DirFS(dir), options)
+}
+
+type templateSources struct {
+	pages    map[string]map[string]string
+	partials map[string][]string
+	layouts  map[string]map[string]string
+}
+
+// Reload parses every template again. The previous templates stay in use
+// when parsing fails.
+func (store *TemplateStore) Reload() error {
+	sources := templateSources{
+		pages:    map[string]map[string]string{},
+		partials: map[string][]string{},
+		layouts:  map[string]map[string]string{},
+	}
+
+	err := fs.WalkDir(store.files, ".", func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.HasSuffix(filePath, ".tmpl") {
+			return err
+		}
+
+		base := strings.TrimSuffix(filePath, ".tmpl")
+		kind := strings.TrimPrefix(path.Ext(base), ".")
+		name := strings.TrimSuffix(base, path.Ext(base))
+		if !slices.Contains(templateKinds, kind) || name == "" {
+			return nil
+		}
+
+		data, err := fs.ReadFile(store.files, filePath)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case strings.HasPrefix(filePath, "partials/"):
+			if kind != "html" {
+				kind = "text"
+			}
+			sources.partials[kind] = append(sources.partials[kind], string(data))
+		case strings.HasPrefix(filePath, "layouts/"):
+			if sources.layouts[kind] == nil {
+				sources.layouts[kind] = map[string]string{}
+			}
+			sources.layouts[kind][strings.TrimPrefix(name, "layouts/")] = string(data)
+		default:
+			if sources.pages[name] == nil {
+				sources.pages[name] = map[string]string{}
+			}
+			sources.pages[name][kind] = string(data)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to load templates: %w", err)
+	}
+
+	email := map[string]*compiledEmailTemplate{}
+	sms := map[string]templateExecutor{}
+	for name, kinds := range sources.pages {
+		compiled := &compiledEmailTemplate{}
+		for kind, source := range kinds {
+			executor, err := sources.compile(name, kind, source)
+			if err != nil {
+				return err
+			}
+
+			switch kind {
+			case "subject":
+				compiled.subject = executor
+			case "text":
+				compiled.text = executor
+			case "html":
+				compiled.html = executor
+			case "sms":
+				sms[name] = executor
+			}
+		}
+
+		if compiled.subject != nil || compiled.text != nil || compiled.html != nil {
+			email[name] = compiled
+		}
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.email = email
+	store.sms = sms
+
+	return nil
+}
+
+func (sources *templateSources) compile(name, kind, source string) (templateExecutor, error) {
+	layout := ""
+	if kind == "html" || kind == "text" {
+		layout = sources.layouts[kind][path.Base(name)]
+		if layout == "" {
+			layout = sources.layouts[kind]["default"]
+		}
+	}
+
+	partialKind := "text"
+	if kind == "html" {
+		partialKind = "html"
+	}
+
+	fileName := name + "." + kind + ".tmpl"
+	if kind == "html" {
+		root := htmltemplate.New(fileName).Option("missingkey=error")
+		for index, partial := range sources.partials[partialKind] {
+			if _, err := root.New("partial" + strconv.Itoa(index)).Parse(partial); err != nil {
+				return nil, fmt.Errorf("Invalid partial template for %s: %w", fileName, err)
+			}
+		}
+
+		// The layout is parsed first so that the page's "content" block
+		// replaces the layout's default.
+		entry := fileName
+		if layout != "" && definesContent(source) {
+			if _, err := root.New("layout").Parse(layout); err != nil {
+				return nil, fmt.Errorf("Invalid layout template for %s: %w", fileName, err)
+			}
+			entry = "layout"
+		}
+		if _, err := root.Parse(source); err != nil {
+			return nil, fmt.Errorf("Invalid template %s: %w", fileName, err)
+		}
+
+		return func(writer io.Writer, data any) error {
+			return root.ExecuteTemplate(writer, entry, data)
+		}, nil
+	}
+
+	root := template.New(fileName).Option("missingkey=error")
+	for index, partial := range sources.partials[partialKind] {
+		if _, err := root.New("partial" + strconv.Itoa(index)).Parse(partial); err != nil {
+			return nil, fmt.Errorf("Invalid partial template for %s: %w", fileName, err)
+		}
+	}
+
+	entry := fileName
+	if layout != "" && definesContent(source) {
+		if _, err := root.New("layout").Parse(layout); err != nil {
+			return nil, fmt.Errorf("Invalid layout template for %s: %w", fileName, err)
+		}
+		entry = "layout"
+	}
+	if _, err := root.Parse(source); err != nil {
+		return nil, fmt.Errorf("Invalid template %s: %w", fileName, err)
+	}
+
+	return func(writer io.Writer, data any) error {
+		return root.ExecuteTemplate(writer, entry, data)
+	}, nil
+}
+
+func definesContent(source string) bool {
+	parsed, err := template.New("").Parse(source)
+	return err == nil && parsed.Lookup("content") != nil
+}
+
+func (store *TemplateStore) RenderEmail(name string, data any, receivers ...string) (*Message, error) {
+	if store.options.Reload {
+		if err := store.Reload(); err != nil {
+			return nil, err
+		}
+	}
+
+	store.mutex.RLock()
+	compiled, ok := store.email[name]
+	store.mutex.RUnlock()
+	if !ok {
+		return nil, &ValidationError{Field: "template", Message: "Unknown template " + name}
+	}
+
+	return compiled.render(name, data, receivers)
+}
+
+func (store *TemplateStore) RenderSMS(name string, data any, receivers ...string) (*Message, error) {
+	if store.options.Reload {
+		if err := store.Reload(); err != nil {
+			return nil, err
+		}
+	}
+
+	store.mutex.RLock()
+	executor, ok := store.sms[name]
+	store.mutex.RUnlock()
+	if !ok {
+		return nil, &ValidationError{Field: "template", Message: "Unknown template " + name}
+	}
+
+	text, err := executeTemplate(executor, data)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to render SMS of %s: %w", name, err)
+	}
+
+	return &Message{Channel: ChannelSMS, To: receivers, Text: text}, nil
+}
+
+func (store *TemplateStore) SendEmail(
+	ctx context.Context,
+	sender Sender,
+	name string,
+	data any,
+	receivers ...string,
+) (*SendResult, error) {
+	message, err := store.RenderEmail(name, data, receivers...)
+	if err != nil {
+		return nil, err
+	}
+
+	return sender.Send(ctx, message)
+}
+
+func (store *TemplateStore) SendSMS(
+	ctx context.Context,
+	sender Sender,
+	name string,
+	data any,
+	receivers ...string,
+) (*SendResult, error) {
+	message, err := store.RenderSMS(name, data, receivers...)
+	if err != nil {
+		return nil, err
+	}
+
+	return sender.Send(ctx, message)
+}
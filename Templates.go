@@ -0,0 +1,148 @@
+package messagingutilities
+
+import (
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// EmailTemplate holds the sources of a templated email. Subject and Text are
+// rendered with text/template and HTML with html/template, so data is only
+// escaped in the HTML body. Any of them may be empty.
+type EmailTemplate struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type templateExecutor func(writer io.Writer, data any) error
+
+type compiledEmailTemplate struct {
+	subject templateExecutor
+	text    templateExecutor
+	html    templateExecutor
+}
+
+// TemplateRegistry parses templates once at registration and renders them
+// into messages. Missing data keys are reported as errors rather than being
+// rendered as "<no value>".
+type TemplateRegistry struct {
+	mutex     sync.RWMutex
+	templates map[string]*compiledEmailTemplate
+}
+
+func NewTemplateRegistry() *TemplateRegistry {
+	return &TemplateRegistry{templates: map[string]*compiledEmailTemplate{}}
+}
+
+var DefaultTemplates = NewTemplateRegistry()
+
+func RegisterEmailTemplate(name string, emailTemplate EmailTemplate) error {
+	return DefaultTemplates.Register(name, emailTemplate)
+}
+
+func (registry *TemplateRegistry) Register(name string, emailTemplate EmailTemplate) error {
+	if name == "" {
+		return &ValidationError{Field: "template", Message: "Template name cannot be empty"}
+	}
+
+	compiled := &compiledEmailTemplate{}
+	if emailTemplate.Subject != "" {
+		parsed, err := template.New(name + ".subject").Option("missingkey=error").Parse(emailTemplate.Subject)
+		if err != nil {
+			return fmt.Errorf("Invalid subject template %s: %w", name, err)
+		}
+		compiled.subject = parsed.Execute
+	}
+	if emailTemplate.Text != "" {
+		parsed, err := template.New(name + ".text").Option("missingkey=error").Parse(emailTemplate.Text)
+		if err != nil {
+			return fmt.Errorf("Invalid text template %s: %w", name, err)
+		}
+		compiled.text = parsed.Execute
+	}
+	if emailTemplate.HTML != "" {
+		parsed, err := htmltemplate.New(name + ".html").Option("missingkey=error").Parse(emailTemplate.HTML)
+		if err != nil {
+			return fmt.Errorf("Invalid HTML template %s: %w", name, err)
+		}
+		compiled.html = parsed.Execute
+	}
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	registry.templates[name] = compiled
+
+	return nil
+}
+
+// RenderEmail renders a registered template into an email message addressed
+// to receivers.
+func (registry *TemplateRegistry) RenderEmail(name string, data any, receivers ...string) (*Message, error) {
+	registry.mutex.RLock()
+	compiled, ok := registry.templates[name]
+	registry.mutex.RUnlock()
+	if !ok {
+		return nil, &ValidationError{Field: "template", Message: "Unknown template " + name}
+	}
+
+	return compiled.render(name, data, receivers)
+}
+
+func (compiled *compiledEmailTemplate) render(name string, data any, receivers []string) (*Message, error) {
+	message := &Message{Channel: ChannelEmail, To: receivers}
+	var err error
+	if compiled.subject != nil {
+		if message.Subject, err = executeTemplate(compiled.subject, data); err != nil {
+			return nil, fmt.Errorf("Failed to render subject of %s: %w", name, err)
+		}
+		// A subject is a single header line.
+		message.Subject = strings.Join(strings.Fields(message.Subject), " ")
+	}
+	if compiled.text != nil {
+		if message.Text, err = executeTemplate(compiled.text, data); err != nil {
+			return nil, fmt.Errorf("Failed to render text of %s: %w", name, err)
+		}
+	}
+	if compiled.html != nil {
+		if message.HTML, err = executeTemplate(compiled.html, data); err != nil {
+			return nil, fmt.Errorf("Failed to render HTML of %s: %w", name, err)
+		}
+	}
+
+	return message, nil
+}
+
+func executeTemplate(execute templateExecutor, data any) (string, error) {
+	builder := &strings.Builder{}
+	if err := execute(builder, data); err != nil {
+		return "", err
+	}
+
+	return builder.String(), nil
+}
+
+func SendTemplatedEmail(sender Sender, name string, data any, receivers ...string) (*SendResult, error) {
+	return SendTemplatedEmailContext(context.Background(), sender, name, data, receivers...)
+}
+
+// SendTemplatedEmailContext renders a template from DefaultTemplates and
+// sends it.
+func SendTemplatedEmailContext(
+	ctx context.Context,
+	sender Sender,
+	name string,
+	data any,
+	receivers ...string,
+) (*SendResult, error) {
+	message, err := DefaultTemplates.RenderEmail(name, data, receivers...)
+	if err != nil {
+		return nil, err
+	}
+
+	return sender.Send(ctx, message)
+}
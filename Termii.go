@@ -0,0 +1,125 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TermiiChannel is the route a Termii message takes. The generic route does
+// not reach numbers on the Do-Not-Disturb registry, which the dnd route is
+// for; it is meant for transactional messages only.
+type TermiiChannel string
+
+const (
+	TermiiChannelGeneric TermiiChannel = "generic"
+	TermiiChannelDND     TermiiChannel = "dnd"
+)
+
+// TermiiCredentials sends from SenderID, a registered alphanumeric sender
+// ID. Channel defaults to TermiiChannelGeneric.
+type TermiiCredentials struct {
+	APIKey   string
+	SenderID string
+	Channel  TermiiChannel
+}
+
+// TermiiSender posts to BaseURL, which is shown on the Termii dashboard and
+// differs between accounts.
+type TermiiSender struct {
+	Credentials *TermiiCredentials
+	BaseURL     string
+}
+
+func NewTermiiSender(credentials *TermiiCredentials) *TermiiSender {
+	return &TermiiSender{
+		Credentials: credentials,
+		BaseURL:     "https://api.ng.termii.com",
+	}
+}
+
+type termiiRequest struct {
+	APIKey  string        `json:"api_key"`
+	To      string        `json:"to"`
+	From    string        `json:"from"`
+	SMS     string        `json:"sms"`
+	Type    string        `json:"type"`
+	Channel TermiiChannel `json:"channel"`
+}
+
+type termiiResponse struct {
+	Code      string `json:"code"`
+	MessageID string `json:"message_id"`
+	Message   string `json:"message"`
+}
+
+func (sender *TermiiSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	switch sender.Credentials.Channel {
+	case "", TermiiChannelGeneric, TermiiChannelDND:
+	default:
+		return nil, &ValidationError{Field: "channel", Message: "Unsupported Termii channel " + string(sender.Credentials.Channel)}
+	}
+
+	return sendEachSms(ctx, "termii", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sender.send(ctx, text, receiver)
+	})
+}
+
+func (sender *TermiiSender) send(ctx context.Context, text, receiver string) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("termii", "sms", err) }()
+
+	channel := sender.Credentials.Channel
+	if channel == "" {
+		channel = TermiiChannelGeneric
+	}
+
+	// Termii expects numbers in international format without the leading
+	// plus sign.
+	body, err := json.Marshal(termiiRequest{
+		APIKey:  sender.Credentials.APIKey,
+		To:      strings.TrimPrefix(strings.TrimSpace(receiver), "+"),
+		From:    sender.Credentials.SenderID,
+		SMS:     text,
+		Type:    "plain",
+		Channel: channel,
+	})
+	if err != nil {
+		return result, fmt.Errorf("Failed to encode Termii request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		strings.TrimSuffix(sender.BaseURL, "/")+"/api/sms/send",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return result, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+
+	_, responseBody, err := doProviderRequest("termii", "Termii", request)
+	result.Raw = string(responseBody)
+	if err != nil {
+		return result, err
+	}
+
+	var termiiResp termiiResponse
+	if err := json.Unmarshal(responseBody, &termiiResp); err != nil {
+		return result, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	if termiiResp.MessageID == "" {
+		return result, fmt.Errorf("Termii rejected the message: %s", termiiResp.Message)
+	}
+
+	result.MessageID = termiiResp.MessageID
+	result.Status = termiiResp.Message
+
+	return result, nil
+}
@@ -0,0 +1,334 @@
+package messagingutilities
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+type TrackingEventType string
+
+const (
+	TrackingEventOpen  TrackingEventType = "open"
+	TrackingEventClick TrackingEventType = "click"
+)
+
+type TrackingEvent struct {
+	Type       TrackingEventType `json:"type"`
+	MessageID  string            `json:"messageId"`
+	Recipient  string            `json:"recipient,omitempty"`
+	URL        string            `json:"url,omitempty"`
+	UserAgent  string            `json:"userAgent,omitempty"`
+	RemoteAddr string            `json:"remoteAddr,omitempty"`
+	OccurredAt time.Time         `json:"occurredAt"`
+}
+
+type TrackingStore interface {
+	SaveTrackingEvent(event TrackingEvent) error
+	TrackingEvents(messageID string) ([]TrackingEvent, error)
+}
+
+type MemoryTrackingStore struct {
+	mutex  sync.RWMutex
+	events map[string][]TrackingEvent
+}
+
+func NewMemoryTrackingStore() *MemoryTrackingStore {
+	return &MemoryTrackingStore{events: map[string][]TrackingEvent{}}
+}
+
+func (store *MemoryTrackingStore) SaveTrackingEvent(event TrackingEvent) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.events[event.MessageID] = append(store.events[event.MessageID], event)
+
+	return nil
+}
+
+func (store *MemoryTrackingStore) TrackingEvents(messageID string) ([]TrackingEvent, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return append([]TrackingEvent(nil), store.events[messageID]...), nil
+}
+
+type TrackingOptions struct {
+	// Secret signs tracking tokens so that links cannot be forged.
+	Secret []byte
+	// BaseURL is the public URL the handler is mounted at, for example
+	// "https://example.com/track". Tracking URLs are generated below it.
+	BaseURL string
+	Store   TrackingStore
+	OnEvent func(event TrackingEvent)
+}
+
+// TrackingHandler generates tracking URLs for outgoing email and serves them.
+// Requests for "<BaseURL>/open/<token>" record an open and return a
+// transparent 1x1 GIF, and requests for "<BaseURL>/click/<token>" record a
+// click and redirect to the original link.
+type TrackingHandler struct {
+	options TrackingOptions
+}
+
+func NewTrackingHandler(options TrackingOptions) (*TrackingHandler, error) {
+	if len(options.Secret) == 0 {
+		return nil, fmt.Errorf("Tracking secret is required")
+	}
+	if options.BaseURL == "" {
+		return nil, fmt.Errorf("Tracking base URL is required")
+	}
+	if options.Store == nil {
+		options.Store = NewMemoryTrackingStore()
+	}
+	options.BaseURL = strings.TrimSuffix(options.BaseURL, "/")
+
+	return &TrackingHandler{options: options}, nil
+}
+
+type trackingToken struct {
+	MessageID string `json:"m"`
+	Recipient string `json:"r,omitempty"`
+	URL       string `json:"u,omitempty"`
+}
+
+func (handler *TrackingHandler) encodeToken(token trackingToken) string {
+	return encodeSignedToken(handler.options.Secret, token)
+}
+
+func (handler *TrackingHandler) decodeToken(value string) (*trackingToken, bool) {
+	token := &trackingToken{}
+	if !decodeSignedToken(handler.options.Secret, value, token) || token.MessageID == "" {
+		return nil, false
+	}
+
+	return token, true
+}
+
+// encodeSignedToken encodes value as URL-safe JSON followed by its HMAC, for
+// use in a URL path segment.
+func encodeSignedToken(secret []byte, value any) string {
+	data, _ := json.Marshal(value)
+	payload := base64.RawURLEncoding.EncodeToString(data)
+
+	return payload + "." + signTokenPayload(secret, payload)
+}
+
+func decodeSignedToken(secret []byte, token string, value any) bool {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signTokenPayload(secret, payload))) {
+		return false
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return false
+	}
+
+	return json.Unmarshal(data, value) == nil
+}
+
+func signTokenPayload(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (handler *TrackingHandler) OpenURL(messageID, recipient string) string {
+	return handler.options.BaseURL + "/open/" + handler.encodeToken(trackingToken{MessageID: messageID, Recipient: recipient})
+}
+
+// InjectOpenPixel appends a tracking image for messageID and recipient to the
+// end of the HTML body.
+func (handler *TrackingHandler) InjectOpenPixel(document, messageID, recipient string) (string, error) {
+	root, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return "", fmt.Errorf("Failed to parse html: %w", err)
+	}
+
+	for node := range root.Descendants() {
+		if node.Type == html.ElementNode && node.Data == "body" {
+			node.AppendChild(&html.Node{
+				Type: html.ElementNode,
+				Data: "img",
+				Attr: []html.Attribute{
+					{Key: "src", Val: handler.OpenURL(messageID, recipient)},
+					{Key: "width", Val: "1"},
+					{Key: "height", Val: "1"},
+					{Key: "alt", Val: ""},
+					{Key: "style", Val: "display: block; width: 1px; height: 1px; border: 0"},
+				},
+			})
+			break
+		}
+	}
+
+	builder := &strings.Builder{}
+	if err := html.Render(builder, root); err != nil {
+		return "", fmt.Errorf("Failed to render html: %w", err)
+	}
+
+	return builder.String(), nil
+}
+
+func (handler *TrackingHandler) ClickURL(messageID, recipient, target string) string {
+	return handler.options.BaseURL + "/click/" + handler.encodeToken(trackingToken{MessageID: messageID, Recipient: recipient, URL: target})
+}
+
+// RewriteLinks points every http and https link of an HTML body at the click
+// tracking URL for messageID and recipient. Other links such as mailto: and
+// in-page anchors are left alone, as are links with a data-no-track
+// attribute.
+func (handler *TrackingHandler) RewriteLinks(document, messageID, recipient string) (string, error) {
+	root, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return "", fmt.Errorf("Failed to parse html: %w", err)
+	}
+
+	for node := range root.Descendants() {
+		if node.Type != html.ElementNode || node.Data != "a" {
+			continue
+		}
+
+		skip, hrefIndex := false, -1
+		for index, attribute := range node.Attr {
+			switch attribute.Key {
+			case "data-no-track":
+				skip = true
+			case "href":
+				hrefIndex = index
+			}
+		}
+		if skip || hrefIndex < 0 {
+			continue
+		}
+
+		target := strings.TrimSpace(node.Attr[hrefIndex].Val)
+		parsed, err := url.Parse(target)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			continue
+		}
+		node.Attr[hrefIndex].Val = handler.ClickURL(messageID, recipient, target)
+	}
+
+	builder := &strings.Builder{}
+	if err := html.Render(builder, root); err != nil {
+		return "", fmt.Errorf("Failed to render html: %w", err)
+	}
+
+	return builder.String(), nil
+}
+
+// trackingPixel is a transparent 1x1 GIF.
+var trackingPixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+func (handler *TrackingHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet && request.Method != http.MethodHead {
+		http.Error(writer, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	kind := path.Base(path.Dir(request.URL.Path))
+	token, valid := handler.decodeToken(path.Base(request.URL.Path))
+
+	switch kind {
+	case "open":
+		// Mail clients show a broken image for errors, so the pixel is
+		// served even when the token is not valid.
+		if valid {
+			handler.record(request, TrackingEventOpen, token)
+		}
+
+		writer.Header().Set("Content-Type", "image/gif")
+		writer.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate, max-age=0")
+		writer.Header().Set("Pragma", "no-cache")
+		writer.Header().Set("Expires", "0")
+		writer.WriteHeader(http.StatusOK)
+		writer.Write(trackingPixel)
+	case "click":
+		if !valid || token.URL == "" {
+			http.NotFound(writer, request)
+			return
+		}
+
+		handler.record(request, TrackingEventClick, token)
+		writer.Header().Set("Cache-Control", "no-store")
+		http.Redirect(writer, request, token.URL, http.StatusFound)
+	default:
+		http.NotFound(writer, request)
+	}
+}
+
+// record saves an event. Store failures are ignored since the response to the
+// recipient does not depend on them.
+func (handler *TrackingHandler) record(request *http.Request, eventType TrackingEventType, token *trackingToken) {
+	event := TrackingEvent{
+		Type:       eventType,
+		MessageID:  token.MessageID,
+		Recipient:  token.Recipient,
+		URL:        token.URL,
+		UserAgent:  request.UserAgent(),
+		RemoteAddr: request.RemoteAddr,
+		OccurredAt: time.Now(),
+	}
+
+	if err := handler.options.Store.SaveTrackingEvent(event); err != nil {
+		return
+	}
+
+	if handler.options.OnEvent != nil {
+		handler.options.OnEvent(event)
+	}
+}
+
+func (handler *TrackingHandler) TrackingEvents(messageID string) ([]TrackingEvent, error) {
+	return handler.options.Store.TrackingEvents(messageID)
+}
+
+// trackingMessageID returns the id a message is tracked under, taken from its
+// "trackingId" metadata or generated and stored there.
+func trackingMessageID(message *Message) (string, error) {
+	if id := message.Metadata["trackingId"]; id != "" {
+		return id, nil
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	id := fmt.Sprintf("%x", random)
+	metadata := map[string]string{}
+	maps.Copy(metadata, message.Metadata)
+	metadata["trackingId"] = id
+	message.Metadata = metadata
+
+	return id, nil
+}
+
+// trackingRecipient is the recipient recorded with tracking events, which is
+// only known when the message has a single recipient.
+func trackingRecipient(message *Message) string {
+	if len(message.To)+len(message.Cc)+len(message.Bcc) == 1 && len(message.To) == 1 {
+		return message.To[0]
+	}
+
+	return ""
+}
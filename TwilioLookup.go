@@ -0,0 +1,82 @@
+package messagingutilities
+
+import (
+	"context"
+	"strconv"
+
+	LOOKUPS_API "github.com/twilio/twilio-go/rest/lookups/v2"
+)
+
+// PhoneNumberInfo is the result of a Twilio Lookup. LineType is one of
+// Twilio's line types, such as "mobile", "landline", "fixedVoip" or
+// "nonFixedVoip", and is empty when the line type package failed, in which
+// case LineTypeErrorCode is set.
+type PhoneNumberInfo struct {
+	PhoneNumber       string
+	NationalFormat    string
+	CountryCode       string
+	Valid             bool
+	ValidationErrors  []string
+	LineType          string
+	Carrier           string
+	MobileCountryCode string
+	MobileNetworkCode string
+	LineTypeErrorCode string
+}
+
+// CanReceiveSMS reports whether the number is valid and not a landline.
+// Numbers of an unknown line type are assumed to receive SMS.
+func (info *PhoneNumberInfo) CanReceiveSMS() bool {
+	return info.Valid && info.LineType != "landline"
+}
+
+// LookupPhoneNumber validates number with Twilio Lookup v2 and fetches its
+// line type and carrier. countryCode is the ISO country used to read
+// numbers in national format and can be empty for E.164 numbers. Line type
+// intelligence is billed per request by Twilio.
+func LookupPhoneNumber(
+	ctx context.Context,
+	credentials *TwilioCredentials,
+	number string,
+	countryCode string,
+) (*PhoneNumberInfo, error) {
+	if number == "" {
+		return nil, &ValidationError{Field: "number", Message: "Phone number cannot be empty"}
+	}
+
+	params := &LOOKUPS_API.FetchPhoneNumberParams{}
+	params.SetFields("line_type_intelligence")
+	if countryCode != "" {
+		params.SetCountryCode(countryCode)
+	}
+
+	response, err := newTwilioRestClient(ctx, credentials).LookupsV2.FetchPhoneNumber(number, params)
+	if err != nil {
+		return nil, wrapTwilioError(err)
+	}
+
+	info := &PhoneNumberInfo{
+		Valid:             response.Valid,
+		LineType:          response.LineTypeIntelligence.Type,
+		Carrier:           response.LineTypeIntelligence.CarrierName,
+		MobileCountryCode: response.LineTypeIntelligence.MobileCountryCode,
+		MobileNetworkCode: response.LineTypeIntelligence.MobileNetworkCode,
+	}
+	if response.PhoneNumber != nil {
+		info.PhoneNumber = *response.PhoneNumber
+	}
+	if response.NationalFormat != nil {
+		info.NationalFormat = *response.NationalFormat
+	}
+	if response.CountryCode != nil {
+		info.CountryCode = *response.CountryCode
+	}
+	for _, validationError := range response.ValidationErrors {
+		info.ValidationErrors = append(info.ValidationErrors, string(validationError))
+	}
+	if code := response.LineTypeIntelligence.ErrorCode; code != 0 {
+		info.LineTypeErrorCode = strconv.Itoa(code)
+	}
+
+	return info, nil
+}
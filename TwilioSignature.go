@@ -0,0 +1,64 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/twilio/twilio-go/client"
+)
+
+// TwilioSignatureOptions configures NewTwilioSignatureMiddleware.
+// PublicBaseURL is the scheme and host Twilio was configured with, for
+// servers behind proxies that rewrite the host.
+type TwilioSignatureOptions struct {
+	AuthToken     string
+	PublicBaseURL string
+	MaxBodyBytes  int64
+}
+
+// ValidateTwilioSignature reports whether signature, the X-Twilio-Signature
+// header, was computed by Twilio over requestURL and body. It accepts form
+// bodies as well as JSON bodies signed through a bodySHA256 query parameter.
+func ValidateTwilioSignature(authToken, requestURL string, body []byte, signature string) bool {
+	if authToken == "" || signature == "" {
+		return false
+	}
+
+	validator := client.NewRequestValidator(authToken)
+
+	return validator.ValidateBody(requestURL, body, signature)
+}
+
+// NewTwilioSignatureMiddleware rejects requests without a valid Twilio
+// signature, such as forged status callbacks or inbound SMS webhooks, before
+// they reach next. The request body is restored for next to read.
+func NewTwilioSignatureMiddleware(options TwilioSignatureOptions) (func(http.Handler) http.Handler, error) {
+	if options.AuthToken == "" {
+		return nil, fmt.Errorf("Twilio auth token is required to validate webhook signatures")
+	}
+
+	if options.MaxBodyBytes <= 0 {
+		options.MaxBodyBytes = 1 << 20
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			body, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, options.MaxBodyBytes))
+			if err != nil {
+				http.Error(writer, "Could not read request body", http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			signature := request.Header.Get("X-Twilio-Signature")
+			if !ValidateTwilioSignature(options.AuthToken, webhookPublicURL(options.PublicBaseURL, request), body, signature) {
+				http.Error(writer, "Invalid signature", http.StatusForbidden)
+				return
+			}
+
+			request.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(writer, request)
+		})
+	}, nil
+}
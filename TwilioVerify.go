@@ -0,0 +1,113 @@
+package messagingutilities
+
+import (
+	"context"
+
+	VERIFY_API "github.com/twilio/twilio-go/rest/verify/v2"
+)
+
+type VerificationChannel string
+
+const (
+	VerificationChannelSMS      VerificationChannel = "sms"
+	VerificationChannelCall     VerificationChannel = "call"
+	VerificationChannelEmail    VerificationChannel = "email"
+	VerificationChannelWhatsApp VerificationChannel = "whatsapp"
+)
+
+// Verification is the state of a Twilio Verify verification. Status is
+// "pending" until the correct code is checked, then "approved"; Valid
+// reports whether the last checked code was correct.
+type Verification struct {
+	SID     string
+	To      string
+	Channel VerificationChannel
+	Status  string
+	Valid   bool
+}
+
+// StartTwilioVerification sends a one-time code to to, a phone number or an
+// email address depending on channel, through the Verify service
+// serviceSID.
+func StartTwilioVerification(
+	ctx context.Context,
+	credentials *TwilioCredentials,
+	serviceSID string,
+	to string,
+	channel VerificationChannel,
+) (*Verification, error) {
+	if serviceSID == "" {
+		return nil, &ValidationError{Field: "serviceSid", Message: "Verify service SID cannot be empty"}
+	}
+	if to == "" {
+		return nil, &ValidationError{Field: "to", Message: "Verification receiver cannot be empty"}
+	}
+	switch channel {
+	case VerificationChannelSMS, VerificationChannelCall, VerificationChannelEmail, VerificationChannelWhatsApp:
+	default:
+		return nil, &ValidationError{Field: "channel", Message: "Unsupported verification channel: " + string(channel)}
+	}
+
+	params := &VERIFY_API.CreateVerificationParams{}
+	params.SetTo(to)
+	params.SetChannel(string(channel))
+
+	response, err := newTwilioRestClient(ctx, credentials).VerifyV2.CreateVerification(serviceSID, params)
+	if err != nil {
+		return nil, wrapTwilioError(err)
+	}
+
+	return twilioVerification(response.Sid, response.To, response.Channel, response.Status, response.Valid), nil
+}
+
+// CheckTwilioVerification checks code against the pending verification of
+// to. A wrong code is not an error; it is reported through Valid.
+func CheckTwilioVerification(
+	ctx context.Context,
+	credentials *TwilioCredentials,
+	serviceSID string,
+	to string,
+	code string,
+) (*Verification, error) {
+	if serviceSID == "" {
+		return nil, &ValidationError{Field: "serviceSid", Message: "Verify service SID cannot be empty"}
+	}
+	if to == "" {
+		return nil, &ValidationError{Field: "to", Message: "Verification receiver cannot be empty"}
+	}
+	if code == "" {
+		return nil, &ValidationError{Field: "code", Message: "Verification code cannot be empty"}
+	}
+
+	params := &VERIFY_API.CreateVerificationCheckParams{}
+	params.SetTo(to)
+	params.SetCode(code)
+
+	response, err := newTwilioRestClient(ctx, credentials).VerifyV2.CreateVerificationCheck(serviceSID, params)
+	if err != nil {
+		return nil, wrapTwilioError(err)
+	}
+
+	return twilioVerification(response.Sid, response.To, response.Channel, response.Status, response.Valid), nil
+}
+
+func twilioVerification(sid, to, channel, status *string, valid *bool) *Verification {
+	verification := &Verification{}
+	if sid != nil {
+		verification.SID = *sid
+	}
+	if to != nil {
+		verification.To = *to
+	}
+	if channel != nil {
+		verification.Channel = VerificationChannel(*channel)
+	}
+	if status != nil {
+		verification.Status = *status
+	}
+	if valid != nil {
+		verification.Valid = *valid
+	}
+
+	return verification
+}
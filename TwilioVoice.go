@@ -0,0 +1,97 @@
+package messagingutilities
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+
+	TWILIO_API "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+// TwilioVoiceCall speaks Text to To, or runs the TwiML document at TwiMLURL
+// instead when it is set. Voice and Language select the text-to-speech
+// voice, such as "Polly.Joanna" and "en-US", and Loop repeats the message.
+type TwilioVoiceCall struct {
+	To             string
+	Text           string
+	Voice          string
+	Language       string
+	Loop           int
+	TwiMLURL       string
+	StatusCallback string
+}
+
+type twimlSay struct {
+	XMLName  xml.Name `xml:"Say"`
+	Voice    string   `xml:"voice,attr,omitempty"`
+	Language string   `xml:"language,attr,omitempty"`
+	Loop     int      `xml:"loop,attr,omitempty"`
+	Text     string   `xml:",chardata"`
+}
+
+func (call *TwilioVoiceCall) twiml() (string, error) {
+	document := struct {
+		XMLName xml.Name `xml:"Response"`
+		Say     twimlSay
+	}{Say: twimlSay{Voice: call.Voice, Language: call.Language, Loop: call.Loop, Text: call.Text}}
+
+	encoded, err := xml.Marshal(document)
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode TwiML: %w", err)
+	}
+
+	return xml.Header + string(encoded), nil
+}
+
+// SendTwilioVoiceCall places a call from the sender phone number of the
+// credentials. The returned result carries the call SID as SessionID.
+func SendTwilioVoiceCall(
+	ctx context.Context,
+	credentials *TwilioCredentials,
+	call TwilioVoiceCall,
+) (result *VoiceCallResult, err error) {
+	defer func() { DefaultStats.RecordResult("twilio", "voice", err) }()
+
+	if credentials.SenderPhoneNumber == "" {
+		return nil, &ValidationError{Field: "sender", Message: "Sender phone number cannot be empty"}
+	}
+	if call.To == "" {
+		return nil, &ValidationError{Field: "to", Message: "Receiver cannot be empty"}
+	}
+	if (call.Text == "") == (call.TwiMLURL == "") {
+		return nil, &ValidationError{Field: "text", Message: "Exactly one of the message text and the TwiML URL is required"}
+	}
+
+	params := &TWILIO_API.CreateCallParams{}
+	params.SetFrom(credentials.SenderPhoneNumber)
+	params.SetTo(call.To)
+	if call.TwiMLURL != "" {
+		params.SetUrl(call.TwiMLURL)
+	} else {
+		twiml, err := call.twiml()
+		if err != nil {
+			return nil, err
+		}
+		params.SetTwiml(twiml)
+	}
+	// The StatusCallback of the credentials receives message statuses, so it
+	// is not used for calls.
+	if call.StatusCallback != "" {
+		params.SetStatusCallback(call.StatusCallback)
+	}
+
+	response, err := newTwilioRestClient(ctx, credentials).Api.CreateCall(params)
+	if err != nil {
+		return nil, wrapTwilioError(err)
+	}
+
+	result = &VoiceCallResult{PhoneNumber: call.To}
+	if response.Sid != nil {
+		result.SessionID = *response.Sid
+	}
+	if response.Status != nil {
+		result.Status = *response.Status
+	}
+
+	return result, nil
+}
@@ -0,0 +1,236 @@
+package messagingutilities
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// USSDRequest is an Africa's Talking USSD callback. Text holds every input
+// of the session joined with "*", and Inputs the same inputs split, so the
+// first request of a session has no inputs.
+type USSDRequest struct {
+	SessionID   string
+	ServiceCode string
+	PhoneNumber string
+	NetworkCode string
+	Text        string
+	Inputs      []string
+}
+
+func ParseUSSDRequest(form url.Values) USSDRequest {
+	request := USSDRequest{
+		SessionID:   form.Get("sessionId"),
+		ServiceCode: form.Get("serviceCode"),
+		PhoneNumber: form.Get("phoneNumber"),
+		NetworkCode: form.Get("networkCode"),
+		Text:        form.Get("text"),
+	}
+	if request.Text != "" {
+		request.Inputs = strings.Split(request.Text, "*")
+	}
+
+	return request
+}
+
+// LastInput returns the input of the current request, or an empty string on
+// the first request of a session.
+func (request *USSDRequest) LastInput() string {
+	if len(request.Inputs) == 0 {
+		return ""
+	}
+
+	return request.Inputs[len(request.Inputs)-1]
+}
+
+// USSDResponse is either a menu that waits for more input or the final
+// screen of a session.
+type USSDResponse struct {
+	Text string
+	End  bool
+}
+
+func USSDContinue(text string) USSDResponse {
+	return USSDResponse{Text: text}
+}
+
+func USSDEnd(text string) USSDResponse {
+	return USSDResponse{Text: text, End: true}
+}
+
+func (response USSDResponse) String() string {
+	if response.End {
+		return "END " + response.Text
+	}
+
+	return "CON " + response.Text
+}
+
+// USSDSession is passed to the USSD callback. State is kept between the
+// requests of a session and can be changed by the callback.
+type USSDSession struct {
+	Request USSDRequest
+	State   map[string]string
+}
+
+type USSDSessionStore interface {
+	Load(ctx context.Context, sessionID string) (map[string]string, error)
+	Save(ctx context.Context, sessionID string, state map[string]string) error
+	Delete(ctx context.Context, sessionID string) error
+}
+
+type memoryUSSDSession struct {
+	state     map[string]string
+	expiresAt time.Time
+}
+
+// MemoryUSSDSessionStore forgets sessions that were not saved for ttl, so
+// sessions the network dropped do not accumulate.
+type MemoryUSSDSessionStore struct {
+	mutex    sync.Mutex
+	ttl      time.Duration
+	sessions map[string]memoryUSSDSession
+}
+
+func NewMemoryUSSDSessionStore(ttl time.Duration) *MemoryUSSDSessionStore {
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+
+	return &MemoryUSSDSessionStore{ttl: ttl, sessions: map[string]memoryUSSDSession{}}
+}
+
+func (store *MemoryUSSDSessionStore) Load(ctx context.Context, sessionID string) (map[string]string, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	session, ok := store.sessions[sessionID]
+	if !ok || time.Now().After(session.expiresAt) {
+		return map[string]string{}, nil
+	}
+
+	return maps.Clone(session.state), nil
+}
+
+func (store *MemoryUSSDSessionStore) Save(ctx context.Context, sessionID string, state map[string]string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := time.Now()
+	for id, session := range store.sessions {
+		if now.After(session.expiresAt) {
+			delete(store.sessions, id)
+		}
+	}
+	store.sessions[sessionID] = memoryUSSDSession{state: maps.Clone(state), expiresAt: now.Add(store.ttl)}
+
+	return nil
+}
+
+func (store *MemoryUSSDSessionStore) Delete(ctx context.Context, sessionID string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	delete(store.sessions, sessionID)
+
+	return nil
+}
+
+type USSDHandlerOptions struct {
+	// OnRequest answers every request of a session.
+	OnRequest func(ctx context.Context, session *USSDSession) (USSDResponse, error)
+	// Sessions keeps the state of sessions. A MemoryUSSDSessionStore is used
+	// when it is nil.
+	Sessions USSDSessionStore
+	// Auth is required, as USSD gateway callbacks are not signed.
+	Auth         *WebhookAuth
+	MaxBodyBytes int64
+}
+
+type USSDHandler struct {
+	options USSDHandlerOptions
+}
+
+func NewUSSDHandler(options USSDHandlerOptions) (*USSDHandler, error) {
+	if options.OnRequest == nil {
+		return nil, fmt.Errorf("A request callback is required")
+	}
+
+	if !options.Auth.configured() {
+		return nil, fmt.Errorf("USSD callbacks require a secret, basic auth or allowed networks")
+	}
+
+	if options.Sessions == nil {
+		options.Sessions = NewMemoryUSSDSessionStore(0)
+	}
+	if options.MaxBodyBytes <= 0 {
+		options.MaxBodyBytes = 1 << 20
+	}
+
+	return &USSDHandler{options: options}, nil
+}
+
+func (handler *USSDHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(writer, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !handler.options.Auth.authorize(writer, request) {
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, handler.options.MaxBodyBytes))
+	if err != nil {
+		http.Error(writer, "Could not read request body", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(writer, "Invalid form payload", http.StatusBadRequest)
+		return
+	}
+
+	ussdRequest := ParseUSSDRequest(form)
+	if ussdRequest.SessionID == "" {
+		http.Error(writer, "Missing session id", http.StatusBadRequest)
+		return
+	}
+
+	ctx := request.Context()
+	state, err := handler.options.Sessions.Load(ctx, ussdRequest.SessionID)
+	if err != nil {
+		http.Error(writer, "Could not load session", http.StatusInternalServerError)
+		return
+	}
+	if state == nil {
+		state = map[string]string{}
+	}
+
+	session := &USSDSession{Request: ussdRequest, State: state}
+	response, err := handler.options.OnRequest(ctx, session)
+	if err != nil {
+		http.Error(writer, "Could not process request", http.StatusInternalServerError)
+		return
+	}
+
+	if response.End {
+		err = handler.options.Sessions.Delete(ctx, ussdRequest.SessionID)
+	} else {
+		err = handler.options.Sessions.Save(ctx, ussdRequest.SessionID, session.State)
+	}
+	if err != nil {
+		http.Error(writer, "Could not save session", http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(writer, response.String())
+}
@@ -0,0 +1,251 @@
+package messagingutilities
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+type UnsubscribeRecord struct {
+	Address   string          `json:"address"`
+	Category  MessageCategory `json:"category,omitempty"`
+	OneClick  bool            `json:"oneClick"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+type UnsubscribeStore interface {
+	RecordUnsubscribe(ctx context.Context, record UnsubscribeRecord) error
+	IsUnsubscribed(ctx context.Context, address string, category MessageCategory) (bool, error)
+}
+
+type MemoryUnsubscribeStore struct {
+	mutex   sync.RWMutex
+	records map[string]UnsubscribeRecord
+}
+
+func NewMemoryUnsubscribeStore() *MemoryUnsubscribeStore {
+	return &MemoryUnsubscribeStore{records: map[string]UnsubscribeRecord{}}
+}
+
+func (store *MemoryUnsubscribeStore) RecordUnsubscribe(ctx context.Context, record UnsubscribeRecord) error {
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now()
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.records[strings.ToLower(record.Address)+"|"+string(record.Category)] = record
+
+	return nil
+}
+
+func (store *MemoryUnsubscribeStore) IsUnsubscribed(
+	ctx context.Context,
+	address string,
+	category MessageCategory,
+) (bool, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	_, ok := store.records[strings.ToLower(address)+"|"+string(category)]
+
+	return ok, nil
+}
+
+// ConsentUnsubscribeStore records unsubscribes as revoked email consent, so
+// a ConsentChecker stops further messages of the same category.
+type ConsentUnsubscribeStore struct {
+	Store ConsentStore
+}
+
+func (store *ConsentUnsubscribeStore) RecordUnsubscribe(ctx context.Context, record UnsubscribeRecord) error {
+	return store.Store.RecordConsent(ctx, ConsentRecord{
+		Channel:   ChannelEmail,
+		Address:   record.Address,
+		Category:  record.Category,
+		Status:    ConsentStatusRevoked,
+		Source:    "list-unsubscribe",
+		Timestamp: record.Timestamp,
+	})
+}
+
+func (store *ConsentUnsubscribeStore) IsUnsubscribed(
+	ctx context.Context,
+	address string,
+	category MessageCategory,
+) (bool, error) {
+	record, err := store.Store.GetConsent(ctx, ChannelEmail, address, category)
+	if err != nil {
+		return false, err
+	}
+
+	return record.Status == ConsentStatusRevoked, nil
+}
+
+// SuppressionUnsubscribeStore records unsubscribes as email suppressions, so
+// senders drop the address from every further message when List is
+// DefaultSuppressionList. Suppressions have no category, so an unsubscribe
+// from one category stops all email to the address.
+type SuppressionUnsubscribeStore struct {
+	List SuppressionList
+}
+
+func (store *SuppressionUnsubscribeStore) RecordUnsubscribe(ctx context.Context, record UnsubscribeRecord) error {
+	detail := "list-unsubscribe"
+	if record.Category != "" {
+		detail += ":" + string(record.Category)
+	}
+
+	return store.List.Suppress(ctx, Suppression{
+		Channel:   ChannelEmail,
+		Address:   record.Address,
+		Reason:    SuppressionReasonUnsubscribe,
+		Detail:    detail,
+		CreatedAt: record.Timestamp,
+	})
+}
+
+func (store *SuppressionUnsubscribeStore) IsUnsubscribed(
+	ctx context.Context,
+	address string,
+	category MessageCategory,
+) (bool, error) {
+	return store.List.IsSuppressed(ctx, ChannelEmail, address)
+}
+
+type UnsubscribeOptions struct {
+	// Secret signs unsubscribe tokens so that addresses cannot be
+	// unsubscribed by guessing URLs.
+	Secret []byte
+	// BaseURL is the public URL the handler is mounted at, for example
+	// "https://example.com/unsubscribe".
+	BaseURL string
+	// Mailto is an optional address added to List-Unsubscribe for clients
+	// that unsubscribe by email.
+	Mailto string
+	// Store records unsubscribes. When it is nil, they are recorded in
+	// DefaultSuppressionList if it is set, and in a MemoryUnsubscribeStore
+	// otherwise, which senders do not consult.
+	Store         UnsubscribeStore
+	OnUnsubscribe func(record UnsubscribeRecord)
+}
+
+// UnsubscribeHandler generates List-Unsubscribe headers and serves the URLs
+// they point to. One-click POST requests as described in RFC 8058 are
+// recorded immediately. A GET shows a confirmation form instead of
+// unsubscribing, since link scanners fetch URLs found in email.
+type UnsubscribeHandler struct {
+	options UnsubscribeOptions
+}
+
+func NewUnsubscribeHandler(options UnsubscribeOptions) (*UnsubscribeHandler, error) {
+	if len(options.Secret) == 0 {
+		return nil, fmt.Errorf("Unsubscribe secret is required")
+	}
+	if options.BaseURL == "" {
+		return nil, fmt.Errorf("Unsubscribe base URL is required")
+	}
+	if options.Store == nil && DefaultSuppressionList != nil {
+		options.Store = &SuppressionUnsubscribeStore{List: DefaultSuppressionList}
+	}
+	if options.Store == nil {
+		options.Store = NewMemoryUnsubscribeStore()
+	}
+	options.BaseURL = strings.TrimSuffix(options.BaseURL, "/")
+
+	return &UnsubscribeHandler{options: options}, nil
+}
+
+type unsubscribeToken struct {
+	Address  string          `json:"a"`
+	Category MessageCategory `json:"c,omitempty"`
+}
+
+func (handler *UnsubscribeHandler) URL(address string, category MessageCategory) string {
+	return handler.options.BaseURL + "/" + encodeSignedToken(handler.options.Secret, unsubscribeToken{address, category})
+}
+
+// Headers returns the List-Unsubscribe and List-Unsubscribe-Post headers for
+// a message of category sent to address.
+func (handler *UnsubscribeHandler) Headers(address string, category MessageCategory) map[string][]string {
+	targets := []string{"<" + handler.URL(address, category) + ">"}
+	if handler.options.Mailto != "" {
+		targets = append(targets, "<mailto:"+handler.options.Mailto+"?subject=unsubscribe>")
+	}
+
+	return map[string][]string{
+		"List-Unsubscribe":      {strings.Join(targets, ", ")},
+		"List-Unsubscribe-Post": {"List-Unsubscribe=One-Click"},
+	}
+}
+
+const unsubscribeConfirmPage = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Unsubscribe</title></head>
+<body><form method="post"><p>Do you want to stop receiving these emails?</p>
+<button type="submit" name="confirm" value="yes">Unsubscribe</button></form></body></html>
+`
+
+const unsubscribeDonePage = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Unsubscribed</title></head>
+<body><p>You have been unsubscribed.</p></body></html>
+`
+
+func (handler *UnsubscribeHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	token := unsubscribeToken{}
+	if !decodeSignedToken(handler.options.Secret, path.Base(request.URL.Path), &token) || token.Address == "" {
+		http.NotFound(writer, request)
+		return
+	}
+
+	switch request.Method {
+	case http.MethodGet, http.MethodHead:
+		writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(writer, unsubscribeConfirmPage)
+		return
+	case http.MethodPost:
+	default:
+		http.Error(writer, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(request.Body, 1<<20))
+	if err != nil {
+		http.Error(writer, "Could not read request body", http.StatusBadRequest)
+		return
+	}
+	form, _ := url.ParseQuery(string(body))
+
+	record := UnsubscribeRecord{
+		Address:   token.Address,
+		Category:  token.Category,
+		OneClick:  form.Get("List-Unsubscribe") == "One-Click",
+		Timestamp: time.Now(),
+	}
+
+	if err := handler.options.Store.RecordUnsubscribe(request.Context(), record); err != nil {
+		http.Error(writer, "Could not store unsubscribe", http.StatusInternalServerError)
+		return
+	}
+
+	if handler.options.OnUnsubscribe != nil {
+		handler.options.OnUnsubscribe(record)
+	}
+
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(writer, unsubscribeDonePage)
+}
+
+func (handler *UnsubscribeHandler) IsUnsubscribed(
+	ctx context.Context,
+	address string,
+	category MessageCategory,
+) (bool, error) {
+	return handler.options.Store.IsUnsubscribed(ctx, address, category)
+}
@@ -0,0 +1,102 @@
+package messagingutilities
+
+import (
+	"strings"
+)
+
+// Contact is rendered as a vCard 4.0 card. Name is the display name and
+// defaults to FirstName and LastName, then Organization, then the first
+// email address.
+type Contact struct {
+	Name         string   `json:"name,omitempty"`
+	FirstName    string   `json:"firstName,omitempty"`
+	LastName     string   `json:"lastName,omitempty"`
+	Organization string   `json:"organization,omitempty"`
+	Title        string   `json:"title,omitempty"`
+	Emails       []string `json:"emails,omitempty"`
+	Phones       []string `json:"phones,omitempty"`
+	URL          string   `json:"url,omitempty"`
+	Note         string   `json:"note,omitempty"`
+}
+
+func (contact *Contact) displayName() string {
+	if contact.Name != "" {
+		return contact.Name
+	}
+	if name := strings.TrimSpace(contact.FirstName + " " + contact.LastName); name != "" {
+		return name
+	}
+
+	if contact.Organization != "" {
+		return contact.Organization
+	}
+	if len(contact.Emails) > 0 {
+		_, address := splitEmailAddress(contact.Emails[0])
+		return address
+	}
+
+	return ""
+}
+
+// VCard renders the contact as a text/vcard document.
+func (contact *Contact) VCard() string {
+	lines := []string{
+		"BEGIN:VCARD",
+		"VERSION:4.0",
+		"PRODID:-//DerrohXy//MessagingUtilities//EN",
+		"FN:" + escapeICSText(contact.displayName()),
+	}
+
+	if contact.FirstName != "" || contact.LastName != "" {
+		lines = append(lines, "N:"+escapeICSText(contact.LastName)+";"+escapeICSText(contact.FirstName)+";;;")
+	}
+	if contact.Organization != "" {
+		lines = append(lines, "ORG:"+escapeICSText(contact.Organization))
+	}
+	if contact.Title != "" {
+		lines = append(lines, "TITLE:"+escapeICSText(contact.Title))
+	}
+	for _, email := range contact.Emails {
+		_, address := splitEmailAddress(email)
+		lines = append(lines, "EMAIL:"+escapeICSText(address))
+	}
+	for _, phone := range contact.Phones {
+		lines = append(lines, "TEL;VALUE=uri:tel:"+escapeICSText(phone))
+	}
+	if contact.URL != "" {
+		lines = append(lines, "URL:"+escapeICSText(contact.URL))
+	}
+	if contact.Note != "" {
+		lines = append(lines, "NOTE:"+escapeICSText(contact.Note))
+	}
+
+	lines = append(lines, "END:VCARD")
+
+	builder := &strings.Builder{}
+	for _, line := range lines {
+		builder.WriteString(foldICSLine(line))
+		builder.WriteString("\r\n")
+	}
+
+	return builder.String()
+}
+
+// vCardFileName names the attachment after the contact, keeping only
+// characters that are safe in file names.
+func (contact *Contact) vCardFileName() string {
+	name := strings.Map(func(character rune) rune {
+		switch {
+		case character == ' ' || character == '-' || character == '_' || character == '.':
+			return character
+		case character < ' ' || strings.ContainsRune(`/\:*?"<>|`, character):
+			return -1
+		}
+		return character
+	}, contact.displayName())
+	name = strings.Trim(strings.TrimSpace(name), ".")
+	if name == "" {
+		name = "contact"
+	}
+
+	return name + ".vcf"
+}
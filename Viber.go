@@ -0,0 +1,142 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// ViberCredentials authenticates a Viber bot. SenderName and AvatarURL are
+// shown as the author of messages.
+type ViberCredentials struct {
+	AuthToken  string
+	SenderName string
+	AvatarURL  string
+}
+
+// ViberSender sends messages to the Viber user ids in To. Image media URLs
+// are sent as pictures, with Text as the caption of the first one when it
+// fits, and other media URLs are sent as links.
+type ViberSender struct {
+	Credentials *ViberCredentials
+	BaseURL     string
+}
+
+func NewViberSender(credentials *ViberCredentials) *ViberSender {
+	return &ViberSender{
+		Credentials: credentials,
+		BaseURL:     "https://chatapi.viber.com/pa",
+	}
+}
+
+// viberMaxCaption is the length limit of picture captions, in characters.
+const viberMaxCaption = 768
+
+type viberSenderInfo struct {
+	Name   string `json:"name"`
+	Avatar string `json:"avatar,omitempty"`
+}
+
+type viberRequest struct {
+	Receiver      string          `json:"receiver"`
+	MinAPIVersion int             `json:"min_api_version"`
+	Sender        viberSenderInfo `json:"sender"`
+	Type          string          `json:"type"`
+	Text          string          `json:"text,omitempty"`
+	Media         string          `json:"media,omitempty"`
+}
+
+type viberResponse struct {
+	Status        int         `json:"status"`
+	StatusMessage string      `json:"status_message"`
+	MessageToken  json.Number `json:"message_token"`
+}
+
+func (sender *ViberSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	if sender.Credentials.AuthToken == "" {
+		return nil, &ValidationError{Field: "authToken", Message: "Auth token cannot be empty"}
+	}
+	if sender.Credentials.SenderName == "" {
+		return nil, &ValidationError{Field: "sender", Message: "Sender name cannot be empty"}
+	}
+
+	return sendEachMessage(ctx, ChannelChat, "viber", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sender.send(ctx, message, text, receiver)
+	})
+}
+
+func (sender *ViberSender) send(ctx context.Context, message *Message, text, receiver string) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("viber", "chat", err) }()
+
+	if text, err = chatText(message, text); err != nil {
+		return result, err
+	}
+
+	payloads := []viberRequest{}
+	for _, mediaURL := range message.MediaURLs {
+		if mediaTypeFromURL(mediaURL) != "image" {
+			payloads = append(payloads, viberRequest{Type: "url", Media: mediaURL})
+			continue
+		}
+		payload := viberRequest{Type: "picture", Media: mediaURL}
+		if utf8.RuneCountInString(text) <= viberMaxCaption {
+			payload.Text, text = text, ""
+		}
+		payloads = append(payloads, payload)
+	}
+	if text != "" {
+		payloads = append([]viberRequest{{Type: "text", Text: text}}, payloads...)
+	}
+
+	raw := []string{}
+	for _, payload := range payloads {
+		payload.Receiver = receiver
+		payload.MinAPIVersion = 1
+		payload.Sender = viberSenderInfo{Name: sender.Credentials.SenderName, Avatar: sender.Credentials.AvatarURL}
+
+		body, err := encodeChatPayload(payload, message.Payload)
+		if err != nil {
+			return result, fmt.Errorf("Failed to encode Viber request: %w", err)
+		}
+
+		request, err := http.NewRequestWithContext(
+			ctx,
+			"POST",
+			strings.TrimSuffix(sender.BaseURL, "/")+"/send_message",
+			bytes.NewReader(body),
+		)
+		if err != nil {
+			return result, fmt.Errorf("Failed to create http request: %w", err)
+		}
+
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("X-Viber-Auth-Token", sender.Credentials.AuthToken)
+
+		_, responseBody, err := doProviderRequest("viber", "Viber", request)
+		raw = append(raw, string(responseBody))
+		result.Raw = strings.Join(raw, "\n")
+		if err != nil {
+			return result, err
+		}
+
+		var viberResp viberResponse
+		if err := json.Unmarshal(responseBody, &viberResp); err != nil {
+			return result, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+		}
+
+		// Viber answers with status 200 and reports errors in the body.
+		if viberResp.Status != 0 {
+			return result, fmt.Errorf("Viber rejected the message: %s", viberResp.StatusMessage)
+		}
+
+		if result.MessageID == "" {
+			result.MessageID = viberResp.MessageToken.String()
+		}
+	}
+
+	return result, nil
+}
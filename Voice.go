@@ -0,0 +1,189 @@
+package messagingutilities
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type VoiceCallResult struct {
+	PhoneNumber string `json:"phoneNumber"`
+	Status      string `json:"status"`
+	SessionID   string `json:"sessionId,omitempty"`
+}
+
+type atVoiceResponse struct {
+	Entries []struct {
+		PhoneNumber string `json:"phoneNumber"`
+		Status      string `json:"status"`
+		SessionID   string `json:"sessionId"`
+	} `json:"entries"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// StartAfricasTalkingCall calls every receiver from from, an Africa's
+// Talking voice number. Once a call is answered, Africa's Talking asks the
+// callback URL of the number for the actions to run, which are written with
+// VoiceResponse.
+func StartAfricasTalkingCall(
+	ctx context.Context,
+	credentials *AfricasTalkingCredentials,
+	from string,
+	receivers []string,
+) (results []VoiceCallResult, err error) {
+	defer func() { DefaultStats.RecordResult("africastalking", "voice", err) }()
+
+	if from == "" {
+		return nil, &ValidationError{Field: "from", Message: "Caller number cannot be empty"}
+	}
+	if len(receivers) == 0 {
+		return nil, &ValidationError{Field: "to", Message: "Receivers cannot be empty"}
+	}
+
+	payload := url.Values{}
+	payload.Set("username", credentials.username())
+	payload.Set("from", from)
+	payload.Set("to", strings.Join(receivers, ","))
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		credentials.url("voice", "/call"),
+		strings.NewReader(payload.Encode()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("apiKey", credentials.ApiKey)
+
+	_, responseBody, err := doProviderRequest("africastalking", "Africa's talking", request)
+	if err != nil {
+		return nil, err
+	}
+
+	var atResp atVoiceResponse
+	if err := json.Unmarshal(responseBody, &atResp); err != nil {
+		return nil, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	if len(atResp.Entries) == 0 && atResp.ErrorMessage != "" && atResp.ErrorMessage != "None" {
+		return nil, fmt.Errorf("Africa's talking rejected the call: %s", atResp.ErrorMessage)
+	}
+
+	results = []VoiceCallResult{}
+	errs := []error{}
+	for _, entry := range atResp.Entries {
+		results = append(results, VoiceCallResult{
+			PhoneNumber: entry.PhoneNumber,
+			Status:      entry.Status,
+			SessionID:   entry.SessionID,
+		})
+		if entry.Status != "Queued" {
+			errs = append(errs, fmt.Errorf("%s: Call could not be started: %s", entry.PhoneNumber, entry.Status))
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// VoiceGetDigits collects keypad input after playing its prompt, Say or
+// Play, and posts the digits to CallbackURL as dtmfDigits.
+type VoiceGetDigits struct {
+	Say         string
+	Play        string
+	NumDigits   int
+	Timeout     int
+	FinishOnKey string
+	CallbackURL string
+}
+
+type voiceSay struct {
+	XMLName xml.Name `xml:"Say"`
+	Text    string   `xml:",chardata"`
+}
+
+type voicePlay struct {
+	XMLName xml.Name `xml:"Play"`
+	URL     string   `xml:"url,attr"`
+}
+
+type voiceGetDigits struct {
+	XMLName     xml.Name `xml:"GetDigits"`
+	NumDigits   int      `xml:"numDigits,attr,omitempty"`
+	Timeout     int      `xml:"timeout,attr,omitempty"`
+	FinishOnKey string   `xml:"finishOnKey,attr,omitempty"`
+	CallbackURL string   `xml:"callbackUrl,attr,omitempty"`
+	Prompt      any
+}
+
+// VoiceResponse builds the actions Africa's Talking runs on a call, in the
+// order they are added.
+type VoiceResponse struct {
+	actions []any
+}
+
+func NewVoiceResponse() *VoiceResponse {
+	return &VoiceResponse{}
+}
+
+func (response *VoiceResponse) Say(text string) *VoiceResponse {
+	response.actions = append(response.actions, voiceSay{Text: text})
+	return response
+}
+
+func (response *VoiceResponse) Play(audioURL string) *VoiceResponse {
+	response.actions = append(response.actions, voicePlay{URL: audioURL})
+	return response
+}
+
+func (response *VoiceResponse) GetDigits(digits VoiceGetDigits) *VoiceResponse {
+	action := voiceGetDigits{
+		NumDigits:   digits.NumDigits,
+		Timeout:     digits.Timeout,
+		FinishOnKey: digits.FinishOnKey,
+		CallbackURL: digits.CallbackURL,
+	}
+	switch {
+	case digits.Say != "":
+		action.Prompt = voiceSay{Text: digits.Say}
+	case digits.Play != "":
+		action.Prompt = voicePlay{URL: digits.Play}
+	}
+
+	response.actions = append(response.actions, action)
+	return response
+}
+
+func (response *VoiceResponse) XML() ([]byte, error) {
+	document := struct {
+		XMLName xml.Name `xml:"Response"`
+		Actions []any
+	}{Actions: response.actions}
+
+	encoded, err := xml.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode voice response: %w", err)
+	}
+
+	return append([]byte(xml.Header), encoded...), nil
+}
+
+// ServeHTTP answers a voice callback with the actions of the response.
+func (response *VoiceResponse) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	document, err := response.XML()
+	if err != nil {
+		http.Error(writer, "Could not encode response", http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/xml")
+	writer.Write(document)
+}
@@ -0,0 +1,582 @@
+package messagingutilities
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/subtle"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/twilio/twilio-go/client"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusQueued       DeliveryStatus = "queued"
+	DeliveryStatusSent         DeliveryStatus = "sent"
+	DeliveryStatusDelivered    DeliveryStatus = "delivered"
+	DeliveryStatusUndelivered  DeliveryStatus = "undelivered"
+	DeliveryStatusFailed       DeliveryStatus = "failed"
+	DeliveryStatusBounced      DeliveryStatus = "bounced"
+	DeliveryStatusComplained   DeliveryStatus = "complained"
+	DeliveryStatusUnsubscribed DeliveryStatus = "unsubscribed"
+	DeliveryStatusUnknown      DeliveryStatus = "unknown"
+)
+
+type DeliveryEvent struct {
+	Provider       string            `json:"provider"`
+	Channel        string            `json:"channel"`
+	MessageID      string            `json:"messageId"`
+	Recipient      string            `json:"recipient"`
+	Status         DeliveryStatus    `json:"status"`
+	ProviderStatus string            `json:"providerStatus"`
+	ErrorCode      string            `json:"errorCode,omitempty"`
+	ErrorMessage   string            `json:"errorMessage,omitempty"`
+	Permanent      bool              `json:"permanent,omitempty"`
+	ReceivedAt     time.Time         `json:"receivedAt"`
+	Raw            map[string]string `json:"raw,omitempty"`
+}
+
+type ReceiptStore interface {
+	SaveDeliveryEvent(event DeliveryEvent) error
+	DeliveryEvents(messageID string) ([]DeliveryEvent, error)
+}
+
+type MemoryReceiptStore struct {
+	mutex  sync.RWMutex
+	events map[string][]DeliveryEvent
+}
+
+func NewMemoryReceiptStore() *MemoryReceiptStore {
+	return &MemoryReceiptStore{events: map[string][]DeliveryEvent{}}
+}
+
+func (store *MemoryReceiptStore) SaveDeliveryEvent(event DeliveryEvent) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.events[event.MessageID] = append(store.events[event.MessageID], event)
+
+	return nil
+}
+
+func (store *MemoryReceiptStore) DeliveryEvents(messageID string) ([]DeliveryEvent, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return append([]DeliveryEvent{}, store.events[messageID]...), nil
+}
+
+var errInvalidWebhookSignature = errors.New("Invalid webhook signature")
+
+// WebhookAuth authenticates the callbacks of providers that do not sign
+// them. Every check that is set must pass: Secret must match the "secret"
+// query parameter of the callback URL, Username and Password the basic auth
+// credentials of the request, and the remote address one of
+// AllowedNetworks. Behind a reverse proxy, the remote address is only the
+// client's when the proxy rewrites it.
+type WebhookAuth struct {
+	Secret          string
+	Username        string
+	Password        string
+	AllowedNetworks []netip.Prefix
+}
+
+func (auth *WebhookAuth) configured() bool {
+	return auth != nil &&
+		(auth.Secret != "" || auth.Username != "" || auth.Password != "" || len(auth.AllowedNetworks) > 0)
+}
+
+// check reports the status to answer with when request fails a check, or
+// zero when it passes them all.
+func (auth *WebhookAuth) check(request *http.Request) int {
+	if len(auth.AllowedNetworks) > 0 {
+		host, _, err := net.SplitHostPort(request.RemoteAddr)
+		if err != nil {
+			host = request.RemoteAddr
+		}
+		address, err := netip.ParseAddr(host)
+		if err != nil || !slices.ContainsFunc(auth.AllowedNetworks, func(network netip.Prefix) bool {
+			return network.Contains(address.Unmap())
+		}) {
+			return http.StatusForbidden
+		}
+	}
+
+	if auth.Secret != "" {
+		secret := request.URL.Query().Get("secret")
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(auth.Secret)) != 1 {
+			return http.StatusForbidden
+		}
+	}
+
+	if auth.Username != "" || auth.Password != "" {
+		username, password, ok := request.BasicAuth()
+		usernameMatches := subtle.ConstantTimeCompare([]byte(username), []byte(auth.Username)) == 1
+		passwordMatches := subtle.ConstantTimeCompare([]byte(password), []byte(auth.Password)) == 1
+		if !ok || !usernameMatches || !passwordMatches {
+			return http.StatusUnauthorized
+		}
+	}
+
+	return 0
+}
+
+// authorize answers request with an error and reports false when it fails
+// a check.
+func (auth *WebhookAuth) authorize(writer http.ResponseWriter, request *http.Request) bool {
+	switch auth.check(request) {
+	case http.StatusUnauthorized:
+		writer.Header().Set("WWW-Authenticate", `Basic realm="webhooks"`)
+		http.Error(writer, "Unauthorized", http.StatusUnauthorized)
+		return false
+	case http.StatusForbidden:
+		http.Error(writer, "Forbidden", http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+// DeliveryWebhookOptions enables a route for each configured provider:
+// "twilio", "africastalking", "hubtel", "ses", "sendgrid" and "mailgun" as
+// the last path segment. SendGridVerificationKey is the key shown in the signed event
+// webhook settings and MailgunSigningKey the HTTP webhook signing key.
+// Africa's Talking and Hubtel do not sign their callbacks, so their routes
+// require AfricasTalkingAuth and HubtelAuth.
+type DeliveryWebhookOptions struct {
+	Twilio                  *TwilioCredentials
+	AfricasTalking          *AfricasTalkingCredentials
+	AfricasTalkingAuth      *WebhookAuth
+	Hubtel                  *HubtelCredentials
+	HubtelAuth              *WebhookAuth
+	SES                     *SNSWebhookOptions
+	SendGridVerificationKey string
+	MailgunSigningKey       string
+	Store                   ReceiptStore
+	// Suppressions receives permanent bounces, complaints and unsubscribes.
+	// They are not recorded when it is nil.
+	Suppressions  SuppressionList
+	OnEvent       func(event DeliveryEvent)
+	PublicBaseURL string
+	MaxBodyBytes  int64
+}
+
+type DeliveryWebhookHandler struct {
+	options         DeliveryWebhookOptions
+	twilioValidator *client.RequestValidator
+	sendGridKey     *ecdsa.PublicKey
+
+	snsMutex        sync.Mutex
+	snsCertificates map[string]*x509.Certificate
+}
+
+func NewDeliveryWebhookHandler(options DeliveryWebhookOptions) (*DeliveryWebhookHandler, error) {
+	if options.Store == nil {
+		return nil, fmt.Errorf("A receipt store is required")
+	}
+
+	if options.Twilio == nil && options.AfricasTalking == nil && options.Hubtel == nil && options.SES == nil &&
+		options.SendGridVerificationKey == "" && options.MailgunSigningKey == "" {
+		return nil, fmt.Errorf("At least one provider must be configured")
+	}
+
+	if options.MaxBodyBytes <= 0 {
+		options.MaxBodyBytes = 1 << 20
+	}
+
+	handler := &DeliveryWebhookHandler{options: options, snsCertificates: map[string]*x509.Certificate{}}
+
+	if options.Twilio != nil {
+		if options.Twilio.AuthToken == "" {
+			return nil, fmt.Errorf("Twilio auth token is required to validate webhook signatures")
+		}
+
+		validator := client.NewRequestValidator(options.Twilio.AuthToken)
+		handler.twilioValidator = &validator
+	}
+
+	if options.AfricasTalking != nil && options.AfricasTalking.username() == "" {
+		return nil, fmt.Errorf("Africa's talking username is required")
+	}
+
+	if options.AfricasTalking != nil && !options.AfricasTalkingAuth.configured() {
+		return nil, fmt.Errorf("Africa's talking webhooks require a secret, basic auth or allowed networks")
+	}
+
+	if options.Hubtel != nil && !options.HubtelAuth.configured() {
+		return nil, fmt.Errorf("Hubtel webhooks require a secret, basic auth or allowed networks")
+	}
+
+	if options.SendGridVerificationKey != "" {
+		publicKey, err := parseSendGridPublicKey(options.SendGridVerificationKey)
+		if err != nil {
+			return nil, err
+		}
+		handler.sendGridKey = publicKey
+	}
+
+	return handler, nil
+}
+
+// NewAfricasTalkingDeliveryHandler returns a handler for Africa's Talking
+// delivery reports alone, served at any path. options.AfricasTalking is
+// required and the other providers are ignored.
+func NewAfricasTalkingDeliveryHandler(options DeliveryWebhookOptions) (http.Handler, error) {
+	if options.AfricasTalking == nil {
+		return nil, fmt.Errorf("Africa's talking credentials are required")
+	}
+
+	handler, err := NewDeliveryWebhookHandler(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		handler.serve(writer, request, "africastalking")
+	}), nil
+}
+
+func (handler *DeliveryWebhookHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	handler.serve(writer, request, path.Base(request.URL.Path))
+}
+
+// serve handles a webhook of provider, the route name of a provider in
+// DeliveryWebhookOptions.
+func (handler *DeliveryWebhookHandler) serve(writer http.ResponseWriter, request *http.Request, provider string) {
+	if request.Method != http.MethodPost {
+		http.Error(writer, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var auth *WebhookAuth
+	switch provider {
+	case "africastalking":
+		auth = handler.options.AfricasTalkingAuth
+	case "hubtel":
+		auth = handler.options.HubtelAuth
+	}
+	if auth != nil && !auth.authorize(writer, request) {
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, handler.options.MaxBodyBytes))
+	if err != nil {
+		http.Error(writer, "Could not read request body", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var events []DeliveryEvent
+	switch provider {
+	case "twilio":
+		if handler.twilioValidator == nil {
+			http.NotFound(writer, request)
+			return
+		}
+
+		signature := request.Header.Get("X-Twilio-Signature")
+		if !handler.twilioValidator.ValidateBody(handler.publicURL(request), body, signature) {
+			http.Error(writer, "Invalid signature", http.StatusForbidden)
+			return
+		}
+
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			http.Error(writer, "Invalid form payload", http.StatusBadRequest)
+			return
+		}
+
+		if form.Get("AccountSid") != handler.options.Twilio.AccountSID {
+			http.Error(writer, "Unknown account", http.StatusForbidden)
+			return
+		}
+
+		events = []DeliveryEvent{parseTwilioDeliveryEvent(form)}
+	case "africastalking":
+		if handler.options.AfricasTalking == nil {
+			http.NotFound(writer, request)
+			return
+		}
+
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			http.Error(writer, "Invalid form payload", http.StatusBadRequest)
+			return
+		}
+
+		events = []DeliveryEvent{parseAfricasTalkingDeliveryEvent(form)}
+	case "hubtel":
+		if handler.options.Hubtel == nil {
+			http.NotFound(writer, request)
+			return
+		}
+
+		event, err := parseHubtelDeliveryEvent(body)
+		if err != nil {
+			http.Error(writer, "Invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+
+		events = []DeliveryEvent{event}
+	case "ses":
+		if handler.options.SES == nil {
+			http.NotFound(writer, request)
+			return
+		}
+
+		var ok bool
+		if events, ok = handler.handleSNS(writer, request, body); !ok {
+			return
+		}
+	case "sendgrid":
+		if handler.sendGridKey == nil {
+			http.NotFound(writer, request)
+			return
+		}
+
+		if !verifySendGridSignature(handler.sendGridKey, request, body) {
+			http.Error(writer, "Invalid signature", http.StatusForbidden)
+			return
+		}
+
+		events, err = parseSendGridDeliveryEvents(body)
+		if err != nil {
+			http.Error(writer, "Invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+	case "mailgun":
+		if handler.options.MailgunSigningKey == "" {
+			http.NotFound(writer, request)
+			return
+		}
+
+		event, ok, err := parseMailgunDeliveryEvent(handler.options.MailgunSigningKey, body)
+		if errors.Is(err, errInvalidWebhookSignature) {
+			http.Error(writer, "Invalid signature", http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			http.Error(writer, "Invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+		if ok {
+			events = []DeliveryEvent{event}
+		}
+	default:
+		http.NotFound(writer, request)
+		return
+	}
+
+	for _, event := range events {
+		if event.MessageID == "" && event.Channel == "sms" {
+			http.Error(writer, "Missing message id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	for _, event := range events {
+		if event.MessageID != "" {
+			if err := handler.options.Store.SaveDeliveryEvent(event); err != nil {
+				http.Error(writer, "Could not store delivery event", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := handler.suppress(request.Context(), event); err != nil {
+			http.Error(writer, "Could not update suppression list", http.StatusInternalServerError)
+			return
+		}
+
+		if handler.options.OnEvent != nil {
+			handler.options.OnEvent(event)
+		}
+	}
+
+	writer.WriteHeader(http.StatusOK)
+}
+
+// suppress adds the recipient of a permanent bounce, complaint or
+// unsubscribe to the suppression list.
+func (handler *DeliveryWebhookHandler) suppress(ctx context.Context, event DeliveryEvent) error {
+	list := handler.options.Suppressions
+	if list == nil || event.Recipient == "" {
+		return nil
+	}
+
+	var reason SuppressionReason
+	switch {
+	case event.Status == DeliveryStatusBounced && event.Permanent:
+		reason = SuppressionReasonBounce
+	case event.Status == DeliveryStatusComplained:
+		reason = SuppressionReasonComplaint
+	case event.Status == DeliveryStatusUnsubscribed:
+		reason = SuppressionReasonUnsubscribe
+	default:
+		return nil
+	}
+
+	return list.Suppress(ctx, Suppression{
+		Channel: Channel(event.Channel),
+		Address: event.Recipient,
+		Reason:  reason,
+		Detail:  event.Provider + ": " + event.ErrorMessage,
+	})
+}
+
+func (handler *DeliveryWebhookHandler) DeliveryEvents(messageID string) ([]DeliveryEvent, error) {
+	return handler.options.Store.DeliveryEvents(messageID)
+}
+
+func (handler *DeliveryWebhookHandler) LatestDeliveryEvent(messageID string) (*DeliveryEvent, error) {
+	events, err := handler.options.Store.DeliveryEvents(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	latest := events[0]
+	for _, event := range events[1:] {
+		if !event.ReceivedAt.Before(latest.ReceivedAt) {
+			latest = event
+		}
+	}
+
+	return &latest, nil
+}
+
+func (handler *DeliveryWebhookHandler) publicURL(request *http.Request) string {
+	return webhookPublicURL(handler.options.PublicBaseURL, request)
+}
+
+// webhookPublicURL rebuilds the URL a provider signed from publicBaseURL, or
+// from the request and its forwarded scheme when publicBaseURL is empty.
+func webhookPublicURL(publicBaseURL string, request *http.Request) string {
+	if publicBaseURL != "" {
+		return strings.TrimSuffix(publicBaseURL, "/") + request.URL.RequestURI()
+	}
+
+	scheme := "http"
+	if request.TLS != nil {
+		scheme = "https"
+	}
+	if forwarded := request.Header.Get("X-Forwarded-Proto"); forwarded != "" {
+		scheme = forwarded
+	}
+
+	return scheme + "://" + request.Host + request.URL.RequestURI()
+}
+
+func flattenForm(form url.Values) map[string]string {
+	raw := map[string]string{}
+	for key, values := range form {
+		if len(values) > 0 {
+			raw[key] = values[0]
+		}
+	}
+
+	return raw
+}
+
+func parseTwilioDeliveryEvent(form url.Values) DeliveryEvent {
+	providerStatus := form.Get("MessageStatus")
+	if providerStatus == "" {
+		providerStatus = form.Get("SmsStatus")
+	}
+
+	status := twilioDeliveryStatus(providerStatus)
+
+	channel := "sms"
+	if strings.HasPrefix(form.Get("To"), "whatsapp:") {
+		channel = "whatsapp"
+	}
+
+	return DeliveryEvent{
+		Provider:       "twilio",
+		Channel:        channel,
+		MessageID:      form.Get("MessageSid"),
+		Recipient:      form.Get("To"),
+		Status:         status,
+		ProviderStatus: providerStatus,
+		ErrorCode:      form.Get("ErrorCode"),
+		ErrorMessage:   form.Get("ErrorMessage"),
+		ReceivedAt:     time.Now(),
+		Raw:            flattenForm(form),
+	}
+}
+
+func twilioDeliveryStatus(providerStatus string) DeliveryStatus {
+	switch providerStatus {
+	case "accepted", "scheduled", "queued":
+		return DeliveryStatusQueued
+	case "sending", "sent":
+		return DeliveryStatusSent
+	case "delivered", "read":
+		return DeliveryStatusDelivered
+	case "undelivered":
+		return DeliveryStatusUndelivered
+	case "failed", "canceled":
+		return DeliveryStatusFailed
+	}
+
+	return DeliveryStatusUnknown
+}
+
+// africasTalkingPermanentFailures are the failure reasons of reports that
+// will fail again if the message is resent.
+var africasTalkingPermanentFailures = map[string]bool{
+	"InvalidLinkId":              true,
+	"UserIsInactive":             true,
+	"UserInBlackList":            true,
+	"UserAccountSuspended":       true,
+	"NotNetworkSubscriber":       true,
+	"UserNotSubscribedToProduct": true,
+	"UserDoesNotExist":           true,
+	"DoNotDisturbRejection":      true,
+}
+
+func parseAfricasTalkingDeliveryEvent(form url.Values) DeliveryEvent {
+	providerStatus := form.Get("status")
+	failureReason := form.Get("failureReason")
+
+	status := DeliveryStatusUnknown
+	switch providerStatus {
+	case "Submitted", "Buffered", "Sent":
+		status = DeliveryStatusSent
+	case "Success":
+		status = DeliveryStatusDelivered
+	case "AbsentSubscriber", "Expired":
+		status = DeliveryStatusUndelivered
+	case "Rejected", "Failed":
+		status = DeliveryStatusFailed
+	}
+
+	return DeliveryEvent{
+		Provider:       "africastalking",
+		Channel:        "sms",
+		MessageID:      form.Get("id"),
+		Recipient:      form.Get("phoneNumber"),
+		Status:         status,
+		ProviderStatus: providerStatus,
+		ErrorCode:      failureReason,
+		ErrorMessage:   failureReason,
+		Permanent:      africasTalkingPermanentFailures[failureReason],
+		ReceivedAt:     time.Now(),
+		// Raw keeps the networkCode and retryCount of the report.
+		Raw: flattenForm(form),
+	}
+}
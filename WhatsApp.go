@@ -0,0 +1,85 @@
+package messagingutilities
+
+import (
+	"context"
+	"strings"
+)
+
+// ContentTemplate is a provider-side template, which WhatsApp requires for
+// messages sent outside the 24-hour customer service window. Twilio Content
+// templates are selected by SID, with Variables filling their numbered
+// placeholders keyed "1", "2" and so on. WhatsApp Cloud API templates are
+// selected by Name and Language, with Components filling their parameters.
+type ContentTemplate struct {
+	SID        string              `json:"sid,omitempty"`
+	Variables  map[string]string   `json:"variables,omitempty"`
+	Name       string              `json:"name,omitempty"`
+	Language   string              `json:"language,omitempty"`
+	Components []TemplateComponent `json:"components,omitempty"`
+}
+
+// TemplateComponent fills the parameters of the "header", "body" or
+// "button" of a WhatsApp Cloud API template. Buttons also take a SubType,
+// such as "quick_reply" or "url", and their Index.
+type TemplateComponent struct {
+	Type       string              `json:"type"`
+	SubType    string              `json:"subType,omitempty"`
+	Index      int                 `json:"index,omitempty"`
+	Parameters []TemplateParameter `json:"parameters,omitempty"`
+}
+
+// TemplateParameter is a "text" parameter, or an "image", "video" or
+// "document" parameter linking to the file at URL. Button parameters use
+// the "payload" type for quick replies and "text" for URL suffixes.
+type TemplateParameter struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+// contentTemplateProviders are the senders that accept
+// Message.ContentTemplate.
+var contentTemplateProviders = map[string]bool{
+	"twilio":   true,
+	"whatsapp": true,
+}
+
+// TwilioWhatsAppSender sends WhatsApp messages through Twilio. Recipients
+// and SenderPhoneNumber are plain phone numbers; the "whatsapp:" prefix is
+// added when missing.
+type TwilioWhatsAppSender struct {
+	Credentials *TwilioCredentials
+}
+
+func NewTwilioWhatsAppSender(credentials *TwilioCredentials) *TwilioWhatsAppSender {
+	return &TwilioWhatsAppSender{Credentials: credentials}
+}
+
+func (sender *TwilioWhatsAppSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	if sender.Credentials.SenderPhoneNumber == "" && sender.Credentials.MessagingServiceSID == "" {
+		return nil, &ValidationError{Field: "sender", Message: "A sender phone number or messaging service is required"}
+	}
+
+	if message != nil && len(message.MediaURLs) > 0 {
+		if err := validateMediaURLs(ctx, message.MediaURLs, twilioMaxMedia, twilioMaxMediaBytes); err != nil {
+			return nil, err
+		}
+	}
+
+	return sendEachMessage(ctx, ChannelWhatsApp, "twilio", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sendTwilioMessage(ctx, sender.Credentials, ChannelWhatsApp, message, text, receiver)
+	})
+}
+
+func (sender *TwilioWhatsAppSender) Status(ctx context.Context, result *SendResult) ([]MessageStatus, error) {
+	return NewTwilioSender(sender.Credentials).Status(ctx, result)
+}
+
+func whatsAppAddress(number string) string {
+	number = strings.TrimSpace(number)
+	if strings.HasPrefix(number, "whatsapp:") {
+		return number
+	}
+
+	return "whatsapp:" + number
+}
@@ -0,0 +1,226 @@
+package messagingutilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// WhatsAppCloudCredentials authenticates with the Meta WhatsApp Cloud API.
+// PhoneNumberID is the id of the business phone number messages are sent
+// from, not the number itself.
+type WhatsAppCloudCredentials struct {
+	PhoneNumberID string
+	AccessToken   string
+}
+
+// WhatsAppCloudSender sends WhatsApp messages through the Cloud API. Each
+// media URL is sent as a separate message, with Text as the caption of the
+// first one.
+type WhatsAppCloudSender struct {
+	Credentials *WhatsAppCloudCredentials
+	BaseURL     string
+}
+
+func NewWhatsAppCloudSender(credentials *WhatsAppCloudCredentials) *WhatsAppCloudSender {
+	return &WhatsAppCloudSender{
+		Credentials: credentials,
+		BaseURL:     "https://graph.facebook.com/v21.0",
+	}
+}
+
+type whatsAppCloudText struct {
+	Body string `json:"body"`
+}
+
+type whatsAppCloudMedia struct {
+	Link    string `json:"link"`
+	Caption string `json:"caption,omitempty"`
+}
+
+type whatsAppCloudParameter struct {
+	Type     string              `json:"type"`
+	Text     string              `json:"text,omitempty"`
+	Payload  string              `json:"payload,omitempty"`
+	Image    *whatsAppCloudMedia `json:"image,omitempty"`
+	Video    *whatsAppCloudMedia `json:"video,omitempty"`
+	Document *whatsAppCloudMedia `json:"document,omitempty"`
+}
+
+type whatsAppCloudComponent struct {
+	Type       string                   `json:"type"`
+	SubType    string                   `json:"sub_type,omitempty"`
+	Index      string                   `json:"index,omitempty"`
+	Parameters []whatsAppCloudParameter `json:"parameters,omitempty"`
+}
+
+type whatsAppCloudTemplate struct {
+	Name     string `json:"name"`
+	Language struct {
+		Code string `json:"code"`
+	} `json:"language"`
+	Components []whatsAppCloudComponent `json:"components,omitempty"`
+}
+
+type whatsAppCloudRequest struct {
+	MessagingProduct string                 `json:"messaging_product"`
+	RecipientType    string                 `json:"recipient_type"`
+	To               string                 `json:"to"`
+	Type             string                 `json:"type"`
+	Text             *whatsAppCloudText     `json:"text,omitempty"`
+	Template         *whatsAppCloudTemplate `json:"template,omitempty"`
+	Image            *whatsAppCloudMedia    `json:"image,omitempty"`
+	Video            *whatsAppCloudMedia    `json:"video,omitempty"`
+	Audio            *whatsAppCloudMedia    `json:"audio,omitempty"`
+	Document         *whatsAppCloudMedia    `json:"document,omitempty"`
+}
+
+type whatsAppCloudResponse struct {
+	Messages []struct {
+		ID            string `json:"id"`
+		MessageStatus string `json:"message_status"`
+	} `json:"messages"`
+}
+
+func (sender *WhatsAppCloudSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	if sender.Credentials.PhoneNumberID == "" {
+		return nil, &ValidationError{Field: "sender", Message: "Phone number ID cannot be empty"}
+	}
+
+	var requests []whatsAppCloudRequest
+	if message != nil {
+		var err error
+		if requests, err = whatsAppCloudRequests(message); err != nil {
+			return nil, err
+		}
+	}
+
+	return sendEachMessage(ctx, ChannelWhatsApp, "whatsapp", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sender.send(ctx, requests, receiver)
+	})
+}
+
+// whatsAppCloudRequests returns the messages to send to every receiver,
+// without their receiver.
+func whatsAppCloudRequests(message *Message) ([]whatsAppCloudRequest, error) {
+	requests := []whatsAppCloudRequest{}
+
+	if template := message.ContentTemplate; template != nil {
+		if template.Name == "" || template.Language == "" {
+			return nil, &ValidationError{Field: "contentTemplate", Message: "Template name and language cannot be empty"}
+		}
+
+		cloudTemplate := &whatsAppCloudTemplate{Name: template.Name}
+		cloudTemplate.Language.Code = template.Language
+		for _, component := range template.Components {
+			cloudComponent := whatsAppCloudComponent{Type: component.Type, SubType: component.SubType}
+			if component.Type == "button" {
+				cloudComponent.Index = fmt.Sprint(component.Index)
+			}
+			for _, parameter := range component.Parameters {
+				cloudParameter := whatsAppCloudParameter{Type: parameter.Type}
+				switch parameter.Type {
+				case "image":
+					cloudParameter.Image = &whatsAppCloudMedia{Link: parameter.URL}
+				case "video":
+					cloudParameter.Video = &whatsAppCloudMedia{Link: parameter.URL}
+				case "document":
+					cloudParameter.Document = &whatsAppCloudMedia{Link: parameter.URL}
+				case "payload":
+					cloudParameter.Payload = parameter.Text
+				default:
+					cloudParameter.Text = parameter.Text
+				}
+				cloudComponent.Parameters = append(cloudComponent.Parameters, cloudParameter)
+			}
+			cloudTemplate.Components = append(cloudTemplate.Components, cloudComponent)
+		}
+
+		requests = append(requests, whatsAppCloudRequest{Type: "template", Template: cloudTemplate})
+	}
+
+	caption := message.Text
+	for _, mediaURL := range message.MediaURLs {
+		media := &whatsAppCloudMedia{Link: mediaURL, Caption: caption}
+		request := whatsAppCloudRequest{Type: mediaTypeFromURL(mediaURL)}
+		switch request.Type {
+		case "image":
+			request.Image = media
+		case "video":
+			request.Video = media
+		case "audio":
+			// Audio messages cannot have a caption.
+			media.Caption = ""
+			request.Audio = media
+		default:
+			request.Document = media
+		}
+		if media.Caption != "" {
+			caption = ""
+		}
+		requests = append(requests, request)
+	}
+
+	if caption != "" {
+		requests = append(requests, whatsAppCloudRequest{Type: "text", Text: &whatsAppCloudText{Body: caption}})
+	}
+
+	return requests, nil
+}
+
+func (sender *WhatsAppCloudSender) send(
+	ctx context.Context,
+	requests []whatsAppCloudRequest,
+	receiver string,
+) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("whatsapp", "whatsapp", err) }()
+
+	raw := []string{}
+	for _, payload := range requests {
+		payload.MessagingProduct = "whatsapp"
+		payload.RecipientType = "individual"
+		payload.To = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(receiver), "whatsapp:"), "+")
+
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return result, fmt.Errorf("Failed to encode WhatsApp request: %w", err)
+		}
+
+		request, err := http.NewRequestWithContext(
+			ctx,
+			"POST",
+			strings.TrimSuffix(sender.BaseURL, "/")+"/"+url.PathEscape(sender.Credentials.PhoneNumberID)+"/messages",
+			bytes.NewReader(body),
+		)
+		if err != nil {
+			return result, fmt.Errorf("Failed to create http request: %w", err)
+		}
+
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Authorization", "Bearer "+sender.Credentials.AccessToken)
+
+		_, responseBody, err := doProviderRequest("whatsapp", "WhatsApp", request)
+		raw = append(raw, string(responseBody))
+		result.Raw = strings.Join(raw, "\n")
+		if err != nil {
+			return result, err
+		}
+
+		var cloudResp whatsAppCloudResponse
+		if err := json.Unmarshal(responseBody, &cloudResp); err != nil {
+			return result, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+		}
+
+		// The first message of a receiver identifies the send.
+		if result.MessageID == "" && len(cloudResp.Messages) > 0 {
+			result.MessageID = cloudResp.Messages[0].ID
+			result.Status = cloudResp.Messages[0].MessageStatus
+		}
+	}
+
+	return result, nil
+}
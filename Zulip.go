@@ -0,0 +1,116 @@
+package messagingutilities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ZulipCredentials authenticates a bot on the organization at SiteURL, such
+// as "https://example.zulipchat.com". Stream is the stream messages are sent
+// to when they have no receivers.
+type ZulipCredentials struct {
+	SiteURL  string
+	BotEmail string
+	APIKey   string
+	Stream   string
+}
+
+// ZulipSender posts Text as Zulip markdown. Receivers that are email
+// addresses get a direct message and any other receiver names a stream,
+// with Subject as the topic, or Topic when a message has none.
+type ZulipSender struct {
+	Credentials *ZulipCredentials
+	Topic       string
+}
+
+func NewZulipSender(credentials *ZulipCredentials) *ZulipSender {
+	return &ZulipSender{
+		Credentials: credentials,
+		Topic:       "notifications",
+	}
+}
+
+type zulipResponse struct {
+	Result string `json:"result"`
+	Msg    string `json:"msg"`
+	ID     int64  `json:"id"`
+}
+
+func (sender *ZulipSender) Send(ctx context.Context, message *Message) (*SendResult, error) {
+	if sender.Credentials.SiteURL == "" {
+		return nil, &ValidationError{Field: "siteUrl", Message: "Zulip site URL cannot be empty"}
+	}
+	if sender.Credentials.BotEmail == "" || sender.Credentials.APIKey == "" {
+		return nil, &ValidationError{Field: "credentials", Message: "Zulip bot email and API key cannot be empty"}
+	}
+
+	message = withDefaultReceiver(message, sender.Credentials.Stream)
+
+	return sendEachMessage(ctx, ChannelChat, "zulip", message, func(ctx context.Context, text, receiver string) (RecipientResult, error) {
+		return sender.send(ctx, message, text, receiver)
+	})
+}
+
+func (sender *ZulipSender) send(ctx context.Context, message *Message, text, receiver string) (result RecipientResult, err error) {
+	defer func() { DefaultStats.RecordResult("zulip", "chat", err) }()
+
+	if text, err = chatText(message, text); err != nil {
+		return result, err
+	}
+
+	payload := url.Values{}
+	payload.Set("content", text)
+	if strings.Contains(receiver, "@") {
+		to, err := json.Marshal([]string{receiver})
+		if err != nil {
+			return result, fmt.Errorf("Failed to encode Zulip request: %w", err)
+		}
+		payload.Set("type", "direct")
+		payload.Set("to", string(to))
+	} else {
+		topic := message.Subject
+		if topic == "" {
+			topic = sender.Topic
+		}
+		payload.Set("type", "stream")
+		payload.Set("to", strings.TrimPrefix(receiver, "#"))
+		payload.Set("topic", topic)
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		strings.TrimSuffix(sender.Credentials.SiteURL, "/")+"/api/v1/messages",
+		strings.NewReader(payload.Encode()),
+	)
+	if err != nil {
+		return result, fmt.Errorf("Failed to create http request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(sender.Credentials.BotEmail, sender.Credentials.APIKey)
+
+	_, responseBody, err := doProviderRequest("zulip", "Zulip", request)
+	result.Raw = string(responseBody)
+	if err != nil {
+		return result, err
+	}
+
+	var zulipResp zulipResponse
+	if err := json.Unmarshal(responseBody, &zulipResp); err != nil {
+		return result, fmt.Errorf("Successfully sent, but failed to parse response: %w", err)
+	}
+
+	if zulipResp.Result != "success" {
+		return result, fmt.Errorf("Zulip rejected the message: %s", zulipResp.Msg)
+	}
+
+	result.MessageID = strconv.FormatInt(zulipResp.ID, 10)
+
+	return result, nil
+}